@@ -39,6 +39,19 @@ class SocketLifecycleHandler
     }
     
 
+    /**
+     * 流式请求（limits.stream_body）的请求体
+     * 网关以数据块发送请求体，JSON请求体解析为数组，其他内容保留原始字符串，与普通请求的 body 一致
+     */
+    public function onBodyReceived($body)
+    {
+        if (empty($this->receivedData)) {
+            return;
+        }
+        $decoded = json_decode($body, true);
+        $this->receivedData['params']['body'] = json_last_error() === JSON_ERROR_NONE ? $decoded : $body;
+    }
+
     public function onCtrl($data) {
         
         // 直接使用 onDataReceived 中存储的解析后数据
@@ -3,6 +3,19 @@ namespace Develop\Server;
 
 class AsyncUnixSocketServer
 {
+    // 协议版本 v1.1，与网关 ipc.ProtocolVersion 一致
+    const PROTOCOL_VERSION = 0x0101;
+    // 心跳消息类型，网关的健康检查发送空负载心跳包并等待心跳响应
+    const MSG_HEARTBEAT = 0x02;
+    // 流式请求类型（路由开启 limits.stream_body），负载为不含请求体的同步请求，之后紧跟若干请求体数据块
+    const MSG_STREAM_REQUEST = 0x06;
+    // 请求体数据块类型，负载为原始字节，负载长度为0的数据块表示请求体结束
+    const MSG_BODY_CHUNK = 0x07;
+    // 协议头长度：2字节版本 + 1字节消息类型 + 4字节负载长度
+    const HEADER_SIZE = 7;
+    // 读取请求的超时秒数，避免不完整的请求阻塞事件循环
+    const READ_TIMEOUT = 30;
+
     private $container; // 用于存储容器实例
     private $socketFile;
     private $pidFile;
@@ -94,41 +107,127 @@ class AsyncUnixSocketServer
                 echo "socket_accept() failed: reason: " . socket_strerror(socket_last_error($this->socket)) . "\n";
                 return;
             }
-            socket_set_nonblock($clientSocket);
-            $data = '';
-            while (true) {
-                $read = socket_read($clientSocket, 1024);
-                if ($read === false) {
-                    if (socket_last_error($clientSocket) == SOCKET_EAGAIN) {
-                        break;
-                    }
-                    echo "socket_read() failed: reason: " . socket_strerror(socket_last_error($clientSocket)) . "\n";
+            // 按协议帧读取：协议头 + 负载
+            socket_set_block($clientSocket);
+            socket_set_option($clientSocket, SOL_SOCKET, SO_RCVTIMEO, ['sec' => self::READ_TIMEOUT, 'usec' => 0]);
+            $frame = $this->readFrame($clientSocket);
+            if ($frame === null) {
+                socket_close($clientSocket);
+                return;
+            }
+            list($msgType, $payload) = $frame;
+            // 心跳包直接回复心跳响应，不进入生命周期处理
+            if ($msgType === self::MSG_HEARTBEAT) {
+                $this->writeAll($clientSocket, pack('nCN', self::PROTOCOL_VERSION, self::MSG_HEARTBEAT, 0));
+                socket_close($clientSocket);
+                return;
+            }
+            // 流式请求：继续读取请求体数据块直到结束块
+            $body = null;
+            if ($msgType === self::MSG_STREAM_REQUEST) {
+                $body = $this->readStreamBody($clientSocket);
+                if ($body === null) {
                     socket_close($clientSocket);
                     return;
                 }
-                $data .= $read;
-                if (strlen($read) < 1024) {
-                    break;
-                }
             }
-            if (!empty($data)) {
-                $data = trim($data);
-                // 触发onDataReceived周期
-                if ($this->lifecycleHandler && $this->lifecycleHandler->onDataReceived($data)) {
-                    // 在生命周期处理类中处理路由和控制逻辑
-                    $response = $this->lifecycleHandler->onCtrl($data);
-                    // 触发onDataSent周期
-                    if ($this->lifecycleHandler) {
-                        $this->lifecycleHandler->onDataSent($response);
-                    }
-                    socket_write($clientSocket, $response, strlen($response));
+            $data = pack('nCN', self::PROTOCOL_VERSION, $msgType, strlen($payload)) . $payload;
+            // 触发onDataReceived周期
+            if ($this->lifecycleHandler && $this->lifecycleHandler->onDataReceived($data)) {
+                // 流式请求的请求体不在JSON负载中，单独交给生命周期处理类
+                if ($body !== null && method_exists($this->lifecycleHandler, 'onBodyReceived')) {
+                    $this->lifecycleHandler->onBodyReceived($body);
                 }
+                // 在生命周期处理类中处理路由和控制逻辑
+                $response = $this->lifecycleHandler->onCtrl($data);
+                // 触发onDataSent周期
+                $this->lifecycleHandler->onDataSent($response);
+                $this->writeAll($clientSocket, $response);
             }
             socket_close($clientSocket);
         });
         $this->event->add();
     }
 
+    /**
+     * 读取一条完整消息
+     * @return array|null [消息类型, 负载]，连接关闭、超时或协议版本不匹配时返回null
+     */
+    private function readFrame($socket)
+    {
+        $header = $this->readExact($socket, self::HEADER_SIZE);
+        if ($header === null) {
+            return null;
+        }
+        $fields = unpack('nversion/CmsgType/NpayloadLen', $header);
+        if ($fields['version'] !== self::PROTOCOL_VERSION) {
+            echo "不支持的协议版本: {$fields['version']}\n";
+            return null;
+        }
+        $payload = $this->readExact($socket, $fields['payloadLen']);
+        if ($payload === null) {
+            return null;
+        }
+        return [$fields['msgType'], $payload];
+    }
+
+    /**
+     * 读取流式请求的请求体数据块
+     * @return string|null 完整请求体，连接关闭、超时或收到其他类型的消息时返回null
+     */
+    private function readStreamBody($socket)
+    {
+        $body = '';
+        while (true) {
+            $frame = $this->readFrame($socket);
+            if ($frame === null) {
+                return null;
+            }
+            list($msgType, $chunk) = $frame;
+            if ($msgType !== self::MSG_BODY_CHUNK) {
+                echo "流式请求中收到无效的消息类型: {$msgType}\n";
+                return null;
+            }
+            if ($chunk === '') {
+                return $body;
+            }
+            $body .= $chunk;
+        }
+    }
+
+    /**
+     * 读取指定长度的数据，socket_read 可能只返回部分数据
+     * @return string|null 连接关闭或超时时返回null
+     */
+    private function readExact($socket, $length)
+    {
+        $data = '';
+        while (strlen($data) < $length) {
+            $read = socket_read($socket, $length - strlen($data));
+            if ($read === false || $read === '') {
+                return null;
+            }
+            $data .= $read;
+        }
+        return $data;
+    }
+
+    /**
+     * 写入全部数据，socket_write 可能只写入部分数据
+     */
+    private function writeAll($socket, $data)
+    {
+        while ($data !== '') {
+            $written = socket_write($socket, $data, strlen($data));
+            if ($written === false) {
+                echo "socket_write() failed: reason: " . socket_strerror(socket_last_error($socket)) . "\n";
+                return false;
+            }
+            $data = substr($data, $written);
+        }
+        return true;
+    }
+
     private function writePidToFile($filePath)
     {
         $pid = getmypid();
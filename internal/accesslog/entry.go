@@ -0,0 +1,178 @@
+package accesslog
+
+import (
+	"bigHammer/internal/middleware"
+	"context"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Entry 单个HTTP请求的访问日志记录
+// 由 Begin 创建并存入请求上下文，处理过程中补充路由、后端和耗时信息，Finish 后输出
+type Entry struct {
+	Time      string `json:"time"`
+	RequestID string `json:"request_id"`
+	Method    string `json:"method"`
+	Host      string `json:"host"`
+	Path      string `json:"path"`
+	Query     string `json:"query,omitempty"`
+	// Route 匹配的路由路径，未匹配时为空
+	Route string `json:"route,omitempty"`
+	// Backend 处理请求的后端名称，使用默认业务Socket时为 default
+	Backend string `json:"backend,omitempty"`
+	// Variant 流量拆分选中的变体
+	Variant string `json:"variant,omitempty"`
+	// Version 版本化路由的API版本
+	Version string `json:"version,omitempty"`
+	// Endpoint 最后一次尝试使用的业务进程端点
+	Endpoint string `json:"endpoint,omitempty"`
+	// Attempts 调用业务进程的次数（含重试）
+	Attempts int `json:"attempts,omitempty"`
+	Status   int `json:"status"`
+	// BytesIn 已读取的请求体字节数
+	BytesIn int64 `json:"bytes_in"`
+	// BytesOut 已写入的响应体字节数
+	BytesOut int64 `json:"bytes_out"`
+	// DurationMs 请求总耗时（毫秒）
+	DurationMs float64 `json:"duration_ms"`
+	// UpstreamMs 等待业务进程的耗时（毫秒），多次尝试时累加
+	UpstreamMs float64 `json:"upstream_ms"`
+	// GatewayMs 网关自身的处理耗时（毫秒），即总耗时减去业务进程耗时
+	GatewayMs float64 `json:"gateway_ms"`
+	ClientIP  string  `json:"client_ip"`
+	UserAgent string  `json:"user_agent,omitempty"`
+	// Error 调用业务进程失败时的错误信息
+	Error string `json:"error,omitempty"`
+
+	start    time.Time
+	upstream time.Duration
+	body     *countingReader
+}
+
+type entryKey struct{}
+
+// FromContext 从上下文中获取访问日志记录，未记录时返回nil
+// Entry 的方法都可以在nil上调用
+func FromContext(ctx context.Context) *Entry {
+	e, _ := ctx.Value(entryKey{}).(*Entry)
+	return e
+}
+
+// Begin 开始记录请求
+// 功能：
+// 1. 创建访问日志记录并存入请求上下文
+// 2. 包装请求体以统计读取的字节数
+// 参数：
+//   - req *http.Request: HTTP请求，应已分配请求ID
+// 返回值：
+//   - *Entry: 访问日志记录
+//   - *http.Request: 携带访问日志记录的请求
+func Begin(req *http.Request) (*Entry, *http.Request) {
+	now := time.Now()
+	e := &Entry{
+		Time:      now.UTC().Format(time.RFC3339Nano),
+		RequestID: middleware.RequestIDFromContext(req.Context()),
+		Method:    req.Method,
+		Host:      req.Host,
+		Path:      req.URL.Path,
+		Query:     req.URL.RawQuery,
+		ClientIP:  middleware.ClientIP(req),
+		UserAgent: req.UserAgent(),
+		start:     now,
+	}
+	req = req.WithContext(context.WithValue(req.Context(), entryKey{}, e))
+	if req.Body != nil && req.Body != http.NoBody {
+		e.body = &countingReader{ReadCloser: req.Body}
+		req.Body = e.body
+	}
+	return e, req
+}
+
+// SetRoute 记录匹配的路由路径
+func (e *Entry) SetRoute(route string) {
+	if e != nil {
+		e.Route = route
+	}
+}
+
+// SetBackend 记录处理请求的后端名称
+func (e *Entry) SetBackend(backend string) {
+	if e != nil {
+		e.Backend = backend
+	}
+}
+
+// SetVariant 记录流量拆分选中的变体
+func (e *Entry) SetVariant(variant string) {
+	if e != nil {
+		e.Variant = variant
+	}
+}
+
+// SetVersion 记录版本化路由的API版本
+func (e *Entry) SetVersion(version string) {
+	if e != nil {
+		e.Version = version
+	}
+}
+
+// SetError 记录调用业务进程的错误
+func (e *Entry) SetError(err error) {
+	if e != nil && err != nil {
+		e.Error = err.Error()
+	}
+}
+
+// Upstream 记录一次对业务进程的调用
+// 参数：
+//   - endpoint string: 业务进程端点
+//   - elapsed time.Duration: 本次调用耗时
+func (e *Entry) Upstream(endpoint string, elapsed time.Duration) {
+	if e == nil {
+		return
+	}
+	e.Endpoint = endpoint
+	e.Attempts++
+	e.upstream += elapsed
+}
+
+// Finish 结束记录，计算字节数和耗时
+// 参数：
+//   - status int: 响应状态码
+//   - bytesOut int64: 响应体字节数
+func (e *Entry) Finish(status int, bytesOut int64) {
+	if e == nil {
+		return
+	}
+	total := time.Since(e.start)
+	e.Status = status
+	e.BytesOut = bytesOut
+	if e.body != nil {
+		e.BytesIn = atomic.LoadInt64(&e.body.n)
+	}
+	e.DurationMs = milliseconds(total)
+	e.UpstreamMs = milliseconds(e.upstream)
+	e.GatewayMs = milliseconds(total - e.upstream)
+}
+
+// milliseconds 将耗时转换为保留三位小数的毫秒数
+func milliseconds(d time.Duration) float64 {
+	if d < 0 {
+		d = 0
+	}
+	return float64(d.Microseconds()) / 1000
+}
+
+// countingReader 统计已读取字节数的请求体包装器
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	atomic.AddInt64(&r.n, int64(n))
+	return n, err
+}
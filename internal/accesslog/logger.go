@@ -0,0 +1,140 @@
+package accesslog
+
+import (
+	"bigHammer/internal/config"
+	"bigHammer/pkg/utils"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// Logger 访问日志输出
+type Logger struct {
+	mu     sync.Mutex
+	out    io.Writer
+	closer io.Closer
+	text   bool
+}
+
+// current 当前使用的访问日志输出，未初始化或关闭时为nil
+var current atomic.Pointer[Logger]
+
+// New 根据配置创建访问日志输出
+// 参数：
+//   - cfg config.AccessLogConfig: 访问日志配置
+// 返回值：
+//   - *Logger: 访问日志输出，配置关闭时为nil
+//   - error: 格式无效或日志文件无法打开时返回的错误信息
+func New(cfg config.AccessLogConfig) (*Logger, error) {
+	if cfg.Disabled {
+		return nil, nil
+	}
+	l := &Logger{}
+	switch cfg.Format {
+	case "", "json":
+	case "text":
+		l.text = true
+	default:
+		return nil, fmt.Errorf("unknown access log format %q", cfg.Format)
+	}
+	switch cfg.Output {
+	case "", "stdout":
+		l.out = os.Stdout
+	case "stderr":
+		l.out = os.Stderr
+	default:
+		path, err := utils.ResolvePath(cfg.Output)
+		if err != nil {
+			return nil, err
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("open access log: %v", err)
+		}
+		l.out, l.closer = f, f
+	}
+	return l, nil
+}
+
+// Init 按配置创建访问日志输出并替换当前输出，旧的日志文件会被关闭
+// 参数：
+//   - cfg config.AccessLogConfig: 访问日志配置
+// 返回值：
+//   - error: 配置无效时返回的错误信息，此时保留原输出
+func Init(cfg config.AccessLogConfig) error {
+	l, err := New(cfg)
+	if err != nil {
+		return err
+	}
+	if old := current.Swap(l); old != nil {
+		old.Close()
+	}
+	return nil
+}
+
+// Log 使用当前输出记录一条访问日志，未初始化时忽略
+func Log(e *Entry) {
+	if l := current.Load(); l != nil {
+		l.Log(e)
+	}
+}
+
+// Log 输出一条访问日志
+func (l *Logger) Log(e *Entry) {
+	if l == nil || e == nil {
+		return
+	}
+	var line []byte
+	if l.text {
+		line = []byte(formatText(e))
+	} else {
+		var err error
+		if line, err = json.Marshal(e); err != nil {
+			return
+		}
+	}
+	line = append(line, '\n')
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.out.Write(line)
+}
+
+// Close 关闭日志文件
+func (l *Logger) Close() error {
+	if l == nil || l.closer == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.closer.Close()
+}
+
+// formatText 以单行文本格式输出访问日志
+// 例如：2006-01-02T15:04:05Z 127.0.0.1 "GET /users?page=1" 200 0B 512B 3.2ms upstream=2.9ms route=/users backend=default request_id=...
+func formatText(e *Entry) string {
+	uri := e.Path
+	if e.Query != "" {
+		uri += "?" + e.Query
+	}
+	line := fmt.Sprintf("%s %s \"%s %s\" %d %dB %dB %.3fms upstream=%.3fms",
+		e.Time, e.ClientIP, e.Method, uri, e.Status, e.BytesIn, e.BytesOut, e.DurationMs, e.UpstreamMs)
+	for _, field := range [][2]string{
+		{"route", e.Route},
+		{"backend", e.Backend},
+		{"variant", e.Variant},
+		{"version", e.Version},
+		{"endpoint", e.Endpoint},
+		{"request_id", e.RequestID},
+	} {
+		if field[1] != "" {
+			line += " " + field[0] + "=" + field[1]
+		}
+	}
+	if e.Error != "" {
+		line += fmt.Sprintf(" error=%q", e.Error)
+	}
+	return line
+}
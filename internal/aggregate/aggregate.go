@@ -0,0 +1,465 @@
+package aggregate
+
+import (
+	"bigHammer/internal/transform"
+	"bigHammer/internal/upstream"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// 子调用类型
+const (
+	// TypeIPC 通过IPC调用业务进程命令
+	TypeIPC = "ipc"
+	// TypePlugin 调用网关插件
+	TypePlugin = "plugin"
+	// TypeHTTP 调用HTTP上游服务
+	TypeHTTP = "http"
+)
+
+// 子调用结果
+const (
+	ResultSuccess = "success"
+	ResultError   = "error"
+	ResultTimeout = "timeout"
+	ResultSkipped = "skipped"
+)
+
+var partDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "gateway_aggregate_part_duration_seconds",
+	Help:    "聚合路由子调用耗时，result 取值 success / error / timeout / skipped",
+	Buckets: prometheus.DefBuckets,
+}, []string{"route", "part", "result"})
+
+// Options 聚合路由配置，对应 router.json 中路由的 aggregate 字段
+// 多个子调用并行执行（按 depends_on 等待依赖），结果合并为一个JSON响应
+type Options struct {
+	// Parts 子调用列表
+	Parts []Part `json:"parts"`
+	// Timeout 整个聚合请求的超时秒数，默认10
+	Timeout int `json:"timeout,omitempty"`
+}
+
+// Part 聚合路由的一个子调用
+type Part struct {
+	// Name 子调用名称，结果写入响应的同名字段，也用于 depends_on 和 $.parts.<name> 引用
+	Name string `json:"name"`
+	// Type 子调用类型：ipc（默认）、plugin 或 http
+	Type string `json:"type,omitempty"`
+	// Command ipc 子调用的业务命令
+	Command string `json:"command,omitempty"`
+	// Backend ipc 子调用使用的后端，为空时使用路由的 backend，仍为空时使用 bussiness_socket_path
+	Backend string `json:"backend,omitempty"`
+	// Plugin plugin 子调用的插件（服务）名称
+	Plugin string `json:"plugin,omitempty"`
+	// Method plugin 子调用的方法名；http 子调用的请求方法，默认 GET
+	Method string `json:"method,omitempty"`
+	// URL http 子调用的地址
+	URL string `json:"url,omitempty"`
+	// Headers http 子调用的请求头，值的写法与 params 相同
+	Headers map[string]string `json:"headers,omitempty"`
+	// Params 子调用参数：ipc 作为 params 发送，plugin 作为插件参数，http 的 GET/DELETE 作为查询参数、其他方法作为JSON请求体
+	// 以 $. 开头的字符串为选择器，从 {"body", "query", "headers", "path", "route", "parts"} 上下文取值，其他值原样使用
+	Params map[string]interface{} `json:"params,omitempty"`
+	// DependsOn 依赖的子调用，全部完成后才执行本调用
+	DependsOn []string `json:"depends_on,omitempty"`
+	// Timeout 子调用超时秒数，默认5
+	Timeout int `json:"timeout,omitempty"`
+	// Select 从子调用结果中选取的部分，例如 $.data
+	Select string `json:"select,omitempty"`
+	// Optional 子调用失败时是否仍返回其他结果，失败信息写入响应的 errors 字段
+	Optional bool `json:"optional,omitempty"`
+	// Fallback 子调用失败时使用的值，配置后视为 optional
+	Fallback interface{} `json:"fallback,omitempty"`
+	// Merge 结果为对象时合并到响应根对象，而不是写入同名字段
+	Merge bool `json:"merge,omitempty"`
+	// Hidden 结果只供其他子调用引用，不写入响应
+	Hidden bool `json:"hidden,omitempty"`
+}
+
+func (p Part) kind() string {
+	if p.Type == "" {
+		return TypeIPC
+	}
+	return p.Type
+}
+
+func (p Part) timeout() time.Duration {
+	if p.Timeout <= 0 {
+		return 5 * time.Second
+	}
+	return time.Duration(p.Timeout) * time.Second
+}
+
+func (p Part) optional() bool {
+	return p.Optional || p.Fallback != nil
+}
+
+func (o Options) timeout() time.Duration {
+	if o.Timeout <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(o.Timeout) * time.Second
+}
+
+// Validate 校验聚合配置：名称唯一、类型参数完整、依赖存在且无环，$.parts 引用必须声明为依赖
+func (o Options) Validate() error {
+	if len(o.Parts) == 0 {
+		return fmt.Errorf("aggregate: parts is required")
+	}
+	names := make(map[string]Part, len(o.Parts))
+	for _, p := range o.Parts {
+		if p.Name == "" {
+			return fmt.Errorf("aggregate: part name is required")
+		}
+		if _, ok := names[p.Name]; ok {
+			return fmt.Errorf("aggregate: duplicate part %s", p.Name)
+		}
+		names[p.Name] = p
+		switch p.kind() {
+		case TypeIPC:
+			if p.Command == "" {
+				return fmt.Errorf("aggregate part %s: command is required", p.Name)
+			}
+		case TypePlugin:
+			if p.Plugin == "" {
+				return fmt.Errorf("aggregate part %s: plugin is required", p.Name)
+			}
+		case TypeHTTP:
+			if !strings.HasPrefix(p.URL, "http://") && !strings.HasPrefix(p.URL, "https://") {
+				return fmt.Errorf("aggregate part %s: url must start with http:// or https://", p.Name)
+			}
+		default:
+			return fmt.Errorf("aggregate part %s: unknown type %q", p.Name, p.Type)
+		}
+	}
+	for _, p := range o.Parts {
+		for _, dep := range p.DependsOn {
+			if _, ok := names[dep]; !ok {
+				return fmt.Errorf("aggregate part %s: unknown dependency %s", p.Name, dep)
+			}
+		}
+		for _, value := range p.values() {
+			if ref := partRef(value); ref != "" && !contains(p.DependsOn, ref) {
+				return fmt.Errorf("aggregate part %s: %s must be listed in depends_on", p.Name, ref)
+			}
+		}
+	}
+	return checkCycles(o.Parts)
+}
+
+// values 返回子调用所有可能包含选择器的参数值
+func (p Part) values() []interface{} {
+	values := make([]interface{}, 0, len(p.Params)+len(p.Headers))
+	for _, v := range p.Params {
+		values = append(values, v)
+	}
+	for _, v := range p.Headers {
+		values = append(values, v)
+	}
+	return values
+}
+
+// partRef 返回 $.parts.<name> 选择器引用的子调用名称
+func partRef(value interface{}) string {
+	s, ok := value.(string)
+	if !ok || !strings.HasPrefix(s, "$.parts.") {
+		return ""
+	}
+	name := strings.TrimPrefix(s, "$.parts.")
+	if end := strings.IndexAny(name, ".["); end >= 0 {
+		name = name[:end]
+	}
+	return name
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCycles 检查子调用依赖是否存在环
+func checkCycles(parts []Part) error {
+	deps := make(map[string][]string, len(parts))
+	for _, p := range parts {
+		deps[p.Name] = p.DependsOn
+	}
+	// state: 0 未访问，1 访问中，2 已完成
+	state := make(map[string]int, len(parts))
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case 1:
+			return fmt.Errorf("aggregate: dependency cycle at part %s", name)
+		case 2:
+			return nil
+		}
+		state[name] = 1
+		for _, dep := range deps[name] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+		return nil
+	}
+	for _, p := range parts {
+		if err := visit(p.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Aggregator 编译后的聚合路由
+type Aggregator struct {
+	route string
+	opts  Options
+	// pools 各子调用使用的后端，使用默认业务Socket时为nil
+	pools []*upstream.Pool
+	// selects 各子调用结果的选择器，未配置时为nil
+	selects []*transform.Selector
+	// index 子调用名称到下标的映射
+	index map[string]int
+}
+
+// New 创建聚合路由
+// 参数：
+//   - route string: 路由路径，用于日志和指标标签
+//   - opts Options: 聚合配置
+//   - backend string: 路由的默认后端名称，可为空
+//   - pools map[string]*upstream.Pool: 已创建的后端
+// 返回值：
+//   - *Aggregator: 聚合路由
+//   - error: 配置无效或引用了不存在的后端时返回的错误信息
+func New(route string, opts Options, backend string, pools map[string]*upstream.Pool) (*Aggregator, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	a := &Aggregator{
+		route:   route,
+		opts:    opts,
+		pools:   make([]*upstream.Pool, len(opts.Parts)),
+		selects: make([]*transform.Selector, len(opts.Parts)),
+		index:   make(map[string]int, len(opts.Parts)),
+	}
+	for i, p := range opts.Parts {
+		a.index[p.Name] = i
+		if p.kind() == TypeIPC {
+			name := p.Backend
+			if name == "" {
+				name = backend
+			}
+			if name != "" {
+				if a.pools[i] = pools[name]; a.pools[i] == nil {
+					return nil, fmt.Errorf("aggregate part %s: unknown backend %s", p.Name, name)
+				}
+			}
+		}
+		if p.Select != "" {
+			sel, err := transform.ParseSelector(p.Select)
+			if err != nil {
+				return nil, fmt.Errorf("aggregate part %s: %v", p.Name, err)
+			}
+			a.selects[i] = &sel
+		}
+	}
+	return a, nil
+}
+
+// PartError 子调用失败信息
+type PartError struct {
+	// Part 子调用名称
+	Part string `json:"part"`
+	// Message 错误描述
+	Message string `json:"error"`
+	// err 原始错误，用于判断超时等错误类别
+	err error
+}
+
+// Error 返回子调用名称和错误描述
+func (e *PartError) Error() string {
+	return "part " + e.Part + ": " + e.Message
+}
+
+// Unwrap 返回原始错误
+func (e *PartError) Unwrap() error {
+	return e.err
+}
+
+// Result 聚合结果
+type Result struct {
+	// Data 合并后的响应数据
+	Data map[string]interface{}
+	// Errors 失败的可选子调用
+	Errors []*PartError
+	// Failed 失败的必需子调用，非nil时不应返回 Data
+	Failed *PartError
+}
+
+// outcome 单个子调用的执行结果
+type outcome struct {
+	value interface{}
+	err   error
+	done  chan struct{}
+}
+
+// Run 执行所有子调用并合并结果
+// 功能：
+// 1. 没有依赖的子调用立即并行执行，其他子调用在依赖全部完成后执行
+// 2. 依赖失败（且没有 fallback）的子调用不执行，视为失败
+// 3. 每个子调用有独立超时，整个聚合受 timeout 限制
+// 4. 可选子调用失败时使用 fallback（未配置时为null），必需子调用失败时返回 Failed
+// 参数：
+//   - ctx context.Context: 客户端请求上下文
+//   - requestID string: 网关请求ID，随 ipc、plugin 子调用发送，http 子调用写入 X-Request-ID
+//   - input map[string]interface{}: 选择器上下文 {"body", "query", "headers", "path", "route"}
+// 返回值：
+//   - *Result: 聚合结果
+func (a *Aggregator) Run(ctx context.Context, requestID string, input map[string]interface{}) *Result {
+	ctx, cancel := context.WithTimeout(ctx, a.opts.timeout())
+	defer cancel()
+
+	outcomes := make([]*outcome, len(a.opts.Parts))
+	for i := range outcomes {
+		outcomes[i] = &outcome{done: make(chan struct{})}
+	}
+	for i := range a.opts.Parts {
+		go a.runPart(ctx, i, requestID, input, outcomes)
+	}
+
+	result := &Result{Data: make(map[string]interface{})}
+	for i, p := range a.opts.Parts {
+		o := outcomes[i]
+		<-o.done
+		value := o.value
+		if o.err != nil {
+			perr := &PartError{Part: p.Name, Message: o.err.Error(), err: o.err}
+			if !p.optional() {
+				if result.Failed == nil {
+					result.Failed = perr
+				}
+				continue
+			}
+			result.Errors = append(result.Errors, perr)
+			value = p.Fallback
+		}
+		if p.Hidden {
+			continue
+		}
+		if obj, ok := value.(map[string]interface{}); ok && p.Merge {
+			for k, v := range obj {
+				result.Data[k] = v
+			}
+			continue
+		}
+		result.Data[p.Name] = value
+	}
+	return result
+}
+
+// runPart 等待依赖完成后执行一个子调用
+func (a *Aggregator) runPart(ctx context.Context, i int, requestID string, input map[string]interface{}, outcomes []*outcome) {
+	p := a.opts.Parts[i]
+	o := outcomes[i]
+	defer close(o.done)
+	start := time.Now()
+
+	// 依赖的结果写入 parts 上下文，失败且配置了 fallback 的依赖使用 fallback
+	parts := make(map[string]interface{}, len(p.DependsOn))
+	for _, dep := range p.DependsOn {
+		d := a.opts.Parts[a.index[dep]]
+		do := outcomes[a.index[dep]]
+		select {
+		case <-do.done:
+		case <-ctx.Done():
+			o.err = ctx.Err()
+			a.observe(p.Name, ResultTimeout, start)
+			return
+		}
+		if do.err != nil && d.Fallback == nil {
+			o.err = fmt.Errorf("dependency %s failed", dep)
+			a.observe(p.Name, ResultSkipped, start)
+			return
+		}
+		if do.err != nil {
+			parts[dep] = d.Fallback
+		} else {
+			parts[dep] = do.value
+		}
+	}
+	scope := make(map[string]interface{}, len(input)+1)
+	for k, v := range input {
+		scope[k] = v
+	}
+	scope["parts"] = parts
+
+	partCtx, cancel := context.WithTimeout(ctx, p.timeout())
+	defer cancel()
+	value, err := a.call(partCtx, i, requestID, scope)
+	if err == nil && a.selects[i] != nil {
+		value, _ = a.selects[i].Get(value)
+	}
+	o.value, o.err = value, err
+	switch {
+	case err == nil:
+		a.observe(p.Name, ResultSuccess, start)
+	case partCtx.Err() == context.DeadlineExceeded:
+		o.err = fmt.Errorf("timeout after %s: %w", time.Since(start).Round(time.Millisecond), context.DeadlineExceeded)
+		a.observe(p.Name, ResultTimeout, start)
+	default:
+		a.observe(p.Name, ResultError, start)
+	}
+}
+
+func (a *Aggregator) observe(part, result string, start time.Time) {
+	partDuration.WithLabelValues(a.route, part, result).Observe(time.Since(start).Seconds())
+}
+
+// resolve 解析参数值：以 $. 开头的字符串按选择器从上下文取值（不存在时为nil），其他值原样返回
+func resolve(value interface{}, scope map[string]interface{}) interface{} {
+	s, ok := value.(string)
+	if !ok || !strings.HasPrefix(s, "$.") {
+		return value
+	}
+	sel, err := transform.ParseSelector(s)
+	if err != nil {
+		return value
+	}
+	v, _ := sel.Get(scope)
+	return v
+}
+
+// resolveString 解析参数值并转换为字符串，对象和数组编码为JSON
+func resolveString(value interface{}, scope map[string]interface{}) string {
+	switch v := resolve(value, scope).(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64, bool, json.Number:
+		return fmt.Sprint(v)
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+// decode 将子调用的响应解析为JSON，无法解析时作为字符串返回
+func decode(data []byte) interface{} {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return string(data)
+	}
+	return value
+}
@@ -0,0 +1,154 @@
+package aggregate
+
+import (
+	"bigHammer/internal/config"
+	ipc "bigHammer/internal/ipc/socket"
+	"bigHammer/internal/plugin"
+	"bigHammer/pkg/utils"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// maxResponseSize http 子调用响应体的最大字节数
+const maxResponseSize = 4 * 1024 * 1024
+
+// client http 子调用使用的客户端，超时由子调用的上下文控制
+var client = &http.Client{}
+
+// call 按子调用类型执行调用
+func (a *Aggregator) call(ctx context.Context, i int, requestID string, scope map[string]interface{}) (interface{}, error) {
+	p := a.opts.Parts[i]
+	switch p.kind() {
+	case TypePlugin:
+		return callPlugin(ctx, p, requestID, scope)
+	case TypeHTTP:
+		return callHTTP(ctx, p, requestID, scope)
+	}
+	return a.callIPC(ctx, i, requestID, scope)
+}
+
+// callIPC 通过IPC调用业务进程命令
+func (a *Aggregator) callIPC(ctx context.Context, i int, requestID string, scope map[string]interface{}) (interface{}, error) {
+	p := a.opts.Parts[i]
+	params := make(map[string]interface{}, len(p.Params))
+	for k, v := range p.Params {
+		params[k] = resolve(v, scope)
+	}
+	pool := a.pools[i]
+	if pool == nil {
+		socketPath, err := utils.ResolvePath(config.GlobalConfig.BussinessSocketPath)
+		if err != nil {
+			return nil, err
+		}
+		output, err := ipc.TransmitIPCContext(ctx, requestID, p.Command, params, socketPath)
+		if err != nil {
+			return nil, err
+		}
+		return decode(output), nil
+	}
+	e, err := pool.Pick("")
+	if err != nil {
+		return nil, err
+	}
+	output, err := ipc.TransmitIPCContext(ctx, requestID, p.Command, params, e.Address)
+	pool.Done(e, err)
+	if err != nil {
+		return nil, err
+	}
+	return decode(output), nil
+}
+
+// callPlugin 调用网关插件，插件返回的状态码不小于400时视为失败，结果为插件响应的 data
+func callPlugin(ctx context.Context, p Part, requestID string, scope map[string]interface{}) (interface{}, error) {
+	params := make(map[string]string, len(p.Params))
+	for k, v := range p.Params {
+		params[k] = resolveString(v, scope)
+	}
+	req := plugin.Request{Service: p.Plugin, Method: p.Method, Params: params, RequestID: requestID}
+	done := make(chan plugin.Response, 1)
+	go func() {
+		done <- plugin.DispatchRequest(req)
+	}()
+	select {
+	case resp := <-done:
+		if resp.Status >= 400 {
+			return nil, fmt.Errorf("plugin %s: %d %s", p.Plugin, resp.Status, resp.Message)
+		}
+		// 转换为JSON通用类型，使 select、merge 和 $.parts 引用对插件结果同样有效
+		data, err := json.Marshal(resp.Data)
+		if err != nil {
+			return nil, err
+		}
+		return decode(data), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// callHTTP 调用HTTP上游服务，非2xx响应视为失败
+func callHTTP(ctx context.Context, p Part, requestID string, scope map[string]interface{}) (interface{}, error) {
+	method := strings.ToUpper(p.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	target, err := url.Parse(p.URL)
+	if err != nil {
+		return nil, err
+	}
+	var body io.Reader
+	if method == http.MethodGet || method == http.MethodDelete || method == http.MethodHead {
+		query := target.Query()
+		for k, v := range p.Params {
+			query.Set(k, resolveString(v, scope))
+		}
+		target.RawQuery = query.Encode()
+	} else if len(p.Params) > 0 {
+		params := make(map[string]interface{}, len(p.Params))
+		for k, v := range p.Params {
+			params[k] = resolve(v, scope)
+		}
+		data, err := json.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if requestID != "" {
+		req.Header.Set("X-Request-ID", requestID)
+	}
+	for k, v := range p.Headers {
+		req.Header.Set(k, resolveString(v, scope))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxResponseSize {
+		return nil, fmt.Errorf("response exceeds %d bytes", maxResponseSize)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("upstream returned %d", resp.StatusCode)
+	}
+	return decode(data), nil
+}
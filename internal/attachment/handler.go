@@ -0,0 +1,246 @@
+package attachment
+
+import (
+	"bigHammer/internal/middleware"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxFieldSize 上传表单中普通字段的最大字节数
+const maxFieldSize = 1 << 20
+
+// Options 文件路由配置，对应 router.json 中路由的 files 字段
+type Options struct {
+	// Mode 路由模式：upload 或 download
+	Mode string `json:"mode"`
+	// Dir 存储子目录（相对 attachment_storage）
+	Dir string `json:"dir,omitempty"`
+	// MaxSize 单个文件最大字节数，0表示不限制
+	MaxSize int64 `json:"max_size,omitempty"`
+	// MaxFiles 单次请求最多文件数，0表示不限制
+	MaxFiles int `json:"max_files,omitempty"`
+	// AllowedTypes 允许的MIME类型，支持 image/* 通配，为空时不限制
+	AllowedTypes []string `json:"allowed_types,omitempty"`
+	// DownloadPath 下载路由路径，配置后上传结果包含签名下载链接
+	DownloadPath string `json:"download_path,omitempty"`
+	// URLTTL 签名下载链接有效秒数，默认3600
+	URLTTL int `json:"url_ttl,omitempty"`
+	// Public 下载时是否跳过签名校验（通常与认证中间件配合使用）
+	Public bool `json:"public,omitempty"`
+}
+
+// Validate 校验文件路由配置
+func (o Options) Validate() error {
+	if o.Mode != "upload" && o.Mode != "download" {
+		return fmt.Errorf("unknown files mode %q", o.Mode)
+	}
+	return nil
+}
+
+// ttl 返回签名链接有效期
+func (o Options) ttl() time.Duration {
+	if o.URLTTL <= 0 {
+		return time.Hour
+	}
+	return time.Duration(o.URLTTL) * time.Second
+}
+
+// uploadResult 上传处理结果，作为请求体发送给业务进程
+type uploadResult struct {
+	Fields map[string]interface{} `json:"fields"`
+	Files  []*Metadata            `json:"files"`
+}
+
+// UploadHandler 创建文件上传处理器
+// 功能：
+// 1. 以流式方式逐个读取multipart分段，文件直接写入磁盘，不在内存中缓存
+// 2. 校验文件数量、大小和类型，失败时删除本次已保存的文件
+// 3. 将普通字段和文件元数据组装为JSON请求体交给 next（通常为IPC转发）；
+//    next 为nil时直接返回元数据，next 返回错误状态码时删除本次保存的文件
+// 参数：
+//   - store *Storage: 附件存储
+//   - opts Options: 文件路由配置
+//   - next http.Handler: 处理元数据的后续处理器，可为nil
+// 返回值：
+//   - http.Handler: 上传处理器
+func UploadHandler(store *Storage, opts Options, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost && req.Method != http.MethodPut {
+			w.Header().Set("Allow", "POST, PUT")
+			middleware.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+			return
+		}
+		reader, err := req.MultipartReader()
+		if err != nil {
+			middleware.WriteError(w, http.StatusBadRequest, "Expected multipart/form-data", nil)
+			return
+		}
+
+		result := uploadResult{Fields: make(map[string]interface{})}
+		fail := func(status int, message string) {
+			for _, meta := range result.Files {
+				store.Remove(meta)
+			}
+			middleware.WriteError(w, status, message, nil)
+		}
+
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				fail(http.StatusBadRequest, "Malformed multipart body")
+				return
+			}
+
+			if part.FileName() == "" {
+				value, err := io.ReadAll(io.LimitReader(part, maxFieldSize+1))
+				part.Close()
+				if err != nil || len(value) > maxFieldSize {
+					fail(http.StatusRequestEntityTooLarge, "Form field too large")
+					return
+				}
+				addField(result.Fields, part.FormName(), string(value))
+				continue
+			}
+
+			if opts.MaxFiles > 0 && len(result.Files) >= opts.MaxFiles {
+				part.Close()
+				fail(http.StatusRequestEntityTooLarge, "Too many files")
+				return
+			}
+			meta, err := store.Save(part, part.FormName(), part.FileName(), opts.Dir, opts.MaxSize, opts.AllowedTypes)
+			part.Close()
+			switch {
+			case errors.Is(err, ErrTooLarge):
+				fail(http.StatusRequestEntityTooLarge, "File too large")
+				return
+			case errors.Is(err, ErrTypeNotAllowed):
+				fail(http.StatusUnsupportedMediaType, err.Error())
+				return
+			case err != nil:
+				log.Println("保存上传文件失败:", err)
+				fail(http.StatusInternalServerError, "Error storing file")
+				return
+			}
+			if opts.DownloadPath != "" {
+				meta.URL = store.SignURL(opts.DownloadPath, meta.ID, opts.ttl())
+			}
+			result.Files = append(result.Files, meta)
+		}
+
+		payload, err := json.Marshal(result)
+		if err != nil {
+			fail(http.StatusInternalServerError, "Error encoding upload result")
+			return
+		}
+		log.Printf("上传完成 %s: %d 个文件", req.URL.Path, len(result.Files))
+
+		if next == nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"status":  http.StatusCreated,
+				"message": "Upload successful",
+				"data":    result,
+			})
+			return
+		}
+
+		// 以元数据替换原始请求体，业务进程只接收文件信息而不是文件内容
+		req.Body = io.NopCloser(bytes.NewReader(payload))
+		req.ContentLength = int64(len(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Length", strconv.Itoa(len(payload)))
+		// 业务进程处理失败时文件不会被引用，删除本次保存的文件和元数据
+		rec := middleware.NewStatusRecorder(w)
+		next.ServeHTTP(rec, req)
+		if rec.Status >= http.StatusBadRequest {
+			for _, meta := range result.Files {
+				store.Remove(meta)
+			}
+		}
+	})
+}
+
+// addField 记录普通表单字段，同名字段合并为数组
+func addField(fields map[string]interface{}, name, value string) {
+	switch existing := fields[name].(type) {
+	case nil:
+		fields[name] = value
+	case string:
+		fields[name] = []interface{}{existing, value}
+	case []interface{}:
+		fields[name] = append(existing, value)
+	}
+}
+
+// DownloadHandler 创建文件下载处理器
+// 功能：
+// 1. 校验签名链接（Public 模式除外）
+// 2. 根据ID查找元数据并返回文件，支持 Range、If-None-Match 等条件请求
+// 参数：
+//   - store *Storage: 附件存储
+//   - opts Options: 文件路由配置
+// 返回值：
+//   - http.Handler: 下载处理器
+func DownloadHandler(store *Storage, opts Options) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			middleware.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+			return
+		}
+		q := req.URL.Query()
+		id := q.Get("id")
+		if id == "" {
+			middleware.WriteError(w, http.StatusBadRequest, "Parameter 'id' is required", nil)
+			return
+		}
+		if !opts.Public {
+			if err := store.VerifyURL(id, q.Get("expires"), q.Get("sig")); err != nil {
+				middleware.WriteError(w, http.StatusForbidden, err.Error(), nil)
+				return
+			}
+		}
+
+		meta, err := store.Lookup(id)
+		if err != nil {
+			middleware.WriteError(w, http.StatusNotFound, "File not found", nil)
+			return
+		}
+		file, err := store.Open(meta)
+		if err != nil {
+			if !errors.Is(err, ErrNotFound) {
+				log.Println("打开附件失败:", err)
+			}
+			middleware.WriteError(w, http.StatusNotFound, "File not found", nil)
+			return
+		}
+		defer file.Close()
+		info, err := file.Stat()
+		if err != nil {
+			middleware.WriteError(w, http.StatusInternalServerError, "Error reading file", nil)
+			return
+		}
+
+		w.Header().Set("Content-Type", meta.ContentType)
+		w.Header().Set("ETag", `"`+meta.SHA256+`"`)
+		// 按 RFC 6266 编码文件名，非ASCII文件名使用 filename*=utf-8''
+		disposition := mime.FormatMediaType("attachment", map[string]string{"filename": meta.Filename})
+		if disposition == "" {
+			disposition = "attachment"
+		}
+		w.Header().Set("Content-Disposition", disposition)
+		http.ServeContent(w, req, meta.Filename, info.ModTime(), file)
+	})
+}
@@ -0,0 +1,49 @@
+package attachment
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// signature 计算附件ID和过期时间的签名
+func (s *Storage) signature(id string, expires int64) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(id + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignURL 生成带过期时间的下载链接
+// 参数：
+//   - downloadPath string: 下载路由路径
+//   - id string: 附件ID
+//   - ttl time.Duration: 有效期
+// 返回值：
+//   - string: 形如 /files/download?id=...&expires=...&sig=... 的链接
+func (s *Storage) SignURL(downloadPath, id string, ttl time.Duration) string {
+	expires := time.Now().Add(ttl).Unix()
+	q := url.Values{}
+	q.Set("id", id)
+	q.Set("expires", strconv.FormatInt(expires, 10))
+	q.Set("sig", s.signature(id, expires))
+	return downloadPath + "?" + q.Encode()
+}
+
+// VerifyURL 校验下载链接的签名和有效期
+func (s *Storage) VerifyURL(id, expires, sig string) error {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return errors.New("invalid expires")
+	}
+	if time.Now().Unix() > exp {
+		return errors.New("download link expired")
+	}
+	if !hmac.Equal([]byte(sig), []byte(s.signature(id, exp))) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
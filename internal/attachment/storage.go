@@ -0,0 +1,284 @@
+package attachment
+
+import (
+	"bigHammer/internal/config"
+	"bigHammer/internal/interface/database"
+	"bigHammer/internal/shared"
+	"bigHammer/pkg/utils"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MetadataPrefix 附件元数据在内存数据库中的键前缀
+const MetadataPrefix = "file:"
+
+var (
+	// ErrTooLarge 附件超过大小限制
+	ErrTooLarge = errors.New("file too large")
+	// ErrTypeNotAllowed 附件类型不在允许列表中
+	ErrTypeNotAllowed = errors.New("file type not allowed")
+	// ErrNotFound 附件不存在
+	ErrNotFound = errors.New("file not found")
+)
+
+// Metadata 附件元数据
+// 上传完成后代替文件内容发送给业务进程，并保存在 AgilityMemDB 中供下载使用
+type Metadata struct {
+	// ID 附件唯一标识（UUIDv4）
+	ID string `json:"id"`
+	// Field 表单字段名
+	Field string `json:"field"`
+	// Filename 客户端提交的原始文件名
+	Filename string `json:"filename"`
+	// ContentType 根据文件内容识别的MIME类型
+	ContentType string `json:"content_type"`
+	// Size 文件字节数
+	Size int64 `json:"size"`
+	// SHA256 文件内容的SHA-256摘要（十六进制）
+	SHA256 string `json:"sha256"`
+	// Path 相对附件存储根目录的路径
+	Path string `json:"path"`
+	// CreatedAt 上传时间（RFC3339）
+	CreatedAt string `json:"created_at"`
+	// URL 带签名的下载地址，仅在配置了下载路由时返回
+	URL string `json:"url,omitempty"`
+}
+
+// metadataPersistInterval 附件元数据持久化到数据文件的间隔
+const metadataPersistInterval = 10 * time.Second
+
+// Storage 基于本地目录的附件存储
+type Storage struct {
+	// Root 附件存储根目录
+	Root string
+	db   database.IDatabase
+	// signingKey 下载链接签名密钥
+	signingKey []byte
+
+	mu sync.Mutex
+	// dirty 上次持久化之后元数据是否有变更
+	dirty bool
+}
+
+// NewStorage 创建附件存储
+// 参数：
+//   - root string: 存储根目录，不存在时自动创建
+//   - db database.IDatabase: 保存附件元数据的数据库
+//   - signingKey []byte: 下载链接签名密钥
+// 返回值：
+//   - *Storage: 附件存储
+//   - error: 创建目录失败时返回的错误信息
+func NewStorage(root string, db database.IDatabase, signingKey []byte) (*Storage, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("error creating attachment storage: %v", err)
+	}
+	s := &Storage{Root: root, db: db, signingKey: signingKey}
+	go s.persistLoop()
+	return s, nil
+}
+
+// markDirty 记录元数据有变更，由 persistLoop 定期持久化
+func (s *Storage) markDirty() {
+	s.mu.Lock()
+	s.dirty = true
+	s.mu.Unlock()
+}
+
+// persistLoop 定期将有变更的附件元数据持久化，避免每次上传都重写整个数据文件
+// 退出前的最后一次变更由主程序退出时的持久化写入
+func (s *Storage) persistLoop() {
+	ticker := time.NewTicker(metadataPersistInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.mu.Lock()
+		dirty := s.dirty
+		s.dirty = false
+		s.mu.Unlock()
+		if !dirty {
+			continue
+		}
+		if err := s.db.Persist(); err != nil {
+			log.Println("持久化附件元数据失败:", err)
+		}
+	}
+}
+
+var (
+	defaultStorage    *Storage
+	defaultStorageErr error
+	defaultOnce       sync.Once
+)
+
+// DefaultStorage 返回基于全局配置 attachment_storage 的附件存储
+// 未配置 attachment_signing_key 时使用进程随机密钥，重启后已签发的链接失效
+func DefaultStorage() (*Storage, error) {
+	defaultOnce.Do(func() {
+		root, err := utils.ResolvePath(config.GlobalConfig.AttachmentStorage)
+		if err != nil {
+			defaultStorageErr = fmt.Errorf("error resolving attachment storage: %v", err)
+			return
+		}
+		db, err := shared.ResolveDatabase()
+		if err != nil {
+			defaultStorageErr = err
+			return
+		}
+		key := []byte(config.GlobalConfig.AttachmentSigningKey)
+		if len(key) == 0 {
+			log.Println("未配置 attachment_signing_key，使用随机密钥签名下载链接")
+			key = make([]byte, 32)
+			if _, err := rand.Read(key); err != nil {
+				defaultStorageErr = fmt.Errorf("error generating signing key: %v", err)
+				return
+			}
+		}
+		defaultStorage, defaultStorageErr = NewStorage(root, db, key)
+	})
+	return defaultStorage, defaultStorageErr
+}
+
+// typeAllowed 判断MIME类型是否匹配允许列表，支持 image/* 形式的通配
+func typeAllowed(contentType string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	for _, pattern := range allowed {
+		if pattern == "*/*" || strings.EqualFold(pattern, mediaType) {
+			return true
+		}
+		if strings.HasSuffix(pattern, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// Save 将文件流写入存储
+// 功能：
+// 1. 根据前512字节识别文件类型并校验允许列表
+// 2. 边写入磁盘边计算SHA-256，超过大小限制时中止并删除临时文件
+// 3. 保存元数据到数据库
+// 参数：
+//   - r io.Reader: 文件内容
+//   - field string: 表单字段名
+//   - filename string: 原始文件名
+//   - subdir string: 存储子目录
+//   - maxSize int64: 最大字节数，0表示不限制
+//   - allowed []string: 允许的MIME类型
+// 返回值：
+//   - *Metadata: 附件元数据
+//   - error: 超限（ErrTooLarge）、类型不允许（ErrTypeNotAllowed）或写入失败时返回的错误信息
+func (s *Storage) Save(r io.Reader, field, filename, subdir string, maxSize int64, allowed []string) (*Metadata, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, fmt.Errorf("error reading upload: %v", err)
+	}
+	head = head[:n]
+	contentType := http.DetectContentType(head)
+	if !typeAllowed(contentType, allowed) {
+		return nil, fmt.Errorf("%w: %s", ErrTypeNotAllowed, contentType)
+	}
+
+	id := uuid.New().String()
+	now := time.Now()
+	ext := strings.ToLower(filepath.Ext(filename))
+	relPath := path.Join(path.Clean("/" + subdir)[1:], now.Format("2006/01/02"), id+ext)
+	fullPath := filepath.Join(s.Root, filepath.FromSlash(relPath))
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return nil, fmt.Errorf("error creating upload directory: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(fullPath), ".upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("error creating upload file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hasher := sha256.New()
+	src := io.MultiReader(bytes.NewReader(head), r)
+	if maxSize > 0 {
+		src = io.LimitReader(src, maxSize+1)
+	}
+	size, err := io.Copy(io.MultiWriter(tmp, hasher), src)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error writing upload: %v", err)
+	}
+	if maxSize > 0 && size > maxSize {
+		return nil, ErrTooLarge
+	}
+	if err := os.Rename(tmp.Name(), fullPath); err != nil {
+		return nil, fmt.Errorf("error storing upload: %v", err)
+	}
+
+	meta := &Metadata{
+		ID:          id,
+		Field:       field,
+		Filename:    filepath.Base(filename),
+		ContentType: contentType,
+		Size:        size,
+		SHA256:      hex.EncodeToString(hasher.Sum(nil)),
+		Path:        relPath,
+		CreatedAt:   now.Format(time.RFC3339),
+	}
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Put(MetadataPrefix+id, string(data)); err != nil {
+		os.Remove(fullPath)
+		return nil, fmt.Errorf("error saving file metadata: %v", err)
+	}
+	s.markDirty()
+	return meta, nil
+}
+
+// Remove 删除附件文件及其元数据
+func (s *Storage) Remove(meta *Metadata) {
+	os.Remove(filepath.Join(s.Root, filepath.FromSlash(meta.Path)))
+	s.db.Delete(MetadataPrefix + meta.ID)
+	s.markDirty()
+}
+
+// Lookup 根据ID查找附件元数据
+func (s *Storage) Lookup(id string) (*Metadata, error) {
+	value, ok := s.db.Get(MetadataPrefix + id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	var meta Metadata
+	if err := json.Unmarshal([]byte(value), &meta); err != nil {
+		return nil, fmt.Errorf("invalid file metadata: %v", err)
+	}
+	return &meta, nil
+}
+
+// Open 打开附件文件
+func (s *Storage) Open(meta *Metadata) (*os.File, error) {
+	f, err := os.Open(filepath.Join(s.Root, filepath.FromSlash(meta.Path)))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
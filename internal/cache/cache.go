@@ -0,0 +1,205 @@
+package cache
+
+import (
+	"bigHammer/internal/middleware/auth"
+	"bigHammer/internal/shared"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options 响应缓存配置，对应 router.json 中路由的 cache 字段
+type Options struct {
+	// TTL 缓存秒数，响应头中的 max-age/s-maxage 优先，默认60
+	TTL int `json:"ttl,omitempty"`
+	// Storage 缓存存储：memory（进程内LRU，默认）或 memdb（AgilityMemDB，重启后保留）
+	Storage string `json:"storage,omitempty"`
+	// MaxEntries 路由最多缓存条目数，默认1000
+	MaxEntries int `json:"max_entries,omitempty"`
+	// MaxBodySize 可缓存的最大响应体字节数，默认1MB
+	MaxBodySize int64 `json:"max_body_size,omitempty"`
+	// Query 参与缓存键的查询参数，为空时使用完整查询字符串
+	Query []string `json:"query,omitempty"`
+	// Headers 参与缓存键的请求头
+	Headers []string `json:"headers,omitempty"`
+	// PerIdentity 是否按认证身份区分缓存（响应内容与调用方相关时开启）
+	PerIdentity bool `json:"per_identity,omitempty"`
+	// Public 响应与调用方无关，携带凭证的请求也使用共享缓存
+	// 未开启 public 和 per_identity 时，携带凭证或已认证的请求不查询也不写入缓存
+	Public bool `json:"public,omitempty"`
+}
+
+// Validate 校验缓存配置
+func (o Options) Validate() error {
+	if o.Storage != "" && o.Storage != "memory" && o.Storage != "memdb" {
+		return fmt.Errorf("unknown cache storage %q", o.Storage)
+	}
+	return nil
+}
+
+func (o Options) ttl() time.Duration {
+	if o.TTL <= 0 {
+		return time.Minute
+	}
+	return time.Duration(o.TTL) * time.Second
+}
+
+func (o Options) maxEntries() int {
+	if o.MaxEntries <= 0 {
+		return 1000
+	}
+	return o.MaxEntries
+}
+
+func (o Options) maxBodySize() int64 {
+	if o.MaxBodySize <= 0 {
+		return 1 << 20
+	}
+	return o.MaxBodySize
+}
+
+// Cache 单个路由的响应缓存
+type Cache struct {
+	Route string
+	opts  Options
+	store Store
+}
+
+var (
+	registryMu sync.RWMutex
+	// registry 已创建的路由缓存，供清除接口使用
+	// key: 路由路径
+	registry = make(map[string]*Cache)
+)
+
+// New 创建路由响应缓存，创建后需通过 Register 登记才能被清除接口看到
+// 参数：
+//   - route string: 路由名称
+//   - opts Options: 缓存配置
+// 返回值：
+//   - *Cache: 路由缓存
+//   - error: 配置无效或内存数据库不可用时返回的错误信息
+func New(route string, opts Options) (*Cache, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	c := &Cache{Route: route, opts: opts}
+	if opts.Storage == "memdb" {
+		db, err := shared.ResolveDatabase()
+		if err != nil {
+			return nil, err
+		}
+		c.store = newDBStore(db, route, opts.maxEntries())
+	} else {
+		c.store = newMemoryStore(opts.maxEntries())
+	}
+	return c, nil
+}
+
+// Register 以本次编译的路由缓存替换全局注册表
+// 路由编译成功后调用，编译失败时注册表仍指向旧路由正在使用的缓存
+// 参数：
+//   - caches []*Cache: 生效路由使用的所有缓存
+// 返回值：无
+func Register(caches []*Cache) {
+	next := make(map[string]*Cache, len(caches))
+	for _, c := range caches {
+		next[c.Route] = c
+	}
+	registryMu.Lock()
+	registry = next
+	registryMu.Unlock()
+}
+
+// Purge 清除缓存
+// 参数：
+//   - route string: 路由名称（路由路径，配置了主机名时以主机名开头），为空时清除所有路由的缓存；
+//     流量拆分路由的各变体缓存名为 路由名称@变体名，按路由名称清除时一并清除
+// 返回值：
+//   - int: 清除的条目数
+//   - error: 路由未启用缓存时返回的错误信息
+func Purge(route string) (int, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	n, found := 0, false
+	for name, c := range registry {
+		if route == "" || name == route || strings.HasPrefix(name, route+"@") {
+			n += c.store.Purge()
+			found = true
+		}
+	}
+	if !found && route != "" {
+		return 0, fmt.Errorf("route %s has no cache", route)
+	}
+	return n, nil
+}
+
+// Stats 返回各路由的缓存条目数
+func Stats() map[string]int {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	stats := make(map[string]int, len(registry))
+	for route, c := range registry {
+		stats[route] = c.store.Len()
+	}
+	return stats
+}
+
+// cacheable 判断请求能否使用缓存
+// 携带 Authorization、X-API-Key 或已通过认证的请求，响应可能只属于该调用方：
+// 开启 public 时共用缓存，开启 per_identity 且已认证时按身份缓存，否则绕过缓存
+func (c *Cache) cacheable(req *http.Request) bool {
+	id := auth.IdentityFromContext(req.Context())
+	if id == nil && req.Header.Get("Authorization") == "" && req.Header.Get("X-API-Key") == "" {
+		return true
+	}
+	return c.opts.Public || (c.opts.PerIdentity && id != nil)
+}
+
+// key 计算请求的缓存键
+// 由主机名、请求路径、查询参数、配置的请求头和（可选）认证身份组成，取SHA-256摘要
+// 模板路由和 http 前缀路由的不同路径（如 /users/1 和 /users/2）使用不同的缓存条目
+func (c *Cache) key(req *http.Request) string {
+	var b strings.Builder
+	b.WriteString(normalizeHost(req.Host) + "\n" + req.URL.Path + "\n")
+	if len(c.opts.Query) == 0 {
+		// 参数顺序不影响缓存命中
+		b.WriteString(req.URL.Query().Encode())
+	} else {
+		query := req.URL.Query()
+		selected := url.Values{}
+		for _, name := range c.opts.Query {
+			if values, ok := query[name]; ok {
+				selected[name] = values
+			}
+		}
+		b.WriteString(selected.Encode())
+	}
+	headers := append([]string(nil), c.opts.Headers...)
+	sort.Strings(headers)
+	for _, name := range headers {
+		b.WriteString("\n" + strings.ToLower(name) + ":" + strings.Join(req.Header.Values(name), ","))
+	}
+	if c.opts.PerIdentity {
+		if id := auth.IdentityFromContext(req.Context()); id != nil {
+			b.WriteString("\nidentity:" + id.Method + ":" + id.Subject)
+		}
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:16])
+}
+
+// normalizeHost 去掉端口和末尾的点并转为小写
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
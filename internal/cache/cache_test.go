@@ -0,0 +1,176 @@
+package cache
+
+import (
+	"bigHammer/internal/middleware/auth"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  Options
+		a, b  func() *http.Request
+		equal bool
+	}{
+		{
+			name:  "query order ignored",
+			a:     get("http://api.example.com/items?a=1&b=2"),
+			b:     get("http://api.example.com/items?b=2&a=1"),
+			equal: true,
+		},
+		{
+			name: "different path",
+			a:    get("http://api.example.com/users/1"),
+			b:    get("http://api.example.com/users/2"),
+		},
+		{
+			name: "different host",
+			a:    get("http://a.example.com/items"),
+			b:    get("http://b.example.com/items"),
+		},
+		{
+			name:  "host case, port and trailing dot ignored",
+			a:     get("http://API.example.com:8080/items"),
+			b:     get("http://api.example.com./items"),
+			equal: true,
+		},
+		{
+			name: "different query",
+			a:    get("http://api.example.com/items?page=1"),
+			b:    get("http://api.example.com/items?page=2"),
+		},
+		{
+			name:  "unselected query ignored",
+			opts:  Options{Query: []string{"page"}},
+			a:     get("http://api.example.com/items?page=1&ts=1"),
+			b:     get("http://api.example.com/items?ts=2&page=1"),
+			equal: true,
+		},
+		{
+			name: "selected header",
+			opts: Options{Headers: []string{"Accept-Language"}},
+			a:    withHeader(get("http://api.example.com/items"), "Accept-Language", "en"),
+			b:    withHeader(get("http://api.example.com/items"), "Accept-Language", "fr"),
+		},
+		{
+			name:  "unselected header ignored",
+			a:     withHeader(get("http://api.example.com/items"), "Accept-Language", "en"),
+			b:     withHeader(get("http://api.example.com/items"), "Accept-Language", "fr"),
+			equal: true,
+		},
+		{
+			name: "per identity",
+			opts: Options{PerIdentity: true},
+			a:    withIdentity(get("http://api.example.com/me"), "alice"),
+			b:    withIdentity(get("http://api.example.com/me"), "bob"),
+		},
+		{
+			name:  "identity ignored when not per identity",
+			a:     withIdentity(get("http://api.example.com/me"), "alice"),
+			b:     withIdentity(get("http://api.example.com/me"), "bob"),
+			equal: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Cache{Route: "/items", opts: tt.opts}
+			ka, kb := c.key(tt.a()), c.key(tt.b())
+			if (ka == kb) != tt.equal {
+				t.Fatalf("key equal = %v, want %v", ka == kb, tt.equal)
+			}
+		})
+	}
+}
+
+func get(target string) func() *http.Request {
+	return func() *http.Request {
+		return httptest.NewRequest(http.MethodGet, target, nil)
+	}
+}
+
+func withHeader(req func() *http.Request, name, value string) func() *http.Request {
+	return func() *http.Request {
+		r := req()
+		r.Header.Set(name, value)
+		return r
+	}
+}
+
+func withIdentity(req func() *http.Request, subject string) func() *http.Request {
+	return func() *http.Request {
+		r := req()
+		return r.WithContext(auth.WithIdentity(r.Context(), &auth.Identity{Subject: subject, Method: "api_key"}))
+	}
+}
+
+func TestHandlerCredentials(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		// want 各请求依次得到的响应体，后端按调用方返回不同内容
+		want []string
+		// calls 后端被调用的次数
+		calls int
+	}{
+		{name: "not shared by default", want: []string{"alice", "bob", "anonymous", "anonymous", "alice"}, calls: 4},
+		{name: "per identity", opts: Options{PerIdentity: true}, want: []string{"alice", "bob", "anonymous", "anonymous", "alice"}, calls: 3},
+		{name: "public", opts: Options{Public: true}, want: []string{"alice", "alice", "alice", "alice", "alice"}, calls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New("/me", tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			calls := 0
+			handler := Handler(c, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				calls++
+				body := "anonymous"
+				if id := auth.IdentityFromContext(req.Context()); id != nil {
+					body = id.Subject
+				}
+				w.Write([]byte(body))
+			}))
+			requests := []func() *http.Request{
+				withIdentity(withHeader(get("http://api.example.com/me"), "Authorization", "Bearer a"), "alice"),
+				withIdentity(withHeader(get("http://api.example.com/me"), "Authorization", "Bearer b"), "bob"),
+				get("http://api.example.com/me"),
+				get("http://api.example.com/me"),
+				withIdentity(withHeader(get("http://api.example.com/me"), "Authorization", "Bearer a"), "alice"),
+			}
+			for i, req := range requests {
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req())
+				if got := rec.Body.String(); got != tt.want[i] {
+					t.Fatalf("request %d body = %q, want %q", i, got, tt.want[i])
+				}
+			}
+			if calls != tt.calls {
+				t.Fatalf("backend calls = %d, want %d", calls, tt.calls)
+			}
+		})
+	}
+
+	// 未经网关认证但携带凭证的请求同样不进入共享缓存
+	c, err := New("/raw", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := 0
+	handler := Handler(c, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		w.Write([]byte(req.Header.Get("X-API-Key")))
+	}))
+	for _, key := range []string{"k1", "k2"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, withHeader(get("http://api.example.com/raw"), "X-API-Key", key)())
+		if rec.Body.String() != key {
+			t.Fatalf("body = %q, want %q", rec.Body.String(), key)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("backend calls = %d, want 2", calls)
+	}
+}
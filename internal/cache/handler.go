@@ -0,0 +1,249 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	lookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_cache_lookups_total",
+		Help: "响应缓存查询次数，result 取值 hit / miss / bypass",
+	}, []string{"route", "result"})
+)
+
+// cacheControl 解析 Cache-Control 头
+// 返回值：指令名（小写）到指令值的映射，无值指令的值为空字符串
+func cacheControl(value string) map[string]string {
+	directives := make(map[string]string)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, arg, _ := strings.Cut(part, "=")
+		directives[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(arg), `"`)
+	}
+	return directives
+}
+
+// etagMatches 判断 If-None-Match 是否匹配ETag（弱比较）
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" || etag == "" {
+		return false
+	}
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// Handler 返回带响应缓存的处理器
+// 功能：
+// 1. 只缓存 GET/HEAD 请求的200响应，HEAD 与 GET 共用缓存
+// 2. 请求 Cache-Control: no-store 时绕过缓存；no-cache 或 max-age=0 时跳过查询并刷新缓存
+// 3. 携带凭证的请求只在配置了 public 或 per_identity 时使用缓存，见 Cache.cacheable
+// 4. 响应 Cache-Control 包含 no-store/private 或设置了 Cookie 时不缓存；s-maxage/max-age 优先于配置的TTL
+// 5. 为缓存的响应生成ETag，If-None-Match 匹配时返回304
+// 参数：
+//   - c *Cache: 路由缓存
+//   - next http.Handler: 后端处理器
+// 返回值：
+//   - http.Handler: 带缓存的处理器
+func Handler(c *Cache, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			next.ServeHTTP(w, req)
+			return
+		}
+		reqCC := cacheControl(req.Header.Get("Cache-Control"))
+		if _, ok := reqCC["no-store"]; ok || !c.cacheable(req) {
+			lookups.WithLabelValues(c.Route, "bypass").Inc()
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		key := c.key(req)
+		_, noCache := reqCC["no-cache"]
+		revalidate := noCache || reqCC["max-age"] == "0"
+		now := time.Now()
+		if !revalidate {
+			if entry, ok := c.store.Get(key); ok && entry.Fresh(now) {
+				lookups.WithLabelValues(c.Route, "hit").Inc()
+				serveEntry(w, req, entry, now)
+				return
+			}
+		}
+		lookups.WithLabelValues(c.Route, "miss").Inc()
+
+		before := w.Header().Clone()
+		cw := &captureWriter{ResponseWriter: w, status: http.StatusOK, limit: c.opts.maxBodySize()}
+		next.ServeHTTP(cw, req)
+		if cw.passthrough {
+			return
+		}
+		// HEAD 请求的响应没有响应体，不写入缓存
+		if req.Method != http.MethodGet {
+			cw.flushBuffered()
+			return
+		}
+
+		entry := c.buildEntry(cw, before, now)
+		if entry != nil {
+			c.store.Set(key, entry)
+			w.Header().Set("ETag", entry.ETag)
+			w.Header().Set("X-Cache", "MISS")
+			if etagMatches(req.Header.Get("If-None-Match"), entry.ETag) {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		cw.flushBuffered()
+	})
+}
+
+// buildEntry 根据后端响应构造缓存条目，不可缓存时返回nil
+// before 为调用后端前的响应头，只保存后端新增或修改的响应头（请求ID、限流等头不进入缓存）
+func (c *Cache) buildEntry(cw *captureWriter, before http.Header, now time.Time) *Entry {
+	header := cw.Header()
+	if cw.status != http.StatusOK || header.Get("Set-Cookie") != "" {
+		return nil
+	}
+	respCC := cacheControl(header.Get("Cache-Control"))
+	if _, ok := respCC["no-store"]; ok {
+		return nil
+	}
+	if _, ok := respCC["private"]; ok {
+		return nil
+	}
+	ttl := c.opts.ttl()
+	for _, directive := range []string{"s-maxage", "max-age"} {
+		if value, ok := respCC[directive]; ok {
+			if seconds, err := strconv.Atoi(value); err == nil {
+				ttl = time.Duration(seconds) * time.Second
+				break
+			}
+		}
+	}
+	if ttl <= 0 {
+		return nil
+	}
+
+	stored := make(http.Header)
+	for name, values := range header {
+		if prev, ok := before[name]; ok && strings.Join(prev, "\x00") == strings.Join(values, "\x00") {
+			continue
+		}
+		stored[name] = append([]string(nil), values...)
+	}
+	etag := header.Get("ETag")
+	if etag == "" {
+		sum := sha256.Sum256(cw.buf.Bytes())
+		etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+	}
+	stored.Del("Content-Length")
+	stored.Set("ETag", etag)
+	return &Entry{
+		Status:    cw.status,
+		Header:    stored,
+		Body:      append([]byte(nil), cw.buf.Bytes()...),
+		ETag:      etag,
+		StoredAt:  now,
+		ExpiresAt: now.Add(ttl),
+	}
+}
+
+// serveEntry 使用缓存条目响应请求
+func serveEntry(w http.ResponseWriter, req *http.Request, entry *Entry, now time.Time) {
+	h := w.Header()
+	for name, values := range entry.Header {
+		h[name] = append([]string(nil), values...)
+	}
+	h.Set("Age", strconv.Itoa(int(now.Sub(entry.StoredAt).Seconds())))
+	h.Set("X-Cache", "HIT")
+	if etagMatches(req.Header.Get("If-None-Match"), entry.ETag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	h.Set("Content-Length", strconv.Itoa(len(entry.Body)))
+	w.WriteHeader(entry.Status)
+	if req.Method != http.MethodHead {
+		w.Write(entry.Body)
+	}
+}
+
+// captureWriter 缓冲后端响应以便写入缓存
+// 响应体超过 limit 或后端主动 Flush（流式响应）时切换为直接透传，不再缓存
+type captureWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	buf         bytes.Buffer
+	limit       int64
+	passthrough bool
+}
+
+func (cw *captureWriter) WriteHeader(status int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+	cw.status = status
+}
+
+func (cw *captureWriter) Write(b []byte) (int, error) {
+	cw.WriteHeader(http.StatusOK)
+	if cw.passthrough {
+		return cw.ResponseWriter.Write(b)
+	}
+	if int64(cw.buf.Len()+len(b)) > cw.limit {
+		cw.startPassthrough()
+		return cw.ResponseWriter.Write(b)
+	}
+	return cw.buf.Write(b)
+}
+
+// Flush 实现 http.Flusher 接口，流式响应不缓存
+func (cw *captureWriter) Flush() {
+	cw.WriteHeader(http.StatusOK)
+	if !cw.passthrough {
+		cw.startPassthrough()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap 返回底层 ResponseWriter
+func (cw *captureWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// startPassthrough 写出已缓冲的内容并切换为透传
+func (cw *captureWriter) startPassthrough() {
+	cw.passthrough = true
+	cw.flushBuffered()
+}
+
+// flushBuffered 写出状态码和已缓冲的响应体
+func (cw *captureWriter) flushBuffered() {
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if cw.buf.Len() > 0 {
+		cw.ResponseWriter.Write(cw.buf.Bytes())
+		cw.buf.Reset()
+	}
+}
@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"bigHammer/internal/plugin"
+	"fmt"
+)
+
+// Plugin 响应缓存管理插件
+// 通过主Socket发送 service=cache 的请求管理缓存，支持的方法：
+//   - purge: 参数 route（为空时清除所有路由的缓存）
+//   - stats: 返回各路由的缓存条目数
+type Plugin struct{}
+
+// HandleRequest 实现 ServicePlugin 接口的请求处理方法
+func (p *Plugin) HandleRequest(req plugin.Request) plugin.Response {
+	switch req.Method {
+	case "purge":
+		n, err := Purge(req.Params["route"])
+		if err != nil {
+			return plugin.Response{Status: 404, Message: err.Error()}
+		}
+		return plugin.Response{Status: 200, Message: "Purged", Data: map[string]int{"purged": n}}
+	case "stats":
+		return plugin.Response{Status: 200, Message: "OK", Data: Stats()}
+	default:
+		return plugin.Response{Status: 400, Message: fmt.Sprintf("Unknown method %s", req.Method)}
+	}
+}
+
+// init 包初始化函数
+// 功能：
+// 1. 注册 cache 插件到插件系统
+// 参数：无
+// 返回值：无
+func init() {
+	plugin.RegisterPlugin("cache", &Plugin{})
+}
@@ -0,0 +1,191 @@
+package cache
+
+import (
+	"bigHammer/internal/interface/database"
+	"container/list"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Entry 缓存的响应
+type Entry struct {
+	Status    int         `json:"status"`
+	Header    http.Header `json:"header"`
+	Body      []byte      `json:"body"`
+	ETag      string      `json:"etag"`
+	StoredAt  time.Time   `json:"stored_at"`
+	ExpiresAt time.Time   `json:"expires_at"`
+}
+
+// Fresh 判断缓存是否仍在有效期内
+func (e *Entry) Fresh(now time.Time) bool {
+	return now.Before(e.ExpiresAt)
+}
+
+// Store 缓存存储
+type Store interface {
+	// Get 查询缓存，不存在时返回 false
+	Get(key string) (*Entry, bool)
+	// Set 写入缓存，超出容量时淘汰最久未使用的条目
+	Set(key string, entry *Entry)
+	// Purge 清空全部缓存，返回清除的条目数
+	Purge() int
+	// Len 返回缓存条目数
+	Len() int
+}
+
+// memoryStore 进程内LRU缓存
+type memoryStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List
+	items      map[string]*list.Element
+}
+
+type lruItem struct {
+	key   string
+	entry *Entry
+}
+
+// newMemoryStore 创建进程内LRU缓存
+func newMemoryStore(maxEntries int) *memoryStore {
+	return &memoryStore{maxEntries: maxEntries, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (s *memoryStore) Get(key string) (*Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+	s.ll.MoveToFront(el)
+	return el.Value.(*lruItem).entry, true
+}
+
+func (s *memoryStore) Set(key string, entry *Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.items[key]; ok {
+		el.Value.(*lruItem).entry = entry
+		s.ll.MoveToFront(el)
+		return
+	}
+	s.items[key] = s.ll.PushFront(&lruItem{key: key, entry: entry})
+	for s.ll.Len() > s.maxEntries {
+		oldest := s.ll.Back()
+		s.ll.Remove(oldest)
+		delete(s.items, oldest.Value.(*lruItem).key)
+	}
+}
+
+func (s *memoryStore) Purge() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.ll.Len()
+	s.ll.Init()
+	s.items = make(map[string]*list.Element)
+	return n
+}
+
+func (s *memoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ll.Len()
+}
+
+// EntryPrefix 缓存条目在内存数据库中的键前缀
+const EntryPrefix = "cache:"
+
+// indexPrefix 路由缓存键索引在内存数据库中的键前缀，用于容量控制和清除
+const indexPrefix = "cacheidx:"
+
+// dbStore 基于 AgilityMemDB 的缓存，网关重启后仍然有效
+// 每个路由维护一份按写入顺序排列的键索引，超出容量时淘汰最早写入的条目
+type dbStore struct {
+	mu         sync.Mutex
+	db         database.IDatabase
+	route      string
+	maxEntries int
+}
+
+// newDBStore 创建基于内存数据库的缓存
+func newDBStore(db database.IDatabase, route string, maxEntries int) *dbStore {
+	return &dbStore{db: db, route: route, maxEntries: maxEntries}
+}
+
+func (s *dbStore) Get(key string) (*Entry, bool) {
+	value, ok := s.db.Get(EntryPrefix + s.route + ":" + key)
+	if !ok {
+		return nil, false
+	}
+	var entry Entry
+	if err := json.Unmarshal([]byte(value), &entry); err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+func (s *dbStore) Set(key string, entry *Entry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.db.Put(EntryPrefix+s.route+":"+key, string(data)); err != nil {
+		log.Println("写入响应缓存失败:", err)
+		return
+	}
+
+	index := s.index()
+	for i, k := range index {
+		if k == key {
+			index = append(index[:i], index[i+1:]...)
+			break
+		}
+	}
+	index = append(index, key)
+	for len(index) > s.maxEntries {
+		s.db.Delete(EntryPrefix + s.route + ":" + index[0])
+		index = index[1:]
+	}
+	s.saveIndex(index)
+}
+
+func (s *dbStore) Purge() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	index := s.index()
+	for _, key := range index {
+		s.db.Delete(EntryPrefix + s.route + ":" + key)
+	}
+	s.db.Delete(indexPrefix + s.route)
+	return len(index)
+}
+
+func (s *dbStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.index())
+}
+
+// index 读取路由的缓存键索引
+func (s *dbStore) index() []string {
+	var index []string
+	if value, ok := s.db.Get(indexPrefix + s.route); ok {
+		json.Unmarshal([]byte(value), &index)
+	}
+	return index
+}
+
+// saveIndex 保存路由的缓存键索引
+func (s *dbStore) saveIndex(index []string) {
+	data, _ := json.Marshal(index)
+	if err := s.db.Put(indexPrefix+s.route, string(data)); err != nil {
+		log.Println("写入响应缓存索引失败:", err)
+	}
+}
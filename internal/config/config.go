@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"time"
 )
 
 // Config 结构体定义了所有的配置项
@@ -25,6 +26,12 @@ type Config struct {
 	// AttachmentStorage 附件存储路径
 	// 用于存储系统附件的目录路径
 	AttachmentStorage   string      `json:"attachment_storage"`
+	// AttachmentSigningKey 附件下载链接签名密钥
+	// 用于生成和校验带过期时间的下载链接，为空时每次启动随机生成
+	AttachmentSigningKey string     `json:"attachment_signing_key"`
+	// JobCallbackSecret 异步任务回调签名密钥
+	// 配置后回调请求携带 X-Signature 头（HMAC-SHA256），为空时不签名
+	JobCallbackSecret   string      `json:"job_callback_secret"`
 	// SocketPath Socket文件路径
 	// 用于Unix Domain Socket通信的文件路径
 	SocketPath          string      `json:"socket_path"`
@@ -37,12 +44,157 @@ type Config struct {
 	// PluginsPath 插件目录路径
 	// 用于存储系统插件的目录路径
 	PluginsPath         string      `json:"plugins_path"`
+	// PluginSchemas 插件方法参数的 JSON Schema 文件
+	// key: 服务名.方法名，例如 cache.purge
+	PluginSchemas       map[string]string `json:"plugin_schemas"`
 	// BussinessMainPath 业务主程序路径
 	// 用于存储业务主程序的文件路径
 	BussinessMainPath   string      `json:"bussiness_main_path"`
+	// MetricsPath Prometheus指标路径
+	// 指标包含路由、后端和调用方信息，默认只在管理接口的 /metrics 上提供（需要访问令牌）；
+	// 配置后同时在HTTP服务的该路径上公开暴露
+	MetricsPath         string      `json:"metrics_path"`
 	// Ports 端口配置
 	// 包含系统使用的所有端口配置
 	Ports               PortsConfig `json:"ports"`
+	// TLS HTTPS配置
+	// 证书、协议版本和加密套件策略
+	TLS                 TLSConfig   `json:"tls"`
+	// OpenAPI OpenAPI文档配置
+	// 文档根据路由配置自动生成
+	OpenAPI             OpenAPIConfig `json:"openapi"`
+	// Admin 管理接口配置
+	// 运行时查看路由、连接、后端状态，以及重新加载配置、重启业务进程
+	Admin               AdminConfig `json:"admin"`
+	// AccessLog 访问日志配置
+	// 每个HTTP请求输出一条结构化记录
+	AccessLog           AccessLogConfig `json:"access_log"`
+	// Shutdown 优雅退出配置
+	// 收到退出信号后先排空在途请求，再停止业务进程
+	Shutdown            ShutdownConfig `json:"shutdown"`
+}
+
+// ShutdownConfig 定义了优雅退出和就绪检查配置
+type ShutdownConfig struct {
+	// DrainTimeout 等待在途HTTP请求、IPC调用和异步请求完成的最长秒数，默认30
+	DrainTimeout int    `json:"drain_timeout"`
+	// ReadyDelay 标记为未就绪后、停止接收新请求前的等待秒数，留给负载均衡摘除流量，默认0
+	ReadyDelay   int    `json:"ready_delay"`
+	// ReadyPath 就绪检查路径，排空期间返回503，为空时使用 /readyz
+	ReadyPath    string `json:"ready_path"`
+	// HealthPath 存活检查路径，为空时使用 /healthz
+	HealthPath   string `json:"health_path"`
+}
+
+// AccessLogConfig 定义了访问日志配置
+type AccessLogConfig struct {
+	// Disabled 是否关闭访问日志
+	Disabled bool   `json:"disabled"`
+	// Format 输出格式："json"（默认，每行一个JSON对象）或 "text"
+	Format   string `json:"format"`
+	// Output 输出位置："stdout"（默认）、"stderr" 或日志文件路径（相对项目根目录，追加写入）
+	Output   string `json:"output"`
+}
+
+// AdminConfig 定义了管理接口监听配置
+type AdminConfig struct {
+	// Enabled 是否启用管理接口
+	Enabled    bool   `json:"enabled"`
+	// Host 监听地址，默认 127.0.0.1
+	Host       string `json:"host"`
+	// Port 监听端口，配置了 socket_path 时忽略
+	Port       string `json:"port"`
+	// SocketPath Unix Socket路径，配置后只在该Socket上监听
+	SocketPath string `json:"socket_path"`
+	// Token 访问令牌，请求需携带 Authorization: Bearer <token>，为空时不启动管理接口
+	Token      string `json:"token"`
+}
+
+// OpenAPIConfig 定义了OpenAPI文档配置
+type OpenAPIConfig struct {
+	// Path HTTP服务上提供文档的路径，为空时使用 /openapi.json
+	Path        string   `json:"path"`
+	// Disabled 是否关闭文档接口（命令行导出不受影响）
+	Disabled    bool     `json:"disabled"`
+	// Title 文档标题
+	Title       string   `json:"title"`
+	// Version API版本
+	Version     string   `json:"version"`
+	// Description API说明
+	Description string   `json:"description"`
+	// Servers 服务地址列表，例如 https://api.example.com
+	Servers     []string `json:"servers"`
+}
+
+// DrainTimeoutDuration 返回等待在途工作完成的最长时间
+func (c ShutdownConfig) DrainTimeoutDuration() time.Duration {
+	if c.DrainTimeout <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(c.DrainTimeout) * time.Second
+}
+
+// ReadinessPath 返回就绪检查路径，未配置时为 /readyz
+func (c ShutdownConfig) ReadinessPath() string {
+	if c.ReadyPath == "" {
+		return "/readyz"
+	}
+	return c.ReadyPath
+}
+
+// LivenessPath 返回存活检查路径，未配置时为 /healthz
+func (c ShutdownConfig) LivenessPath() string {
+	if c.HealthPath == "" {
+		return "/healthz"
+	}
+	return c.HealthPath
+}
+
+// DocumentPath 返回OpenAPI文档路径，未配置时为 /openapi.json
+func (c OpenAPIConfig) DocumentPath() string {
+	if c.Path == "" {
+		return "/openapi.json"
+	}
+	return c.Path
+}
+
+// SystemPaths 返回HTTP服务上由网关自身处理的路径（指标、就绪和存活检查、OpenAPI文档）
+// 这些路径不经过路由表，路由不能使用相同的路径
+func (c *Config) SystemPaths() []string {
+	paths := []string{c.Shutdown.ReadinessPath(), c.Shutdown.LivenessPath()}
+	if c.MetricsPath != "" {
+		paths = append(paths, c.MetricsPath)
+	}
+	if !c.OpenAPI.Disabled {
+		paths = append(paths, c.OpenAPI.DocumentPath())
+	}
+	return paths
+}
+
+// TLSConfig 定义了HTTPS监听配置
+type TLSConfig struct {
+	// Enabled 是否启用HTTPS监听（端口为 ports.https_port）
+	Enabled      bool                `json:"enabled"`
+	// Certificates 证书列表，按SNI选择，第一个为默认证书
+	Certificates []CertificateConfig `json:"certificates"`
+	// MinVersion 最低TLS版本："1.2" 或 "1.3"，默认 "1.2"
+	MinVersion   string              `json:"min_version"`
+	// CipherSuites 允许的TLS 1.2加密套件名称，为空时使用Go默认安全套件
+	CipherSuites []string            `json:"cipher_suites"`
+	// RedirectHTTP 是否将HTTP请求重定向到HTTPS
+	RedirectHTTP bool                `json:"redirect_http"`
+	// SelfSigned 证书文件不存在或即将过期时是否自动生成本地自签名证书
+	SelfSigned   bool                `json:"self_signed"`
+}
+
+// CertificateConfig 定义了单个证书配置
+type CertificateConfig struct {
+	// CertFile 证书文件路径（PEM）
+	CertFile string   `json:"cert_file"`
+	// KeyFile 私钥文件路径（PEM）
+	KeyFile  string   `json:"key_file"`
+	// Hosts 自签名证书包含的域名/IP，仅 self_signed 时使用
+	Hosts    []string `json:"hosts"`
 }
 
 // PortsConfig 定义了端口配置
@@ -54,6 +206,9 @@ type PortsConfig struct {
 	// HTTPPort HTTP服务端口
 	// 用于HTTP服务的端口号
 	HTTPPort      string `json:"http_port"`
+	// HTTPSPort HTTPS服务端口
+	// 用于HTTPS服务的端口号，tls.enabled 为true时生效
+	HTTPSPort     string `json:"https_port"`
 	// WebSocketPort WebSocket服务端口
 	// 用于WebSocket服务的端口号
 	WebSocketPort string `json:"websocket_port"`
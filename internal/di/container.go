@@ -3,6 +3,7 @@ package di
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"sync"
 )
 
@@ -167,3 +168,42 @@ func (c *Container) ResolveType(serviceType reflect.Type) (interface{}, error) {
 	serviceName := serviceType.Name()
 	return c.Resolve(serviceName)
 }
+
+// ServiceInfo 服务注册信息
+type ServiceInfo struct {
+	// Name 服务名称
+	Name string `json:"name"`
+	// Lifecycle 生命周期：singleton 或 prototype
+	Lifecycle string `json:"lifecycle"`
+	// Instantiated 单例是否已经创建
+	Instantiated bool `json:"instantiated"`
+	// Type 已创建实例的类型
+	Type string `json:"type,omitempty"`
+}
+
+// Services 返回所有已注册的服务
+// 功能：
+// 1. 读取服务注册表
+// 2. 按名称排序
+// 参数：无
+// 返回值：
+//   - []ServiceInfo: 服务注册信息，不会触发服务实例化
+func (c *Container) Services() []ServiceInfo {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	services := make([]ServiceInfo, 0, len(c.services))
+	for name, instance := range c.services {
+		info := ServiceInfo{Name: name, Lifecycle: "singleton"}
+		if instance.Lifecycle == Prototype {
+			info.Lifecycle = "prototype"
+		}
+		if instance.Instance != nil {
+			info.Instantiated = true
+			info.Type = reflect.TypeOf(instance.Instance).String()
+		}
+		services = append(services, info)
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	return services
+}
@@ -0,0 +1,117 @@
+package ipc
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AsyncResponse 异步响应负载（0x05），id 与请求一致
+type AsyncResponse struct {
+	ID     string          `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// AsyncCallback 异步请求完成回调
+// 参数：
+//   - resp *AsyncResponse: 业务进程返回的响应，出错时为nil
+//   - err error: 发送失败、超时或响应无效时的错误信息
+type AsyncCallback func(resp *AsyncResponse, err error)
+
+// TransmitAsyncIPC 发送异步请求（0x04）并在后台等待异步响应（0x05）
+// 功能：
+// 1. 立即发送请求，不等待业务处理完成
+// 2. 后台协程在同一连接上读取响应，完成或超时后调用回调并关闭连接
+// 参数：
+//   - id string: 异步请求ID，为空时自动生成UUIDv4
+//   - requestID string: 网关请求ID（X-Request-ID），可为空
+//   - method string: 目标方法
+//   - params interface{}: 业务参数
+//   - socketPath string: 业务进程Socket路径
+//   - timeout time.Duration: 等待响应的超时时间，<=0 时使用 AsyncTimeout
+//   - callback AsyncCallback: 完成回调
+// 返回值：
+//   - string: 异步请求ID
+//   - error: 连接或发送失败时返回的错误信息（此时不会调用回调）
+func TransmitAsyncIPC(id, requestID, method string, params interface{}, socketPath string, timeout time.Duration, callback AsyncCallback) (string, error) {
+	if id == "" {
+		id = uuid.New().String()
+	}
+	if timeout <= 0 {
+		timeout = AsyncTimeout
+	}
+	payload, err := json.Marshal(AsyncRequest{ID: id, RequestID: requestID, Method: method, Params: params})
+	if err != nil {
+		return id, fmt.Errorf("序列化请求失败: %v", err)
+	}
+	if len(payload) > MaxPayloadSize {
+		return id, fmt.Errorf("负载大小超出限制（最大4MB）")
+	}
+
+	conn, err := dialEndpoint(socketPath, 0)
+	if err != nil {
+		return id, fmt.Errorf("连接PHP Socket失败: %w", err)
+	}
+	header := make([]byte, HeaderSize)
+	binary.BigEndian.PutUint16(header[:2], ProtocolVersion)
+	header[2] = MsgTypeAsyncReq
+	binary.BigEndian.PutUint32(header[3:7], uint32(len(payload)))
+	if _, err := conn.Write(append(header, payload...)); err != nil {
+		conn.Close()
+		return id, fmt.Errorf("发送请求失败: %w", err)
+	}
+
+	done := trackPending(PendingRequest{ID: id, RequestID: requestID, Method: method, Direction: DirectionOutbound, Peer: socketPath})
+	go func() {
+		defer done()
+		defer conn.Close()
+		conn.SetReadDeadline(time.Now().Add(timeout))
+		callback(readAsyncResponse(conn, id))
+	}()
+	return id, nil
+}
+
+// readAsyncResponse 读取与请求ID匹配的异步响应
+func readAsyncResponse(conn net.Conn, requestID string) (*AsyncResponse, error) {
+	header := make([]byte, HeaderSize)
+	for {
+		if _, err := io.ReadFull(conn, header); err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				return nil, fmt.Errorf("异步请求ID=%s超时", requestID)
+			}
+			return nil, fmt.Errorf("读取响应头失败: %w", err)
+		}
+		length := binary.BigEndian.Uint32(header[3:7])
+		if length > MaxPayloadSize {
+			return nil, fmt.Errorf("负载大小超出限制: %d", length)
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(conn, payload); err != nil {
+			return nil, fmt.Errorf("读取响应负载失败: %w", err)
+		}
+		if header[2] != 0x05 {
+			return nil, fmt.Errorf("无效的异步响应类型: 0x%x", header[2])
+		}
+
+		var resp AsyncResponse
+		if err := json.Unmarshal(payload, &resp); err != nil || resp.ID == "" {
+			// 不带 id 的响应视为该连接上唯一请求的结果
+			raw, _ := json.Marshal(string(payload))
+			if json.Valid(payload) {
+				raw = payload
+			}
+			return &AsyncResponse{ID: requestID, Result: raw}, nil
+		}
+		if resp.ID != requestID {
+			// 连接上只有一个请求，其他ID的响应直接丢弃
+			continue
+		}
+		return &resp, nil
+	}
+}
@@ -0,0 +1,64 @@
+package ipc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// MsgTypeHeartbeat 心跳消息类型
+const MsgTypeHeartbeat = 0x02
+
+// dialEndpoint 连接业务进程端点
+// 支持 unix:///path/to.sock、tcp://host:port 两种写法，不带前缀时视为Unix Socket路径
+func dialEndpoint(endpoint string, timeout time.Duration) (net.Conn, error) {
+	network, address := "unix", endpoint
+	switch {
+	case strings.HasPrefix(endpoint, "tcp://"):
+		network, address = "tcp", strings.TrimPrefix(endpoint, "tcp://")
+	case strings.HasPrefix(endpoint, "unix://"):
+		address = strings.TrimPrefix(endpoint, "unix://")
+	}
+	if timeout > 0 {
+		return net.DialTimeout(network, address, timeout)
+	}
+	return net.Dial(network, address)
+}
+
+// Heartbeat 向业务进程端点发送心跳包（0x02）并等待任意响应头
+// 参数：
+//   - endpoint string: 端点地址
+//   - timeout time.Duration: 连接和等待响应的超时时间
+// 返回值：
+//   - error: 连接失败、超时或连接被关闭时返回的错误信息
+func Heartbeat(endpoint string, timeout time.Duration) error {
+	conn, err := dialEndpoint(endpoint, timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	header := make([]byte, HeaderSize)
+	binary.BigEndian.PutUint16(header[:2], ProtocolVersion)
+	header[2] = MsgTypeHeartbeat
+	if _, err := conn.Write(header); err != nil {
+		return fmt.Errorf("发送心跳失败: %v", err)
+	}
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return fmt.Errorf("读取心跳响应失败: %v", err)
+	}
+	return nil
+}
+
+// Ping 仅检查业务进程端点是否可以建立连接
+func Ping(endpoint string, timeout time.Duration) error {
+	conn, err := dialEndpoint(endpoint, timeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
@@ -1,6 +1,7 @@
 package ipc
 
 import (
+	"bigHammer/internal/lifecycle"
 	"bigHammer/internal/plugin"
 	"bigHammer/internal/shared"
 	"encoding/binary"
@@ -10,6 +11,7 @@ import (
 	"log"
 	"net"
 	"sync"
+	"sync/atomic"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -36,13 +38,16 @@ var (
 )
 
 type AsyncRequest struct {
-	ID     string      `json:"id"`   // 全局唯一ID（UUIDv4）
-	Method string      `json:"method"` // 目标方法（如JSON-RPC规范）
-	Params interface{} `json:"params"` // 业务参数
+	ID        string      `json:"id"`                   // 全局唯一ID（UUIDv4）
+	RequestID string      `json:"request_id,omitempty"` // 触发该请求的网关请求ID（X-Request-ID）
+	Method    string      `json:"method"`               // 目标方法（如JSON-RPC规范）
+	Params    interface{} `json:"params"`               // 业务参数
 }
 
 func HandleSocket(conn net.Conn) {
 	defer conn.Close()
+	tracked := trackConnection(conn)
+	defer untrackConnection(tracked)
 	buf := make([]byte, HeaderSize)
 	requestMap := make(map[string]chan []byte)
 	var requestMapMu sync.RWMutex
@@ -78,6 +83,7 @@ func HandleSocket(conn net.Conn) {
 			log.Println("读取负载错误:", err)
 			return
 		}
+		atomic.AddInt64(&tracked.requests, 1)
 
 		// 处理异步请求（MsgType=0x04）
 		if header.MsgType == 0x04 {
@@ -92,7 +98,7 @@ func HandleSocket(conn net.Conn) {
 			requestMapMu.Lock()
 			requestMap[asyncReq.ID] = respChan
 			requestMapMu.Unlock()
-			asyncPending.Inc()
+			done := trackPending(PendingRequest{ID: asyncReq.ID, RequestID: asyncReq.RequestID, Method: asyncReq.Method, Direction: DirectionInbound, Peer: tracked.info.ID})
 
 			// 启动goroutine处理异步逻辑
 			go func(id string, respChan chan []byte) {
@@ -100,7 +106,7 @@ func HandleSocket(conn net.Conn) {
 					requestMapMu.Lock()
 					delete(requestMap, id)
 					requestMapMu.Unlock()
-					asyncPending.Dec()
+					done()
 				}()
 
 				select {
@@ -123,42 +129,56 @@ func HandleSocket(conn net.Conn) {
 			continue
 		}
 
-		// 恢复同步请求处理逻辑（确保导入包被使用）
-		pluginInterface, err := shared.GlobalContainer.Resolve("plugin")
-		if err != nil {
-			log.Println("解析插件错误:", err)
+		// 同步插件调用登记为在途工作，优雅退出时等待响应发送完成
+		done := lifecycle.Track(lifecycle.KindIPC)
+		ok := handleSync(conn, header, payload)
+		done()
+		if !ok {
 			return
 		}
+	}
+}
 
-		var req plugin.Request
-		if err := json.Unmarshal(payload, &req); err != nil {
-			log.Println("解析JSON错误:", err)
-			return
-		}
+// handleSync 处理同步插件调用并写回响应
+// 返回值：
+//   - bool: 连接是否可以继续使用，请求无效时返回false
+func handleSync(conn net.Conn, header ProtocolHeader, payload []byte) bool {
+	// 恢复同步请求处理逻辑（确保导入包被使用）
+	pluginInterface, err := shared.GlobalContainer.Resolve("plugin")
+	if err != nil {
+		log.Println("解析插件错误:", err)
+		return false
+	}
 
-		pluginInstance, ok := pluginInterface.(plugin.ServicePlugin)
-		if !ok {
-			log.Println("插件接口不匹配")
-			return
-		}
+	var req plugin.Request
+	if err := json.Unmarshal(payload, &req); err != nil {
+		log.Println("解析JSON错误:", err)
+		return false
+	}
 
-		// 生成响应（示例逻辑，根据实际需求调整）
-		response := pluginInstance.HandleRequest(req)
-		responseData, err := json.Marshal(response)
-		if err != nil {
-			log.Println("序列化响应错误:", err)
-			return
-		}
+	pluginInstance, ok := pluginInterface.(plugin.ServicePlugin)
+	if !ok {
+		log.Println("插件接口不匹配")
+		return false
+	}
+
+	// 生成响应（示例逻辑，根据实际需求调整）
+	response := pluginInstance.HandleRequest(req)
+	responseData, err := json.Marshal(response)
+	if err != nil {
+		log.Println("序列化响应错误:", err)
+		return false
+	}
 
-		// 封装响应协议头
-		respHeader := make([]byte, HeaderSize)
-		binary.BigEndian.PutUint16(respHeader[:2], header.Version)
-		respHeader[2] = 0x05 // 响应类型
-		binary.BigEndian.PutUint32(respHeader[3:7], uint32(len(responseData)))
+	// 封装响应协议头
+	respHeader := make([]byte, HeaderSize)
+	binary.BigEndian.PutUint16(respHeader[:2], header.Version)
+	respHeader[2] = 0x05 // 响应类型
+	binary.BigEndian.PutUint32(respHeader[3:7], uint32(len(responseData)))
 
-		// 发送响应（使用responseData）
-		if _, err := conn.Write(append(respHeader, responseData...)); err != nil {
-			log.Println("发送响应错误:", err)
-		}
+	// 发送响应（使用responseData）
+	if _, err := conn.Write(append(respHeader, responseData...)); err != nil {
+		log.Println("发送响应错误:", err)
 	}
+	return true
 }
@@ -0,0 +1,137 @@
+package ipc
+
+import (
+	"bigHammer/internal/lifecycle"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 异步请求方向
+const (
+	// DirectionInbound 业务进程发给网关的异步请求
+	DirectionInbound = "inbound"
+	// DirectionOutbound 网关发给业务进程的异步请求
+	DirectionOutbound = "outbound"
+)
+
+// ConnectionInfo 业务进程到网关Socket服务的连接
+type ConnectionInfo struct {
+	ID          string    `json:"id"`
+	RemoteAddr  string    `json:"remote_addr"`
+	ConnectedAt time.Time `json:"connected_at"`
+	// Requests 连接上已收到的消息数
+	Requests int64 `json:"requests"`
+}
+
+// PendingRequest 未完成的异步请求
+type PendingRequest struct {
+	ID        string    `json:"id"`
+	// RequestID 触发该请求的网关请求ID（X-Request-ID）
+	RequestID string    `json:"request_id,omitempty"`
+	Method    string    `json:"method"`
+	Direction string    `json:"direction"`
+	// Peer 入站请求为连接ID，出站请求为业务进程端点
+	Peer      string    `json:"peer"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// connection 连接登记信息
+type connection struct {
+	info     ConnectionInfo
+	requests int64
+	conn     net.Conn
+}
+
+var (
+	registryMu sync.RWMutex
+	// connections 活动连接
+	// key: 连接ID
+	connections = make(map[string]*connection)
+	// pending 未完成的异步请求
+	// key: 方向 + 请求ID
+	pending = make(map[string]PendingRequest)
+)
+
+// trackConnection 登记连接，返回连接信息，连接关闭时需调用 untrackConnection
+func trackConnection(conn net.Conn) *connection {
+	c := &connection{info: ConnectionInfo{ID: uuid.NewString(), RemoteAddr: conn.RemoteAddr().String(), ConnectedAt: time.Now()}, conn: conn}
+	if c.info.RemoteAddr == "" {
+		c.info.RemoteAddr = "unix"
+	}
+	registryMu.Lock()
+	connections[c.info.ID] = c
+	registryMu.Unlock()
+	return c
+}
+
+func untrackConnection(c *connection) {
+	registryMu.Lock()
+	delete(connections, c.info.ID)
+	registryMu.Unlock()
+}
+
+// trackPending 登记异步请求，返回完成时调用的注销函数
+// 异步请求同时登记为在途工作，优雅退出时等待其完成
+func trackPending(req PendingRequest) func() {
+	key := req.Direction + ":" + req.ID
+	req.StartedAt = time.Now()
+	registryMu.Lock()
+	pending[key] = req
+	registryMu.Unlock()
+	asyncPending.Inc()
+	done := lifecycle.Track(lifecycle.KindAsync)
+	return func() {
+		registryMu.Lock()
+		delete(pending, key)
+		registryMu.Unlock()
+		asyncPending.Dec()
+		done()
+	}
+}
+
+// CloseConnections 关闭所有IPC连接，用于排空在途工作后停止Socket服务
+// 返回值：
+//   - int: 关闭的连接数
+func CloseConnections() int {
+	registryMu.RLock()
+	conns := make([]net.Conn, 0, len(connections))
+	for _, c := range connections {
+		conns = append(conns, c.conn)
+	}
+	registryMu.RUnlock()
+	for _, conn := range conns {
+		conn.Close()
+	}
+	return len(conns)
+}
+
+// Connections 返回当前所有连接，按建立时间排序
+func Connections() []ConnectionInfo {
+	registryMu.RLock()
+	list := make([]ConnectionInfo, 0, len(connections))
+	for _, c := range connections {
+		info := c.info
+		info.Requests = atomic.LoadInt64(&c.requests)
+		list = append(list, info)
+	}
+	registryMu.RUnlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].ConnectedAt.Before(list[j].ConnectedAt) })
+	return list
+}
+
+// PendingAsync 返回所有未完成的异步请求，按开始时间排序
+func PendingAsync() []PendingRequest {
+	registryMu.RLock()
+	list := make([]PendingRequest, 0, len(pending))
+	for _, req := range pending {
+		list = append(list, req)
+	}
+	registryMu.RUnlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].StartedAt.Before(list[j].StartedAt) })
+	return list
+}
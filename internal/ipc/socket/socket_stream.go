@@ -0,0 +1,102 @@
+package ipc
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// 流式请求消息类型
+const (
+	// MsgTypeStreamReq 流式同步请求，负载与同步请求相同，之后紧跟若干请求体数据块
+	MsgTypeStreamReq = 0x06
+	// MsgTypeBodyChunk 请求体数据块，负载为原始字节，负载长度为0的数据块表示请求体结束
+	MsgTypeBodyChunk = 0x07
+	// StreamChunkSize 每个请求体数据块的最大字节数
+	StreamChunkSize = 64 * 1024
+)
+
+// TransmitStreamIPC 发送流式同步请求并等待响应（0x05）
+// 功能：
+// 1. 发送流式请求（0x06），负载为不含请求体的同步请求
+// 2. 边读取 body 边以数据块（0x07）发送，最后发送空数据块表示结束
+// 3. 读取业务进程的同步响应
+// 请求体不会整体读入内存，也不受 MaxPayloadSize 限制
+// 参数：
+//   - ctx context.Context: 控制连接、发送和等待响应的超时与取消
+//   - requestID string: 网关请求ID（X-Request-ID）
+//   - method string: 目标方法
+//   - params interface{}: 业务参数
+//   - body io.Reader: 请求体
+//   - socketPath string: 业务进程Socket路径
+// 返回值：
+//   - []byte: 响应负载
+//   - error: 连接、读取请求体、读写失败或上下文结束时返回的错误信息
+func TransmitStreamIPC(ctx context.Context, requestID, method string, params interface{}, body io.Reader, socketPath string) ([]byte, error) {
+	var timeout time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		if timeout = time.Until(deadline); timeout <= 0 {
+			return nil, context.DeadlineExceeded
+		}
+	}
+	conn, err := dialEndpoint(socketPath, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("连接PHP Socket失败: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	// 上下文被取消时关闭连接，使阻塞的读写立即返回
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	payload, err := json.Marshal(SyncRequest{RequestID: requestID, Method: method, Params: params})
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求失败: %v", err)
+	}
+	if len(payload) > MaxPayloadSize {
+		return nil, ErrPayloadTooLarge
+	}
+	if err := writeFrame(conn, MsgTypeStreamReq, payload); err != nil {
+		return nil, fmt.Errorf("发送请求失败: %w", err)
+	}
+
+	buf := make([]byte, StreamChunkSize)
+	for {
+		n, readErr := body.Read(buf)
+		if n > 0 {
+			if err := writeFrame(conn, MsgTypeBodyChunk, buf[:n]); err != nil {
+				return nil, fmt.Errorf("发送请求体失败: %w", err)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return nil, fmt.Errorf("读取请求体失败: %w", readErr)
+		}
+	}
+	if err := writeFrame(conn, MsgTypeBodyChunk, nil); err != nil {
+		return nil, fmt.Errorf("发送请求体失败: %w", err)
+	}
+
+	output, _, err := readSyncResponse(conn)
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return output, err
+}
+
+// writeFrame 写入一条完整消息（协议头+负载）
+func writeFrame(w io.Writer, msgType byte, payload []byte) error {
+	frame := make([]byte, HeaderSize, HeaderSize+len(payload))
+	binary.BigEndian.PutUint16(frame[:2], ProtocolVersion)
+	frame[2] = msgType
+	binary.BigEndian.PutUint32(frame[3:7], uint32(len(payload)))
+	_, err := w.Write(append(frame, payload...))
+	return err
+}
@@ -1,8 +1,10 @@
 package ipc
 
 import (
+	"context"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"time"
@@ -19,18 +21,23 @@ const (
 	// 移除重复声明的 MaxPayloadSize，直接使用 socket_receive.go 中已定义的常量
 )
 
+// ErrPayloadTooLarge 请求负载超过 MaxPayloadSize
+var ErrPayloadTooLarge = errors.New("负载大小超出限制（最大4MB）")
+
 // 同步请求结构体（用于构造负载）
 type SyncRequest struct {
-	Method string      `json:"method"`
-	Params interface{} `json:"params"`
+	RequestID string      `json:"request_id,omitempty"` // 网关请求ID（X-Request-ID），用于关联日志
+	Method    string      `json:"method"`
+	Params    interface{} `json:"params"`
 }
 
 // 通用发送函数（支持同步/异步）
-func TransmitIPC(isAsync bool, method string, params interface{}, socketPath string) ([]byte, string, error) {
+// requestID 为网关请求ID，写入请求负载的 request_id 字段，业务进程调用插件时应原样带回
+func TransmitIPC(isAsync bool, requestID, method string, params interface{}, socketPath string) ([]byte, string, error) {
 	// 创建Unix Socket连接
-	conn, err := net.Dial("unix", socketPath)
+	conn, err := dialEndpoint(socketPath, 0)
 	if err != nil {
-		return nil, "", fmt.Errorf("连接PHP Socket失败: %v", err)
+		return nil, "", fmt.Errorf("连接PHP Socket失败: %w", err)
 	}
 	
 	// 修改连接关闭策略
@@ -47,23 +54,25 @@ func TransmitIPC(isAsync bool, method string, params interface{}, socketPath str
 	
 	var payload []byte
 	var msgType byte
-	var requestID string
+	var asyncID string
 
 	// 构造请求负载和消息类型
 	if isAsync {
 		// 生成UUIDv4作为异步请求ID
-		requestID = uuid.New().String()
+		asyncID = uuid.New().String()
 		asyncReq := AsyncRequest{
-			ID:     requestID,
-			Method: method,
-			Params: params,
+			ID:        asyncID,
+			RequestID: requestID,
+			Method:    method,
+			Params:    params,
 		}
 		payload, err = json.Marshal(asyncReq)
 		msgType = MsgTypeAsyncReq
 	} else {
 		syncReq := SyncRequest{
-			Method: method,
-			Params: params,
+			RequestID: requestID,
+			Method:    method,
+			Params:    params,
 		}
 		payload, err = json.Marshal(syncReq)
 		msgType = MsgTypeSync
@@ -74,7 +83,7 @@ func TransmitIPC(isAsync bool, method string, params interface{}, socketPath str
 
 	// 校验负载大小（直接使用 socket_receive.go 中已定义的 MaxPayloadSize）
 	if len(payload) > MaxPayloadSize {
-		return nil, "", fmt.Errorf("负载大小超出限制（最大4MB）")
+		return nil, "", ErrPayloadTooLarge
 	}
 
 	// 封装协议头（2字节版本 + 1字节类型 + 4字节负载长度）
@@ -85,7 +94,7 @@ func TransmitIPC(isAsync bool, method string, params interface{}, socketPath str
 	// 发送完整消息（头+负载）
 	_, err = conn.Write(append(header, payload...))
 	if err != nil {
-		return nil, "", fmt.Errorf("发送请求失败: %v", err)
+		return nil, "", fmt.Errorf("发送请求失败: %w", err)
 	}
 
 	// 同步请求直接等待响应
@@ -94,7 +103,53 @@ func TransmitIPC(isAsync bool, method string, params interface{}, socketPath str
 	}
 
 	// 异步请求返回ID（响应通过asyncReadLoop处理）
-	return nil, requestID, nil
+	return nil, asyncID, nil
+}
+
+// TransmitIPCContext 发送同步请求并等待响应，上下文到期或取消时中断通信
+// 参数：
+//   - ctx context.Context: 控制连接、发送和等待响应的超时与取消
+//   - requestID string: 网关请求ID（X-Request-ID）
+//   - method string: 目标方法
+//   - params interface{}: 业务参数
+//   - socketPath string: 业务进程Socket路径
+// 返回值：
+//   - []byte: 响应负载
+//   - error: 连接、读写失败或上下文结束时返回的错误信息
+func TransmitIPCContext(ctx context.Context, requestID, method string, params interface{}, socketPath string) ([]byte, error) {
+	var timeout time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		if timeout = time.Until(deadline); timeout <= 0 {
+			return nil, context.DeadlineExceeded
+		}
+	}
+	conn, err := dialEndpoint(socketPath, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("连接PHP Socket失败: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	// 上下文被取消时关闭连接，使阻塞的读写立即返回
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	payload, err := json.Marshal(SyncRequest{RequestID: requestID, Method: method, Params: params})
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求失败: %v", err)
+	}
+	if len(payload) > MaxPayloadSize {
+		return nil, ErrPayloadTooLarge
+	}
+	if err := writeFrame(conn, MsgTypeSync, payload); err != nil {
+		return nil, fmt.Errorf("发送请求失败: %w", err)
+	}
+	output, _, err := readSyncResponse(conn)
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return output, err
 }
 
 // 读取同步响应
@@ -103,7 +158,7 @@ func readSyncResponse(conn net.Conn) ([]byte, string, error) {
 	headerBuf := make([]byte, 7)
 	_, err := conn.Read(headerBuf)
 	if err != nil {
-		return nil, "", fmt.Errorf("读取响应头失败: %v", err)
+		return nil, "", fmt.Errorf("读取响应头失败: %w", err)
 	}
 
 	// 解析响应头
@@ -120,7 +175,7 @@ func readSyncResponse(conn net.Conn) ([]byte, string, error) {
 	payload := make([]byte, payloadLen)
 	_, err = conn.Read(payload)
 	if err != nil {
-		return nil, "", fmt.Errorf("读取响应负载失败: %v", err)
+		return nil, "", fmt.Errorf("读取响应负载失败: %w", err)
 	}
 
 	// 同步响应类型应为0x05（与设计文档一致）
@@ -0,0 +1,67 @@
+package jobs
+
+import (
+	"bigHammer/internal/middleware"
+	"bigHammer/internal/middleware/auth"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// PathPrefix 任务状态查询路径前缀，完整路径为 /jobs/{id}
+const PathPrefix = "/jobs/"
+
+// IDFromPath 从请求路径中解析任务ID
+func IDFromPath(path string) string {
+	id := strings.TrimPrefix(path, PathPrefix)
+	if id == path || id == "" || strings.Contains(id, "/") {
+		return ""
+	}
+	return id
+}
+
+// StatusURL 返回任务状态查询路径
+func StatusURL(id string) string {
+	return PathPrefix + id
+}
+
+// StatusHandler 返回任务状态查询处理器
+// 任务记录了调用方身份时，只有同一调用方可以查询
+func StatusHandler(store *Store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			middleware.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+			return
+		}
+		job, err := store.Get(IDFromPath(req.URL.Path))
+		if err == ErrNotFound {
+			middleware.WriteError(w, http.StatusNotFound, "Job not found", nil)
+			return
+		}
+		if err != nil {
+			middleware.WriteError(w, http.StatusInternalServerError, "Error loading job", nil)
+			return
+		}
+		if job.Owner != "" {
+			identity := auth.IdentityFromContext(req.Context())
+			if identity == nil || identity.Subject != job.Owner {
+				middleware.WriteError(w, http.StatusNotFound, "Job not found", nil)
+				return
+			}
+		}
+
+		// 回调地址可能包含调用方的凭证，不在查询结果中返回
+		job.CallbackURL = ""
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		if !job.Done() {
+			w.Header().Set("Retry-After", "1")
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":  http.StatusOK,
+			"message": "OK",
+			"data":    job,
+		})
+	})
+}
@@ -0,0 +1,147 @@
+package jobs
+
+import (
+	"bigHammer/internal/interface/database"
+	"bigHammer/internal/shared"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// KeyPrefix 任务记录在内存数据库中的键前缀
+const KeyPrefix = "job:"
+
+// 任务状态
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// ErrNotFound 任务不存在或已过期
+var ErrNotFound = errors.New("job not found")
+
+// Job 异步任务记录
+type Job struct {
+	// ID 任务ID，同时作为异步IPC请求ID
+	ID string `json:"id"`
+	// Route 创建任务的路由路径
+	Route string `json:"route"`
+	// RequestID 创建任务的HTTP请求ID（X-Request-ID）
+	RequestID string `json:"request_id,omitempty"`
+	// Status 任务状态：pending、succeeded、failed
+	Status string `json:"status"`
+	// Result 业务进程返回的结果，非JSON结果保存为字符串
+	Result json.RawMessage `json:"result,omitempty"`
+	// Error 失败原因
+	Error string `json:"error,omitempty"`
+	// Owner 创建任务的调用方（认证身份的 subject），查询时需为同一调用方
+	Owner string `json:"owner,omitempty"`
+	// CallbackURL 任务完成后通知的地址
+	CallbackURL string `json:"callback_url,omitempty"`
+	// CallbackStatus 回调结果：delivered 或 failed
+	CallbackStatus string `json:"callback_status,omitempty"`
+	// CreatedAt 创建时间（RFC3339）
+	CreatedAt string `json:"created_at"`
+	// UpdatedAt 最后更新时间（RFC3339）
+	UpdatedAt string `json:"updated_at"`
+	// CompletedAt 完成时间（RFC3339）
+	CompletedAt string `json:"completed_at,omitempty"`
+	// Deadline 超过该时间仍未完成的任务视为超时（RFC3339）
+	Deadline string `json:"deadline"`
+	// ExpiresAt 任务记录过期时间（RFC3339），过期后查询返回不存在
+	ExpiresAt string `json:"expires_at"`
+}
+
+// Done 判断任务是否已结束
+func (j *Job) Done() bool {
+	return j.Status == StatusSucceeded || j.Status == StatusFailed
+}
+
+// Store 基于 AgilityMemDB 的任务存储
+type Store struct {
+	mu sync.Mutex
+	db database.IDatabase
+}
+
+// NewStore 创建任务存储
+func NewStore(db database.IDatabase) *Store {
+	return &Store{db: db}
+}
+
+var (
+	defaultStore    *Store
+	defaultStoreErr error
+	defaultOnce     sync.Once
+)
+
+// DefaultStore 返回使用全局内存数据库的任务存储
+func DefaultStore() (*Store, error) {
+	defaultOnce.Do(func() {
+		db, err := shared.ResolveDatabase()
+		if err != nil {
+			defaultStoreErr = err
+			return
+		}
+		defaultStore = NewStore(db)
+	})
+	return defaultStore, defaultStoreErr
+}
+
+// Save 保存任务记录
+// 参数：
+//   - job *Job: 任务记录
+//   - persist bool: 是否立即持久化到数据文件（任务结束时持久化，保证重启后结果可查询）
+// 返回值：
+//   - error: 写入失败时返回的错误信息
+func (s *Store) Save(job *Job, persist bool) error {
+	job.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.db.Put(KeyPrefix+job.ID, string(data)); err != nil {
+		return fmt.Errorf("error saving job: %v", err)
+	}
+	if persist {
+		if err := s.db.Persist(); err != nil {
+			log.Println("持久化任务记录失败:", err)
+		}
+	}
+	return nil
+}
+
+// Get 查询任务
+// 过期的记录会被删除；超过截止时间仍未完成的任务（例如网关重启前提交的任务）标记为超时失败
+func (s *Store) Get(id string) (*Job, error) {
+	s.mu.Lock()
+	value, ok := s.db.Get(KeyPrefix + id)
+	s.mu.Unlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+	var job Job
+	if err := json.Unmarshal([]byte(value), &job); err != nil {
+		return nil, fmt.Errorf("invalid job record: %v", err)
+	}
+
+	now := time.Now()
+	if expires, err := time.Parse(time.RFC3339, job.ExpiresAt); err == nil && now.After(expires) {
+		s.mu.Lock()
+		s.db.Delete(KeyPrefix + id)
+		s.mu.Unlock()
+		return nil, ErrNotFound
+	}
+	if deadline, err := time.Parse(time.RFC3339, job.Deadline); err == nil && !job.Done() && now.After(deadline) {
+		job.Status = StatusFailed
+		job.Error = "timeout"
+		job.CompletedAt = now.UTC().Format(time.RFC3339)
+		s.Save(&job, false)
+	}
+	return &job, nil
+}
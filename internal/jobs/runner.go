@@ -0,0 +1,143 @@
+package jobs
+
+import (
+	ipc "bigHammer/internal/ipc/socket"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Options 异步路由配置，对应 router.json 中路由的 async 字段
+type Options struct {
+	// Timeout 等待业务进程完成的秒数，默认300
+	Timeout int `json:"timeout,omitempty"`
+	// ResultTTL 任务记录保留秒数，默认86400
+	ResultTTL int `json:"result_ttl,omitempty"`
+	// CallbackHosts 允许的回调地址主机名，为空时不接受回调地址
+	CallbackHosts []string `json:"callback_hosts,omitempty"`
+}
+
+// timeout 返回等待业务进程完成的超时时间
+func (o Options) timeout() time.Duration {
+	if o.Timeout <= 0 {
+		return 5 * time.Minute
+	}
+	return time.Duration(o.Timeout) * time.Second
+}
+
+// resultTTL 返回任务记录保留时间
+func (o Options) resultTTL() time.Duration {
+	if o.ResultTTL <= 0 {
+		return 24 * time.Hour
+	}
+	return time.Duration(o.ResultTTL) * time.Second
+}
+
+// ValidateCallback 校验调用方提供的回调地址
+// 只允许 http/https 地址，且主机名必须在 callback_hosts 中；未配置 callback_hosts 时拒绝所有回调，
+// 避免调用方借助回调访问网关所在网络的内部地址
+func (o Options) ValidateCallback(raw string) error {
+	if len(o.CallbackHosts) == 0 {
+		return fmt.Errorf("callback url not allowed for this route")
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid callback url")
+	}
+	for _, host := range o.CallbackHosts {
+		if strings.EqualFold(host, u.Hostname()) {
+			return nil
+		}
+	}
+	return fmt.Errorf("callback host %s not allowed", u.Hostname())
+}
+
+// Submit 创建任务并通过异步IPC发送到业务进程
+// 功能：
+// 1. 保存 pending 状态的任务记录
+// 2. 以任务ID作为请求ID发送异步请求（0x04），不等待业务处理完成
+// 3. 收到异步响应或超时后更新任务状态，配置了回调地址时投递通知
+// 参数：
+//   - store *Store: 任务存储
+//   - opts Options: 路由异步配置
+//   - route string: 路由路径
+//   - command string: 业务方法
+//   - params interface{}: 请求数据
+//   - socketPath string: 业务进程Socket路径
+//   - owner string: 调用方身份，可为空
+//   - callbackURL string: 回调地址，可为空
+//   - requestID string: 创建任务的HTTP请求ID，随异步请求发送到业务进程
+// 返回值：
+//   - *Job: 发送前的任务快照，任务完成后由回调更新的是存储中的记录，调用方读取快照不会与回调竞争
+//   - error: 保存或发送失败时返回的错误信息
+func Submit(store *Store, opts Options, route, command string, params interface{}, socketPath, owner, callbackURL, requestID string) (*Job, error) {
+	now := time.Now().UTC()
+	job := &Job{
+		ID:          uuid.New().String(),
+		Route:       route,
+		RequestID:   requestID,
+		Status:      StatusPending,
+		Owner:       owner,
+		CallbackURL: callbackURL,
+		CreatedAt:   now.Format(time.RFC3339),
+		Deadline:    now.Add(opts.timeout()).Format(time.RFC3339),
+		ExpiresAt:   now.Add(opts.timeout() + opts.resultTTL()).Format(time.RFC3339),
+	}
+	if err := store.Save(job, false); err != nil {
+		return nil, err
+	}
+	snapshot := *job
+
+	_, err := ipc.TransmitAsyncIPC(job.ID, requestID, command, params, socketPath, opts.timeout(), func(resp *ipc.AsyncResponse, err error) {
+		complete(store, job, resp, err)
+	})
+	if err != nil {
+		job.Status = StatusFailed
+		job.Error = err.Error()
+		job.CompletedAt = time.Now().UTC().Format(time.RFC3339)
+		store.Save(job, false)
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// complete 记录任务结果并投递回调
+func complete(store *Store, job *Job, resp *ipc.AsyncResponse, err error) {
+	switch {
+	case err != nil:
+		job.Status = StatusFailed
+		job.Error = err.Error()
+	case resp.Error != "":
+		job.Status = StatusFailed
+		job.Error = resp.Error
+		job.Result = resp.Result
+	default:
+		job.Status = StatusSucceeded
+		job.Result = resp.Result
+	}
+	if len(job.Result) > 0 && !json.Valid(job.Result) {
+		job.Result, _ = json.Marshal(string(job.Result))
+	}
+	job.CompletedAt = time.Now().UTC().Format(time.RFC3339)
+	if err := store.Save(job, job.CallbackURL == ""); err != nil {
+		log.Printf("保存任务 %s 结果失败: %v", job.ID, err)
+	}
+	if job.CallbackURL == "" {
+		return
+	}
+
+	if err := deliver(job); err != nil {
+		log.Printf("任务 %s 回调失败: %v", job.ID, err)
+		job.CallbackStatus = "failed"
+	} else {
+		job.CallbackStatus = "delivered"
+	}
+	if err := store.Save(job, true); err != nil {
+		log.Printf("保存任务 %s 回调状态失败: %v", job.ID, err)
+	}
+}
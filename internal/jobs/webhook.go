@@ -0,0 +1,64 @@
+package jobs
+
+import (
+	"bigHammer/internal/config"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// callbackAttempts 回调最大尝试次数
+const callbackAttempts = 3
+
+// callbackClient 回调使用的HTTP客户端，不跟随重定向
+var callbackClient = &http.Client{
+	Timeout: 10 * time.Second,
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// deliver 将任务结果POST到回调地址
+// 返回2xx视为成功，失败时按1s、2s间隔重试
+// 配置了 job_callback_secret 时请求携带 X-Signature: hex(HMAC-SHA256(secret, body))
+func deliver(job *Job) error {
+	body, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	secret := config.GlobalConfig.JobCallbackSecret
+
+	var lastErr error
+	for attempt := 0; attempt < callbackAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(1<<(attempt-1)) * time.Second)
+		}
+		req, err := http.NewRequest(http.MethodPost, job.CallbackURL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Job-Id", job.ID)
+		if secret != "" {
+			mac := hmac.New(sha256.New, []byte(secret))
+			mac.Write(body)
+			req.Header.Set("X-Signature", hex.EncodeToString(mac.Sum(nil)))
+		}
+		resp, err := callbackClient.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return nil
+		}
+		lastErr = fmt.Errorf("callback returned status %d", resp.StatusCode)
+	}
+	return lastErr
+}
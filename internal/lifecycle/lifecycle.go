@@ -0,0 +1,173 @@
+package lifecycle
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// 在途工作的类别
+const (
+	// KindHTTP 正在处理的HTTP请求
+	KindHTTP = "http"
+	// KindIPC 正在处理的IPC插件调用
+	KindIPC = "ipc"
+	// KindAsync 未完成的异步IPC请求（入站和出站）
+	KindAsync = "async"
+)
+
+var (
+	inflightGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gateway_inflight_work",
+		Help: "网关正在处理的工作数量",
+	}, []string{"kind"})
+	drainingGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "gateway_draining",
+		Help: "网关是否正在排空（1 表示已停止接收新请求）",
+	})
+)
+
+var (
+	draining atomic.Bool
+	mu       sync.Mutex
+	// deadline 排空截止时间，开始排空后设置
+	deadline time.Time
+	// inflight 各类别在途工作数量
+	inflight = make(map[string]int64)
+	// idle 在途工作归零时关闭并替换，用于唤醒 Wait
+	idle = make(chan struct{})
+)
+
+// Track 登记一项在途工作，返回工作完成时调用的注销函数（只生效一次）
+// 参数：
+//   - kind string: 工作类别，见 KindHTTP、KindIPC、KindAsync
+// 返回值：
+//   - func(): 注销函数
+func Track(kind string) func() {
+	mu.Lock()
+	inflight[kind]++
+	mu.Unlock()
+	inflightGauge.WithLabelValues(kind).Inc()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			inflightGauge.WithLabelValues(kind).Dec()
+			mu.Lock()
+			defer mu.Unlock()
+			inflight[kind]--
+			if total() == 0 {
+				close(idle)
+				idle = make(chan struct{})
+			}
+		})
+	}
+}
+
+// total 返回在途工作总数，调用方需持有 mu
+func total() int64 {
+	var n int64
+	for _, v := range inflight {
+		n += v
+	}
+	return n
+}
+
+// InFlight 返回各类别的在途工作数量
+func InFlight() map[string]int64 {
+	mu.Lock()
+	defer mu.Unlock()
+	counts := make(map[string]int64, len(inflight))
+	for kind, n := range inflight {
+		if n > 0 {
+			counts[kind] = n
+		}
+	}
+	return counts
+}
+
+// BeginDrain 开始排空：标记网关未就绪，并设置等待在途工作的截止时间
+// 重复调用时保留第一次设置的截止时间
+// 参数：
+//   - timeout time.Duration: 等待在途工作完成的最长时间
+// 返回值：无
+func BeginDrain(timeout time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+	if draining.Swap(true) {
+		return
+	}
+	deadline = time.Now().Add(timeout)
+	drainingGauge.Set(1)
+}
+
+// Draining 返回网关是否正在排空
+func Draining() bool {
+	return draining.Load()
+}
+
+// DrainContext 返回在排空截止时间到期的上下文，供各服务的 Shutdown 使用
+// 尚未开始排空时使用 fallback 作为超时时间
+func DrainContext(fallback time.Duration) (context.Context, context.CancelFunc) {
+	mu.Lock()
+	d := deadline
+	mu.Unlock()
+	if d.IsZero() {
+		return context.WithTimeout(context.Background(), fallback)
+	}
+	return context.WithDeadline(context.Background(), d)
+}
+
+// Wait 等待所有在途工作完成
+// 参数：
+//   - ctx context.Context: 取消或到期时停止等待
+// 返回值：
+//   - error: 等待被取消或超时时返回 ctx.Err()
+func Wait(ctx context.Context) error {
+	for {
+		mu.Lock()
+		n, ch := total(), idle
+		mu.Unlock()
+		if n == 0 {
+			return nil
+		}
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// ReadinessHandler 就绪检查：正常时返回200，排空期间返回503，供负载均衡摘除流量
+func ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		status, state := http.StatusOK, "ready"
+		if Draining() {
+			status, state = http.StatusServiceUnavailable, "draining"
+		}
+		writeStatus(w, status, state)
+	})
+}
+
+// LivenessHandler 存活检查：进程能够处理请求时总是返回200
+func LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		writeStatus(w, http.StatusOK, "alive")
+	})
+}
+
+func writeStatus(w http.ResponseWriter, status int, state string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   state,
+		"inflight": InFlight(),
+	})
+}
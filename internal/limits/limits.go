@@ -0,0 +1,126 @@
+package limits
+
+import (
+	"bigHammer/internal/accesslog"
+	"bigHammer/internal/middleware"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var rejections = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "gateway_request_size_rejections_total",
+	Help: "因请求过大被拒绝的次数，reason 取值 body / header",
+}, []string{"route", "reason"})
+
+// Options 请求大小限制，对应 router.json 中全局、分组和路由的 limits 字段
+type Options struct {
+	// MaxBodySize 请求体最大字节数，超过时返回413，0表示不限制
+	// 未开启 stream_body 的IPC路由始终受IPC单条消息大小（4MB）的限制
+	MaxBodySize int64 `json:"max_body_size,omitempty"`
+	// MaxHeaderSize 请求头最大字节数（所有请求头名称和值的长度之和），超过时返回413，0表示不限制
+	MaxHeaderSize int `json:"max_header_size,omitempty"`
+	// StreamBody 是否将请求体以数据块流式发送到业务进程，而不是读入内存后嵌入IPC请求的 body 字段
+	// 仅在路由（或分组）上配置有效，用于允许大请求体的 IPC 路由
+	StreamBody bool `json:"stream_body,omitempty"`
+}
+
+// Validate 校验请求大小限制
+func (o Options) Validate() error {
+	if o.MaxBodySize < 0 || o.MaxHeaderSize < 0 {
+		return fmt.Errorf("limits must not be negative")
+	}
+	return nil
+}
+
+// Merge 合并全局限制和路由限制，路由配置的非零字段覆盖全局配置，stream_body 只取路由配置
+// 参数：
+//   - global *Options: 全局限制，可为nil
+//   - route *Options: 路由限制，可为nil
+// 返回值：
+//   - Options: 路由实际使用的限制
+func Merge(global, route *Options) Options {
+	var merged Options
+	if global != nil {
+		merged.MaxBodySize, merged.MaxHeaderSize = global.MaxBodySize, global.MaxHeaderSize
+	}
+	if route == nil {
+		return merged
+	}
+	if route.MaxBodySize > 0 {
+		merged.MaxBodySize = route.MaxBodySize
+	}
+	if route.MaxHeaderSize > 0 {
+		merged.MaxHeaderSize = route.MaxHeaderSize
+	}
+	merged.StreamBody = route.StreamBody
+	return merged
+}
+
+// HeaderSize 返回请求头大小：每个请求头按 "名称: 值\r\n" 计算
+func HeaderSize(header http.Header) int {
+	size := 0
+	for name, values := range header {
+		for _, value := range values {
+			size += len(name) + len(value) + 4
+		}
+	}
+	return size
+}
+
+// Middleware 创建请求大小限制中间件
+// 功能：
+// 1. 请求头超过 max_header_size 时返回413
+// 2. Content-Length 超过 max_body_size 时直接返回413，不读取请求体
+// 3. 未声明长度的请求体在读取超过 max_body_size 时返回错误，由处理器返回413
+// 参数：
+//   - opts Options: 请求大小限制
+// 返回值：
+//   - middleware.Middleware: 没有配置限制时为nil
+func Middleware(opts Options) middleware.Middleware {
+	if opts.MaxBodySize <= 0 && opts.MaxHeaderSize <= 0 {
+		return nil
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if opts.MaxHeaderSize > 0 {
+				if size := HeaderSize(req.Header); size > opts.MaxHeaderSize {
+					reject(w, req, "header", fmt.Sprintf("request header size %d exceeds %d", size, opts.MaxHeaderSize))
+					return
+				}
+			}
+			if opts.MaxBodySize > 0 {
+				if req.ContentLength > opts.MaxBodySize {
+					reject(w, req, "body", fmt.Sprintf("request body size %d exceeds %d", req.ContentLength, opts.MaxBodySize))
+					return
+				}
+				if req.Body != nil && req.Body != http.NoBody {
+					req.Body = http.MaxBytesReader(w, req.Body, opts.MaxBodySize)
+				}
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+// RejectBody 返回请求体过大的413响应，供读取请求体时超出限制的处理器使用
+func RejectBody(w http.ResponseWriter, req *http.Request, err error) {
+	reject(w, req, "body", err.Error())
+}
+
+func reject(w http.ResponseWriter, req *http.Request, reason, detail string) {
+	route := middleware.RouteFromContext(req.Context())
+	rejections.WithLabelValues(route, reason).Inc()
+	log.Printf("请求过大 [%s]: route=%s %s", middleware.RequestIDFromContext(req.Context()), route, detail)
+	accesslog.FromContext(req.Context()).SetError(fmt.Errorf("%s", detail))
+	// 请求体未读完时关闭连接，避免继续接收剩余数据
+	w.Header().Set("Connection", "close")
+	message := "Request body too large"
+	if reason == "header" {
+		message = "Request header too large"
+	}
+	middleware.WriteError(w, http.StatusRequestEntityTooLarge, message, nil)
+}
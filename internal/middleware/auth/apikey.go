@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"bigHammer/internal/interface/database"
+	"bigHammer/internal/middleware"
+	"bigHammer/internal/shared"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultAPIKeyPrefix API Key 在内存数据库中的键前缀
+const DefaultAPIKeyPrefix = "apikey:"
+
+// APIKeyRecord API Key 记录
+// 以JSON形式存储在 AgilityMemDB 中，键为 前缀 + SHA-256(API Key)，
+// 数据库中不保存明文密钥
+type APIKeyRecord struct {
+	// ID API Key 标识，用于日志和审计
+	ID string `json:"id"`
+	// Subject API Key 所属主体（如设备ID、应用ID）
+	Subject string `json:"subject"`
+	// Roles 主体拥有的角色
+	Roles []string `json:"roles,omitempty"`
+	// Disabled 是否已禁用
+	Disabled bool `json:"disabled,omitempty"`
+	// ExpiresAt 过期时间（Unix秒），0表示永不过期
+	ExpiresAt int64 `json:"expires_at,omitempty"`
+	// Metadata 附加属性，认证成功后作为 claims 注入
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// HashAPIKey 计算 API Key 的存储摘要
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// StoreAPIKey 将 API Key 记录写入数据库
+// 参数：
+//   - db database.IDatabase: 数据库实例
+//   - prefix string: 数据库键前缀，与认证器的 prefix 选项一致，为空时使用 DefaultAPIKeyPrefix
+//   - key string: API Key 明文
+//   - record APIKeyRecord: API Key 记录
+// 返回值：
+//   - error: 序列化或写入失败时返回的错误信息
+func StoreAPIKey(db database.IDatabase, prefix, key string, record APIKeyRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("error marshalling api key record: %v", err)
+	}
+	return db.Put(apiKeyPrefix(prefix)+HashAPIKey(key), string(data))
+}
+
+// RevokeAPIKey 从数据库删除 API Key，prefix 为空时使用 DefaultAPIKeyPrefix
+func RevokeAPIKey(db database.IDatabase, prefix, key string) {
+	db.Delete(apiKeyPrefix(prefix) + HashAPIKey(key))
+}
+
+// apiKeyPrefix 返回数据库键前缀，为空时使用默认前缀
+func apiKeyPrefix(prefix string) string {
+	if prefix == "" {
+		return DefaultAPIKeyPrefix
+	}
+	return prefix
+}
+
+// APIKeyAuthenticator API Key 认证器
+// 从请求头、Authorization: ApiKey <key> 或查询参数中读取密钥，
+// 并在 AgilityMemDB 中查找对应记录
+type APIKeyAuthenticator struct {
+	header string
+	query  string
+	prefix string
+}
+
+// NewAPIKeyAuthenticator 根据选项创建 API Key 认证器
+// 选项：
+//   - header: 读取密钥的请求头，默认 X-API-Key
+//   - query: 读取密钥的查询参数，默认不从查询参数读取
+//   - prefix: 数据库键前缀，默认 apikey:
+func NewAPIKeyAuthenticator(options map[string]interface{}) (*APIKeyAuthenticator, error) {
+	a := &APIKeyAuthenticator{}
+	var err error
+	if a.header, err = middleware.OptString(options, "header", "X-API-Key"); err != nil {
+		return nil, err
+	}
+	if a.query, err = middleware.OptString(options, "query", ""); err != nil {
+		return nil, err
+	}
+	if a.prefix, err = middleware.OptString(options, "prefix", DefaultAPIKeyPrefix); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Name 实现 Authenticator 接口
+func (a *APIKeyAuthenticator) Name() string {
+	return "api_key"
+}
+
+// extractKey 从请求中读取 API Key
+func (a *APIKeyAuthenticator) extractKey(req *http.Request) string {
+	if key := req.Header.Get(a.header); key != "" {
+		return key
+	}
+	if authz := req.Header.Get("Authorization"); len(authz) > 7 && strings.EqualFold(authz[:7], "ApiKey ") {
+		return strings.TrimSpace(authz[7:])
+	}
+	if a.query != "" {
+		return req.URL.Query().Get(a.query)
+	}
+	return ""
+}
+
+// Authenticate 实现 Authenticator 接口
+func (a *APIKeyAuthenticator) Authenticate(req *http.Request) (*Identity, error) {
+	key := a.extractKey(req)
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+
+	db, err := shared.ResolveDatabase()
+	if err != nil {
+		return nil, err
+	}
+	value, ok := db.Get(a.prefix + HashAPIKey(key))
+	if !ok {
+		return nil, errors.New("unknown api key")
+	}
+
+	var record APIKeyRecord
+	if err := json.Unmarshal([]byte(value), &record); err != nil {
+		return nil, fmt.Errorf("invalid api key record: %v", err)
+	}
+	if record.Disabled {
+		return nil, fmt.Errorf("api key %s is disabled", record.ID)
+	}
+	if record.ExpiresAt > 0 && time.Now().Unix() >= record.ExpiresAt {
+		return nil, fmt.Errorf("api key %s has expired", record.ID)
+	}
+
+	return &Identity{
+		Subject: record.Subject,
+		Method:  a.Name(),
+		KeyID:   record.ID,
+		Roles:   record.Roles,
+		Claims:  record.Metadata,
+	}, nil
+}
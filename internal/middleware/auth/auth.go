@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"bigHammer/internal/middleware"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Identity 认证通过后的调用方身份
+// 由网关注入到IPC请求数据的 auth 字段中，业务代码可直接信任
+type Identity struct {
+	// Subject 调用方标识（API Key 所属主体、JWT sub 或签名密钥所属主体）
+	Subject string `json:"subject"`
+	// Method 认证方式：api_key、jwt 或 hmac
+	Method string `json:"method"`
+	// KeyID 使用的密钥标识（API Key ID、JWT kid 或 HMAC key id）
+	KeyID string `json:"key_id,omitempty"`
+	// Roles 调用方角色
+	Roles []string `json:"roles,omitempty"`
+	// Claims JWT声明或API Key附加属性
+	Claims map[string]interface{} `json:"claims,omitempty"`
+}
+
+// Authenticator 认证器接口
+// 每种认证方式实现该接口，中间件按配置顺序依次尝试
+type Authenticator interface {
+	// Name 认证方式名称
+	Name() string
+	// Authenticate 认证请求
+	// 返回值：
+	//   - *Identity: 认证成功时的身份
+	//   - error: 请求未携带该方式的凭证时返回 ErrNoCredentials，凭证无效时返回其他错误
+	Authenticate(req *http.Request) (*Identity, error)
+}
+
+// ErrNoCredentials 请求未携带当前认证方式的凭证
+var ErrNoCredentials = errors.New("no credentials")
+
+type identityKey struct{}
+
+// WithIdentity 返回携带调用方身份的上下文
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// IdentityFromContext 从上下文中获取调用方身份，未认证时返回nil
+func IdentityFromContext(ctx context.Context) *Identity {
+	id, _ := ctx.Value(identityKey{}).(*Identity)
+	return id
+}
+
+// New 创建认证中间件
+// 功能：
+// 1. 按顺序尝试各认证器，第一个成功的认证器决定调用方身份
+// 2. 凭证无效时立即返回401，不再尝试后续认证器
+// 3. 将身份写入请求上下文，供路由注入IPC请求数据
+// 参数：
+//   - authenticators []Authenticator: 认证器列表
+//   - optional bool: 为true时允许匿名请求通过（不注入身份）
+//
+// 返回值：
+//   - middleware.Middleware: 认证中间件
+func New(authenticators []Authenticator, optional bool) middleware.Middleware {
+	challenges := make([]string, 0, len(authenticators))
+	for _, a := range authenticators {
+		switch a.Name() {
+		case "jwt":
+			challenges = append(challenges, `Bearer realm="bigHammer"`)
+		case "api_key":
+			challenges = append(challenges, `ApiKey realm="bigHammer"`)
+		case "hmac":
+			challenges = append(challenges, `HMAC-SHA256 realm="bigHammer"`)
+		}
+	}
+	challenge := strings.Join(challenges, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			for _, a := range authenticators {
+				id, err := a.Authenticate(req)
+				if errors.Is(err, ErrNoCredentials) {
+					continue
+				}
+				if err != nil {
+					log.Printf("认证失败 %s %s (%s): %v", req.Method, req.URL.Path, a.Name(), err)
+					w.Header().Set("WWW-Authenticate", challenge)
+					middleware.WriteError(w, http.StatusUnauthorized, "Invalid credentials", nil)
+					return
+				}
+				next.ServeHTTP(w, req.WithContext(WithIdentity(req.Context(), id)))
+				return
+			}
+
+			if optional {
+				next.ServeHTTP(w, req)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", challenge)
+			middleware.WriteError(w, http.StatusUnauthorized, "Authentication required", nil)
+		})
+	}
+}
+
+// newFromOptions 根据 router.json 中的选项创建认证中间件
+// 选项示例：
+//
+//	"auth": {
+//	  "methods": ["jwt", "api_key", "hmac"],
+//	  "optional": false,
+//	  "jwt": {"jwks_file": "/config/jwks.json", "issuer": "...", "audience": "..."},
+//	  "api_key": {"header": "X-API-Key"},
+//	  "hmac": {"max_skew": 300}
+//	}
+func newFromOptions(options map[string]interface{}) (middleware.Middleware, error) {
+	methods, err := middleware.OptStrings(options, "methods", []string{"api_key"})
+	if err != nil {
+		return nil, err
+	}
+	optional, err := middleware.OptBool(options, "optional", false)
+	if err != nil {
+		return nil, err
+	}
+
+	authenticators := make([]Authenticator, 0, len(methods))
+	for _, method := range methods {
+		methodOptions, err := middleware.OptMap(options, method)
+		if err != nil {
+			return nil, err
+		}
+		var a Authenticator
+		switch method {
+		case "api_key":
+			a, err = NewAPIKeyAuthenticator(methodOptions)
+		case "jwt":
+			a, err = NewJWTAuthenticator(methodOptions)
+		case "hmac":
+			a, err = NewHMACAuthenticator(methodOptions)
+		default:
+			err = fmt.Errorf("unknown auth method %s", method)
+		}
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, a)
+	}
+	if len(authenticators) == 0 {
+		return nil, errors.New("at least one auth method is required")
+	}
+	return New(authenticators, optional), nil
+}
+
+func init() {
+	middleware.Register("auth", newFromOptions)
+}
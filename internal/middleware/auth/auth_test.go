@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"bigHammer/internal/di"
+	"bigHammer/internal/interface/database"
+	"bigHammer/internal/shared"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memDB 测试用内存数据库
+type memDB struct {
+	mu   sync.Mutex
+	data map[string]string
+}
+
+func (m *memDB) LoadData() error { return nil }
+
+func (m *memDB) Get(key string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.data[key]
+	return v, ok
+}
+
+func (m *memDB) Put(key string, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[key] = value
+	return nil
+}
+
+func (m *memDB) Delete(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.data, key)
+}
+
+func (m *memDB) Persist() error           { return nil }
+func (m *memDB) BeginTransaction() error  { return nil }
+func (m *memDB) CommitTransaction() error { return nil }
+func (m *memDB) RollbackTransaction()     {}
+
+var testDB = &memDB{data: make(map[string]string)}
+
+func TestMain(m *testing.M) {
+	shared.GlobalContainer = di.NewContainer()
+	shared.GlobalContainer.Register("database", func() database.IDatabase { return testDB }, di.Singleton)
+	os.Exit(m.Run())
+}
+
+func TestAPIKeyAuthenticate(t *testing.T) {
+	StoreAPIKey(testDB, "", "active-key", APIKeyRecord{ID: "k1", Subject: "device-1", Roles: []string{"reader"}})
+	StoreAPIKey(testDB, "", "disabled-key", APIKeyRecord{ID: "k2", Subject: "device-2", Disabled: true})
+	StoreAPIKey(testDB, "", "expired-key", APIKeyRecord{ID: "k3", Subject: "device-3", ExpiresAt: time.Now().Add(-time.Minute).Unix()})
+
+	a, err := NewAPIKeyAuthenticator(map[string]interface{}{"query": "api_key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		header  string
+		authz   string
+		query   string
+		subject string
+		noCreds bool
+		wantErr bool
+	}{
+		{name: "header", header: "active-key", subject: "device-1"},
+		{name: "authorization", authz: "ApiKey active-key", subject: "device-1"},
+		{name: "query", query: "?api_key=active-key", subject: "device-1"},
+		{name: "missing", noCreds: true},
+		{name: "unknown", header: "other-key", wantErr: true},
+		{name: "disabled", header: "disabled-key", wantErr: true},
+		{name: "expired", header: "expired-key", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/items"+tt.query, nil)
+			if tt.header != "" {
+				req.Header.Set("X-API-Key", tt.header)
+			}
+			if tt.authz != "" {
+				req.Header.Set("Authorization", tt.authz)
+			}
+			id, err := a.Authenticate(req)
+			switch {
+			case tt.noCreds:
+				if err != ErrNoCredentials {
+					t.Fatalf("err = %v, want ErrNoCredentials", err)
+				}
+			case tt.wantErr:
+				if err == nil || err == ErrNoCredentials {
+					t.Fatalf("err = %v, want rejection", err)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if id.Subject != tt.subject || id.Method != "api_key" {
+					t.Fatalf("identity = %+v, want subject %s", id, tt.subject)
+				}
+			}
+		})
+	}
+}
+
+func TestAPIKeyPrefix(t *testing.T) {
+	StoreAPIKey(testDB, "tenant:", "tenant-key", APIKeyRecord{ID: "t1", Subject: "tenant"})
+	custom, err := NewAPIKeyAuthenticator(map[string]interface{}{"prefix": "tenant:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	standard, err := NewAPIKeyAuthenticator(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-API-Key", "tenant-key")
+	if id, err := custom.Authenticate(req); err != nil || id.Subject != "tenant" {
+		t.Fatalf("custom prefix: identity = %+v, err = %v", id, err)
+	}
+	if _, err := standard.Authenticate(req); err == nil {
+		t.Fatal("key stored under a custom prefix found under the default prefix")
+	}
+	RevokeAPIKey(testDB, "tenant:", "tenant-key")
+	if _, err := custom.Authenticate(req); err == nil {
+		t.Fatal("revoked key accepted")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	StoreAPIKey(testDB, "", "middleware-key", APIKeyRecord{ID: "m1", Subject: "app"})
+	a, err := NewAPIKeyAuthenticator(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		optional bool
+		key      string
+		status   int
+		subject  string
+	}{
+		{name: "valid key", key: "middleware-key", status: http.StatusOK, subject: "app"},
+		{name: "invalid key", key: "wrong", status: http.StatusUnauthorized},
+		{name: "invalid key optional", optional: true, key: "wrong", status: http.StatusUnauthorized},
+		{name: "anonymous", status: http.StatusUnauthorized},
+		{name: "anonymous optional", optional: true, status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var subject string
+			next := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if id := IdentityFromContext(req.Context()); id != nil {
+					subject = id.Subject
+				}
+			})
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			rec := httptest.NewRecorder()
+			New([]Authenticator{a}, tt.optional)(next).ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if subject != tt.subject {
+				t.Fatalf("subject = %q, want %q", subject, tt.subject)
+			}
+			if tt.status == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Fatal("missing WWW-Authenticate challenge")
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"bigHammer/internal/middleware"
+	"bigHammer/internal/shared"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultHMACKeyPrefix 签名密钥在内存数据库中的键前缀
+const DefaultHMACKeyPrefix = "hmac:"
+
+// HMACKeyRecord 签名密钥记录
+// 以JSON形式存储在 AgilityMemDB 中，键为 前缀 + key id
+type HMACKeyRecord struct {
+	// Secret 签名共享密钥
+	Secret string `json:"secret"`
+	// Subject 密钥所属主体
+	Subject string `json:"subject"`
+	// Roles 主体拥有的角色
+	Roles []string `json:"roles,omitempty"`
+	// Disabled 是否已禁用
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// HMACAuthenticator 请求签名认证器
+// 客户端使用共享密钥对以下规范字符串计算 HMAC-SHA256（十六进制或base64）：
+//
+//	METHOD \n PATH \n RAW_QUERY \n TIMESTAMP \n NONCE \n HEX(SHA256(BODY))
+//
+// 并通过 X-Key-Id、X-Timestamp、X-Nonce、X-Signature 请求头发送
+type HMACAuthenticator struct {
+	keyIDHeader     string
+	timestampHeader string
+	nonceHeader     string
+	signatureHeader string
+	prefix          string
+	maxSkew         time.Duration
+	maxBody         int64
+
+	// nonces 最近使用过的nonce，用于拒绝重放请求
+	nonces map[string]time.Time
+	// nonceQueue 按使用时间排列的nonce，从队首淘汰过期记录
+	nonceQueue []usedNonce
+	noncesMu   sync.Mutex
+}
+
+// usedNonce 使用过的nonce及其使用时间
+type usedNonce struct {
+	key  string
+	seen time.Time
+}
+
+// NewHMACAuthenticator 根据选项创建请求签名认证器
+// 选项：
+//   - key_id_header / timestamp_header / nonce_header / signature_header: 请求头名称
+//   - prefix: 数据库键前缀，默认 hmac:
+//   - max_skew: 允许的时间戳误差秒数，默认300
+//   - max_body: 参与签名的最大请求体字节数，默认10MB
+func NewHMACAuthenticator(options map[string]interface{}) (*HMACAuthenticator, error) {
+	a := &HMACAuthenticator{nonces: make(map[string]time.Time)}
+	var err error
+	if a.keyIDHeader, err = middleware.OptString(options, "key_id_header", "X-Key-Id"); err != nil {
+		return nil, err
+	}
+	if a.timestampHeader, err = middleware.OptString(options, "timestamp_header", "X-Timestamp"); err != nil {
+		return nil, err
+	}
+	if a.nonceHeader, err = middleware.OptString(options, "nonce_header", "X-Nonce"); err != nil {
+		return nil, err
+	}
+	if a.signatureHeader, err = middleware.OptString(options, "signature_header", "X-Signature"); err != nil {
+		return nil, err
+	}
+	if a.prefix, err = middleware.OptString(options, "prefix", DefaultHMACKeyPrefix); err != nil {
+		return nil, err
+	}
+	maxSkew, err := middleware.OptInt(options, "max_skew", 300)
+	if err != nil {
+		return nil, err
+	}
+	a.maxSkew = time.Duration(maxSkew) * time.Second
+	maxBody, err := middleware.OptInt(options, "max_body", 10<<20)
+	if err != nil {
+		return nil, err
+	}
+	a.maxBody = int64(maxBody)
+	return a, nil
+}
+
+// Name 实现 Authenticator 接口
+func (a *HMACAuthenticator) Name() string {
+	return "hmac"
+}
+
+// Authenticate 实现 Authenticator 接口
+func (a *HMACAuthenticator) Authenticate(req *http.Request) (*Identity, error) {
+	keyID := req.Header.Get(a.keyIDHeader)
+	signature := req.Header.Get(a.signatureHeader)
+	if keyID == "" || signature == "" {
+		return nil, ErrNoCredentials
+	}
+
+	// 不带nonce的签名请求无法识别重放，在整个时间窗口内都可以被重复提交
+	nonce := req.Header.Get(a.nonceHeader)
+	if nonce == "" {
+		return nil, errors.New("missing signature nonce")
+	}
+
+	timestamp := req.Header.Get(a.timestampHeader)
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid signature timestamp")
+	}
+	now := time.Now()
+	if skew := now.Sub(time.Unix(ts, 0)); skew > a.maxSkew || skew < -a.maxSkew {
+		return nil, errors.New("signature timestamp outside allowed window")
+	}
+
+	db, err := shared.ResolveDatabase()
+	if err != nil {
+		return nil, err
+	}
+	value, ok := db.Get(a.prefix + keyID)
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %s", keyID)
+	}
+	var record HMACKeyRecord
+	if err := json.Unmarshal([]byte(value), &record); err != nil {
+		return nil, fmt.Errorf("invalid signing key record: %v", err)
+	}
+	if record.Disabled || record.Secret == "" {
+		return nil, fmt.Errorf("signing key %s is disabled", keyID)
+	}
+
+	// 读取请求体计算摘要后放回，供后续处理器继续读取
+	body, err := io.ReadAll(io.LimitReader(req.Body, a.maxBody+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading request body: %v", err)
+	}
+	if int64(len(body)) > a.maxBody {
+		return nil, errors.New("request body too large to verify")
+	}
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	expected := SignRequest([]byte(record.Secret), req.Method, req.URL.EscapedPath(), req.URL.RawQuery, timestamp, nonce, body)
+	if !signatureMatches(signature, expected) {
+		return nil, errors.New("invalid request signature")
+	}
+	if !a.useNonce(keyID+":"+nonce, now) {
+		return nil, errors.New("replayed request nonce")
+	}
+
+	return &Identity{
+		Subject: record.Subject,
+		Method:  a.Name(),
+		KeyID:   keyID,
+		Roles:   record.Roles,
+	}, nil
+}
+
+// useNonce 记录nonce，已在时间窗口内使用过时返回false
+// nonce 按使用时间入队，每次只淘汰队首已过期的记录，单次调用的开销与请求量无关
+func (a *HMACAuthenticator) useNonce(key string, now time.Time) bool {
+	a.noncesMu.Lock()
+	defer a.noncesMu.Unlock()
+
+	for len(a.nonceQueue) > 0 && now.Sub(a.nonceQueue[0].seen) > 2*a.maxSkew {
+		delete(a.nonces, a.nonceQueue[0].key)
+		// 重新切片即可，append 扩容时只复制仍在窗口内的记录
+		a.nonceQueue = a.nonceQueue[1:]
+	}
+	if _, used := a.nonces[key]; used {
+		return false
+	}
+	a.nonces[key] = now
+	a.nonceQueue = append(a.nonceQueue, usedNonce{key: key, seen: now})
+	return true
+}
+
+// SignRequest 计算请求签名（HMAC-SHA256原始字节）
+// 客户端SDK和测试可使用该函数生成 X-Signature
+func SignRequest(secret []byte, method, path, rawQuery, timestamp, nonce string, body []byte) []byte {
+	bodyHash := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n%s\n%s", method, path, rawQuery, timestamp, nonce, hex.EncodeToString(bodyHash[:]))
+	return mac.Sum(nil)
+}
+
+// signatureMatches 以常量时间比较十六进制或base64编码的签名
+func signatureMatches(signature string, expected []byte) bool {
+	if decoded, err := hex.DecodeString(signature); err == nil && hmac.Equal(decoded, expected) {
+		return true
+	}
+	if decoded, err := base64.StdEncoding.DecodeString(signature); err == nil && hmac.Equal(decoded, expected) {
+		return true
+	}
+	return false
+}
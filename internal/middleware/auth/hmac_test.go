@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func storeHMACKey(t *testing.T, keyID string, record HMACKeyRecord) {
+	t.Helper()
+	data, err := json.Marshal(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testDB.Put(DefaultHMACKeyPrefix+keyID, string(data))
+}
+
+// signedRequest 构造签名请求，nonce 为空时不发送 X-Nonce
+func signedRequest(secret, keyID, nonce string, ts time.Time, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/orders?b=2&a=1", strings.NewReader(body))
+	timestamp := strconv.FormatInt(ts.Unix(), 10)
+	sig := SignRequest([]byte(secret), req.Method, req.URL.EscapedPath(), req.URL.RawQuery, timestamp, nonce, []byte(body))
+	req.Header.Set("X-Key-Id", keyID)
+	req.Header.Set("X-Timestamp", timestamp)
+	req.Header.Set("X-Signature", hex.EncodeToString(sig))
+	if nonce != "" {
+		req.Header.Set("X-Nonce", nonce)
+	}
+	return req
+}
+
+func TestHMACAuthenticate(t *testing.T) {
+	storeHMACKey(t, "svc", HMACKeyRecord{Secret: "s3cret", Subject: "billing"})
+	storeHMACKey(t, "off", HMACKeyRecord{Secret: "s3cret", Subject: "old", Disabled: true})
+
+	now := time.Now()
+	tests := []struct {
+		name    string
+		req     func() *http.Request
+		subject string
+		noCreds bool
+	}{
+		{
+			name:    "valid",
+			req:     func() *http.Request { return signedRequest("s3cret", "svc", "n-valid", now, `{"id":1}`) },
+			subject: "billing",
+		},
+		{
+			name: "base64 signature",
+			req: func() *http.Request {
+				req := signedRequest("s3cret", "svc", "n-base64", now, "")
+				sig, _ := hex.DecodeString(req.Header.Get("X-Signature"))
+				req.Header.Set("X-Signature", base64.StdEncoding.EncodeToString(sig))
+				return req
+			},
+			subject: "billing",
+		},
+		{
+			name:    "no credentials",
+			req:     func() *http.Request { return httptest.NewRequest(http.MethodGet, "/", nil) },
+			noCreds: true,
+		},
+		{
+			name: "missing nonce",
+			req:  func() *http.Request { return signedRequest("s3cret", "svc", "", now, "") },
+		},
+		{
+			name: "wrong secret",
+			req:  func() *http.Request { return signedRequest("other", "svc", "n-wrong", now, "") },
+		},
+		{
+			name: "tampered body",
+			req: func() *http.Request {
+				req := signedRequest("s3cret", "svc", "n-tamper", now, `{"amount":1}`)
+				req.Body = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"amount":100}`)).Body
+				return req
+			},
+		},
+		{
+			name: "stale timestamp",
+			req:  func() *http.Request { return signedRequest("s3cret", "svc", "n-stale", now.Add(-10*time.Minute), "") },
+		},
+		{
+			name: "future timestamp",
+			req:  func() *http.Request { return signedRequest("s3cret", "svc", "n-future", now.Add(10*time.Minute), "") },
+		},
+		{
+			name: "unknown key",
+			req:  func() *http.Request { return signedRequest("s3cret", "missing", "n-unknown", now, "") },
+		},
+		{
+			name: "disabled key",
+			req:  func() *http.Request { return signedRequest("s3cret", "off", "n-off", now, "") },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewHMACAuthenticator(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			id, err := a.Authenticate(tt.req())
+			switch {
+			case tt.noCreds:
+				if err != ErrNoCredentials {
+					t.Fatalf("err = %v, want ErrNoCredentials", err)
+				}
+			case tt.subject == "":
+				if err == nil || err == ErrNoCredentials {
+					t.Fatalf("err = %v, want rejection", err)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if id.Subject != tt.subject || id.KeyID != "svc" {
+					t.Fatalf("identity = %+v", id)
+				}
+			}
+		})
+	}
+}
+
+func TestHMACReplay(t *testing.T) {
+	storeHMACKey(t, "svc", HMACKeyRecord{Secret: "s3cret", Subject: "billing"})
+	a, err := NewHMACAuthenticator(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	if _, err := a.Authenticate(signedRequest("s3cret", "svc", "once", now, "body")); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	if _, err := a.Authenticate(signedRequest("s3cret", "svc", "once", now, "body")); err == nil {
+		t.Fatal("replayed nonce was accepted")
+	}
+	if _, err := a.Authenticate(signedRequest("s3cret", "svc", "twice", now, "body")); err != nil {
+		t.Fatalf("fresh nonce: %v", err)
+	}
+}
+
+func TestHMACNonceExpiry(t *testing.T) {
+	a, err := NewHMACAuthenticator(map[string]interface{}{"max_skew": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if !a.useNonce("svc:a", start) {
+		t.Fatal("first use rejected")
+	}
+	if !a.useNonce("svc:b", start.Add(time.Second)) {
+		t.Fatal("second nonce rejected")
+	}
+	if a.useNonce("svc:a", start.Add(time.Second)) {
+		t.Fatal("nonce reused inside the window")
+	}
+	// 超过两倍时间误差后，队首的记录被淘汰，新的记录保留
+	if !a.useNonce("svc:a", start.Add(2500*time.Millisecond)) {
+		t.Fatal("expired nonce not released")
+	}
+	if len(a.nonces) != 2 || len(a.nonceQueue) != 2 {
+		t.Fatalf("nonces = %d, queue = %d, want 2 and 2", len(a.nonces), len(a.nonceQueue))
+	}
+}
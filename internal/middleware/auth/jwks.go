@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+)
+
+// jwksCheckInterval 检查JWKS文件是否变更的最小间隔
+const jwksCheckInterval = 5 * time.Second
+
+// jsonWebKey JWKS 中的单个密钥（RFC 7517）
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	// N、E RSA公钥参数（base64url）
+	N string `json:"n"`
+	E string `json:"e"`
+	// K 对称密钥（base64url），用于HS256
+	K string `json:"k"`
+}
+
+// KeySet 从本地JWKS文件加载的密钥集合
+// 文件修改后会在下次使用时自动重新加载
+type KeySet struct {
+	path     string
+	mu       sync.RWMutex
+	modTime  time.Time
+	checked  time.Time
+	rsaKeys  map[string]*rsa.PublicKey
+	hmacKeys map[string][]byte
+}
+
+// LoadKeySet 加载本地JWKS文件
+// 参数：
+//   - path string: JWKS文件的完整路径
+// 返回值：
+//   - *KeySet: 密钥集合
+//   - error: 文件读取或解析失败时返回的错误信息
+func LoadKeySet(path string) (*KeySet, error) {
+	ks := &KeySet{path: path}
+	if err := ks.load(); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// load 读取并解析JWKS文件
+func (ks *KeySet) load() error {
+	info, err := os.Stat(ks.path)
+	if err != nil {
+		return fmt.Errorf("error loading jwks: %v", err)
+	}
+	content, err := os.ReadFile(ks.path)
+	if err != nil {
+		return fmt.Errorf("error loading jwks: %v", err)
+	}
+
+	var doc struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return fmt.Errorf("error parsing jwks: %v", err)
+	}
+
+	rsaKeys := make(map[string]*rsa.PublicKey)
+	hmacKeys := make(map[string][]byte)
+	for _, k := range doc.Keys {
+		switch k.Kty {
+		case "RSA":
+			pub, err := k.rsaPublicKey()
+			if err != nil {
+				return fmt.Errorf("error parsing jwk %s: %v", k.Kid, err)
+			}
+			rsaKeys[k.Kid] = pub
+		case "oct":
+			secret, err := base64.RawURLEncoding.DecodeString(k.K)
+			if err != nil {
+				return fmt.Errorf("error parsing jwk %s: %v", k.Kid, err)
+			}
+			hmacKeys[k.Kid] = secret
+		}
+	}
+
+	ks.mu.Lock()
+	ks.rsaKeys, ks.hmacKeys = rsaKeys, hmacKeys
+	ks.modTime, ks.checked = info.ModTime(), time.Now()
+	ks.mu.Unlock()
+	return nil
+}
+
+// refresh 文件修改时间变化后重新加载，加载失败时保留旧密钥
+func (ks *KeySet) refresh() {
+	ks.mu.RLock()
+	due := time.Since(ks.checked) >= jwksCheckInterval
+	modTime := ks.modTime
+	ks.mu.RUnlock()
+	if !due {
+		return
+	}
+
+	info, err := os.Stat(ks.path)
+	if err == nil && !info.ModTime().Equal(modTime) {
+		if err := ks.load(); err != nil {
+			log.Println("重新加载JWKS失败:", err)
+		} else {
+			log.Println("JWKS已重新加载:", ks.path)
+			return
+		}
+	}
+	ks.mu.Lock()
+	ks.checked = time.Now()
+	ks.mu.Unlock()
+}
+
+// RSAKey 按 kid 查找RSA公钥；kid 为空且只有一个密钥时返回该密钥
+func (ks *KeySet) RSAKey(kid string) (*rsa.PublicKey, bool) {
+	ks.refresh()
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return lookupKey(ks.rsaKeys, kid)
+}
+
+// HMACKey 按 kid 查找对称密钥；kid 为空且只有一个密钥时返回该密钥
+func (ks *KeySet) HMACKey(kid string) ([]byte, bool) {
+	ks.refresh()
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return lookupKey(ks.hmacKeys, kid)
+}
+
+// lookupKey 按 kid 查找密钥
+func lookupKey[T any](keys map[string]T, kid string) (T, bool) {
+	if key, ok := keys[kid]; ok {
+		return key, true
+	}
+	var zero T
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, true
+		}
+	}
+	return zero, false
+}
+
+// rsaPublicKey 由JWK参数构造RSA公钥
+func (k jsonWebKey) rsaPublicKey() (*rsa.PublicKey, error) {
+	nBytes, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %v", err)
+	}
+	eBytes, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %v", err)
+	}
+	e := new(big.Int).SetBytes(eBytes)
+	if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("invalid exponent")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(nBytes), E: int(e.Int64())}, nil
+}
@@ -0,0 +1,259 @@
+package auth
+
+import (
+	"bigHammer/internal/middleware"
+	"bigHammer/pkg/utils"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// jwtHeader JWT头部
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid"`
+}
+
+// JWTAuthenticator JWT Bearer Token 认证器
+// 支持 HS256（共享密钥或JWKS中的oct密钥）和 RS256（JWKS中的RSA公钥）
+type JWTAuthenticator struct {
+	secret       []byte
+	keys         *KeySet
+	algorithms   map[string]bool
+	issuer       string
+	audience     string
+	leeway       time.Duration
+	subjectClaim string
+	rolesClaim   string
+}
+
+// NewJWTAuthenticator 根据选项创建JWT认证器
+// 选项：
+//   - secret: HS256共享密钥
+//   - jwks_file: 本地JWKS文件路径（相对项目根目录）
+//   - algorithms: 允许的算法，默认 ["HS256", "RS256"]
+//   - issuer / audience: 需要匹配的 iss / aud，为空时不校验
+//   - leeway: 时间声明允许的误差秒数，默认30
+//   - subject_claim / roles_claim: 主体和角色所在的声明，默认 sub / roles
+func NewJWTAuthenticator(options map[string]interface{}) (*JWTAuthenticator, error) {
+	a := &JWTAuthenticator{algorithms: make(map[string]bool)}
+
+	secret, err := middleware.OptString(options, "secret", "")
+	if err != nil {
+		return nil, err
+	}
+	a.secret = []byte(secret)
+
+	jwksFile, err := middleware.OptString(options, "jwks_file", "")
+	if err != nil {
+		return nil, err
+	}
+	if jwksFile != "" {
+		path, err := utils.ResolvePath(jwksFile)
+		if err != nil {
+			return nil, fmt.Errorf("error resolving jwks path: %v", err)
+		}
+		if a.keys, err = LoadKeySet(path); err != nil {
+			return nil, err
+		}
+	}
+	if secret == "" && a.keys == nil {
+		return nil, errors.New("jwt auth requires secret or jwks_file")
+	}
+
+	algorithms, err := middleware.OptStrings(options, "algorithms", []string{"HS256", "RS256"})
+	if err != nil {
+		return nil, err
+	}
+	for _, alg := range algorithms {
+		if alg != "HS256" && alg != "RS256" {
+			return nil, fmt.Errorf("unsupported jwt algorithm %s", alg)
+		}
+		a.algorithms[alg] = true
+	}
+
+	if a.issuer, err = middleware.OptString(options, "issuer", ""); err != nil {
+		return nil, err
+	}
+	if a.audience, err = middleware.OptString(options, "audience", ""); err != nil {
+		return nil, err
+	}
+	leeway, err := middleware.OptInt(options, "leeway", 30)
+	if err != nil {
+		return nil, err
+	}
+	a.leeway = time.Duration(leeway) * time.Second
+	if a.subjectClaim, err = middleware.OptString(options, "subject_claim", "sub"); err != nil {
+		return nil, err
+	}
+	if a.rolesClaim, err = middleware.OptString(options, "roles_claim", "roles"); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Name 实现 Authenticator 接口
+func (a *JWTAuthenticator) Name() string {
+	return "jwt"
+}
+
+// Authenticate 实现 Authenticator 接口
+func (a *JWTAuthenticator) Authenticate(req *http.Request) (*Identity, error) {
+	authz := req.Header.Get("Authorization")
+	if len(authz) < 7 || !strings.EqualFold(authz[:7], "Bearer ") {
+		return nil, ErrNoCredentials
+	}
+	token := strings.TrimSpace(authz[7:])
+
+	header, claims, err := a.verify(token)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.validateClaims(claims, time.Now()); err != nil {
+		return nil, err
+	}
+
+	subject, _ := claims[a.subjectClaim].(string)
+	return &Identity{
+		Subject: subject,
+		Method:  a.Name(),
+		KeyID:   header.Kid,
+		Roles:   stringList(claims[a.rolesClaim]),
+		Claims:  claims,
+	}, nil
+}
+
+// verify 校验签名并解析头部和声明
+func (a *JWTAuthenticator) verify(token string) (jwtHeader, map[string]interface{}, error) {
+	var header jwtHeader
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return header, nil, errors.New("malformed jwt")
+	}
+
+	headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return header, nil, fmt.Errorf("invalid jwt header encoding: %v", err)
+	}
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return header, nil, fmt.Errorf("invalid jwt header: %v", err)
+	}
+	if !a.algorithms[header.Alg] {
+		return header, nil, fmt.Errorf("jwt algorithm %q not allowed", header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return header, nil, fmt.Errorf("invalid jwt signature encoding: %v", err)
+	}
+	signingInput := parts[0] + "." + parts[1]
+	digest := sha256.Sum256([]byte(signingInput))
+
+	switch header.Alg {
+	case "HS256":
+		secret := a.secret
+		if a.keys != nil {
+			if key, ok := a.keys.HMACKey(header.Kid); ok {
+				secret = key
+			}
+		}
+		if len(secret) == 0 {
+			return header, nil, fmt.Errorf("no hmac key for kid %q", header.Kid)
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return header, nil, errors.New("invalid jwt signature")
+		}
+	case "RS256":
+		if a.keys == nil {
+			return header, nil, errors.New("no jwks configured for RS256")
+		}
+		key, ok := a.keys.RSAKey(header.Kid)
+		if !ok {
+			return header, nil, fmt.Errorf("no rsa key for kid %q", header.Kid)
+		}
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return header, nil, errors.New("invalid jwt signature")
+		}
+	}
+
+	claimBytes, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return header, nil, fmt.Errorf("invalid jwt payload encoding: %v", err)
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(claimBytes, &claims); err != nil {
+		return header, nil, fmt.Errorf("invalid jwt payload: %v", err)
+	}
+	return header, claims, nil
+}
+
+// validateClaims 校验 exp、nbf、iat、iss、aud 声明
+func (a *JWTAuthenticator) validateClaims(claims map[string]interface{}, now time.Time) error {
+	if exp, ok := numericClaim(claims, "exp"); ok && now.Add(-a.leeway).After(exp) {
+		return errors.New("jwt has expired")
+	}
+	if nbf, ok := numericClaim(claims, "nbf"); ok && now.Add(a.leeway).Before(nbf) {
+		return errors.New("jwt is not valid yet")
+	}
+	if iat, ok := numericClaim(claims, "iat"); ok && now.Add(a.leeway).Before(iat) {
+		return errors.New("jwt issued in the future")
+	}
+	if a.issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != a.issuer {
+			return fmt.Errorf("unexpected jwt issuer %q", iss)
+		}
+	}
+	if a.audience != "" {
+		found := false
+		for _, aud := range stringList(claims["aud"]) {
+			if aud == a.audience {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.New("jwt audience mismatch")
+		}
+	}
+	return nil
+}
+
+// numericClaim 读取以Unix秒表示的时间声明
+func numericClaim(claims map[string]interface{}, name string) (time.Time, bool) {
+	v, ok := claims[name].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(v), 0), true
+}
+
+// stringList 将字符串或字符串数组声明转换为切片
+func stringList(v interface{}) []string {
+	switch list := v.(type) {
+	case string:
+		if list == "" {
+			return nil
+		}
+		return strings.Fields(list)
+	case []interface{}:
+		result := make([]string, 0, len(list))
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+)
+
+// 记录客户端地址的转发头
+const (
+	// HeaderXForwardedFor X-Forwarded-For，nginx 等代理默认追加的请求头
+	HeaderXForwardedFor = "x-forwarded-for"
+	// HeaderForwarded RFC 7239 Forwarded 请求头
+	HeaderForwarded = "forwarded"
+)
+
+// proxyConfig 受信任代理配置
+type proxyConfig struct {
+	// prefixes 受信任的反向代理网段，来自这些地址的请求才会读取转发头
+	prefixes []netip.Prefix
+	// header 受信任代理写入的转发头，只读取这一个请求头
+	header string
+}
+
+var trustedProxies atomic.Pointer[proxyConfig]
+
+// ParseCIDRs 解析网段列表，单个IP视为只包含该地址的网段
+// 参数：
+//   - list []string: 网段或IP，例如 10.0.0.0/8、192.168.1.10、::1
+// 返回值：
+//   - []netip.Prefix: 解析后的网段
+//   - error: 格式无效时返回的错误信息
+func ParseCIDRs(list []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(list))
+	for _, item := range list {
+		item = strings.TrimSpace(item)
+		if strings.Contains(item, "/") {
+			prefix, err := netip.ParsePrefix(item)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cidr %q", item)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ip %q", item)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// ContainsIP 判断IP是否位于任一网段内
+func ContainsIP(prefixes []netip.Prefix, ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseForwardedHeader 校验并规范化转发头名称，为空时使用 X-Forwarded-For
+// 参数：
+//   - name string: x-forwarded-for 或 forwarded（不区分大小写）
+// 返回值：
+//   - string: HeaderXForwardedFor 或 HeaderForwarded
+//   - error: 名称无效时返回的错误信息
+func ParseForwardedHeader(name string) (string, error) {
+	switch strings.ToLower(name) {
+	case "", HeaderXForwardedFor:
+		return HeaderXForwardedFor, nil
+	case HeaderForwarded:
+		return HeaderForwarded, nil
+	}
+	return "", fmt.Errorf("unknown forwarded header %q", name)
+}
+
+// SetTrustedProxies 设置受信任的反向代理网段和它们写入的转发头
+// 只读取受信任代理实际写入的转发头，另一个请求头由客户端控制，可能原样经过代理，不可信
+// 参数：
+//   - prefixes []netip.Prefix: 受信任的反向代理网段，为空时不读取转发头
+//   - header string: ParseForwardedHeader 返回的转发头
+func SetTrustedProxies(prefixes []netip.Prefix, header string) {
+	trustedProxies.Store(&proxyConfig{prefixes: prefixes, header: header})
+}
+
+// ClientIP 返回请求的客户端IP（不含端口）
+// 直连地址属于受信任代理时，按配置的转发头（X-Forwarded-For 或 Forwarded）从右向左
+// 跳过受信任代理，返回第一个不受信任的地址
+func ClientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	cfg := trustedProxies.Load()
+	if cfg == nil || len(cfg.prefixes) == 0 || !ContainsIP(cfg.prefixes, host) {
+		return host
+	}
+	chain := forwardedFor(req.Header, cfg.header)
+	for i := len(chain) - 1; i >= 0; i-- {
+		ip := chain[i]
+		if _, err := netip.ParseAddr(ip); err != nil {
+			// 无法识别的地址（如 unknown 或混淆标识）之前的内容不可信
+			break
+		}
+		host = ip
+		if !ContainsIP(cfg.prefixes, ip) {
+			break
+		}
+	}
+	return host
+}
+
+// forwardedFor 返回转发头记录的客户端地址链，从左到右依次为原始客户端和各级代理
+func forwardedFor(header http.Header, name string) []string {
+	var chain []string
+	if name == HeaderForwarded {
+		for _, value := range header.Values("Forwarded") {
+			for _, element := range strings.Split(value, ",") {
+				for _, pair := range strings.Split(element, ";") {
+					key, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+					if ok && strings.EqualFold(key, "for") {
+						chain = append(chain, forwardedNode(val))
+					}
+				}
+			}
+		}
+		return chain
+	}
+	for _, value := range header.Values("X-Forwarded-For") {
+		for _, ip := range strings.Split(value, ",") {
+			chain = append(chain, strings.TrimSpace(ip))
+		}
+	}
+	return chain
+}
+
+// forwardedNode 解析 Forwarded 头中 for 参数的节点，去掉引号、方括号和端口
+// 例如 "[2001:db8::1]:4711" → 2001:db8::1，192.0.2.60:8080 → 192.0.2.60
+func forwardedNode(node string) string {
+	node = strings.Trim(node, `"`)
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		return host
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(node, "["), "]")
+}
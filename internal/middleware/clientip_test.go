@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseCIDRs(t *testing.T) {
+	tests := []struct {
+		in      string
+		ip      string
+		match   bool
+		wantErr bool
+	}{
+		{in: "10.0.0.0/8", ip: "10.1.2.3", match: true},
+		{in: "10.1.2.3/8", ip: "10.200.0.1", match: true},
+		{in: "192.168.1.10", ip: "192.168.1.10", match: true},
+		{in: "192.168.1.10", ip: "192.168.1.11"},
+		{in: "10.0.0.0/8", ip: "::ffff:10.0.0.1", match: true},
+		{in: "::1", ip: "::1", match: true},
+		{in: "2001:db8::/32", ip: "2001:db8::5", match: true},
+		{in: "10.0.0.0/8", ip: "not-an-ip"},
+		{in: "10.0.0.0/33", wantErr: true},
+		{in: "example.com", wantErr: true},
+	}
+	for _, tt := range tests {
+		prefixes, err := ParseCIDRs([]string{tt.in})
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseCIDRs(%q) succeeded, want error", tt.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseCIDRs(%q): %v", tt.in, err)
+			continue
+		}
+		if got := ContainsIP(prefixes, tt.ip); got != tt.match {
+			t.Errorf("ContainsIP(%q, %q) = %v, want %v", tt.in, tt.ip, got, tt.match)
+		}
+	}
+}
+
+func TestParseForwardedHeader(t *testing.T) {
+	tests := map[string]string{
+		"":                HeaderXForwardedFor,
+		"X-Forwarded-For": HeaderXForwardedFor,
+		"Forwarded":       HeaderForwarded,
+	}
+	for in, want := range tests {
+		if got, err := ParseForwardedHeader(in); err != nil || got != want {
+			t.Errorf("ParseForwardedHeader(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseForwardedHeader("X-Real-IP"); err == nil {
+		t.Error("ParseForwardedHeader(X-Real-IP) succeeded, want error")
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trusted, err := ParseCIDRs([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer SetTrustedProxies(nil, HeaderXForwardedFor)
+
+	tests := []struct {
+		name      string
+		header    string
+		remote    string
+		xff       []string
+		forwarded string
+		want      string
+	}{
+		{name: "untrusted peer ignores xff", header: HeaderXForwardedFor, remote: "203.0.113.9:1234", xff: []string{"198.51.100.1"}, want: "203.0.113.9"},
+		{name: "trusted peer uses xff", header: HeaderXForwardedFor, remote: "10.0.0.1:1234", xff: []string{"198.51.100.1"}, want: "198.51.100.1"},
+		{name: "skips trusted hops", header: HeaderXForwardedFor, remote: "10.0.0.1:1234", xff: []string{"198.51.100.1, 10.0.0.7"}, want: "198.51.100.1"},
+		{name: "spoofed prefix ignored", header: HeaderXForwardedFor, remote: "10.0.0.1:1234", xff: []string{"1.1.1.1, 198.51.100.1"}, want: "198.51.100.1"},
+		{name: "multiple xff headers", header: HeaderXForwardedFor, remote: "10.0.0.1:1234", xff: []string{"1.1.1.1", "198.51.100.1"}, want: "198.51.100.1"},
+		{name: "garbage stops walk", header: HeaderXForwardedFor, remote: "10.0.0.1:1234", xff: []string{"1.1.1.1, unknown, 10.0.0.2"}, want: "10.0.0.2"},
+		{name: "all trusted", header: HeaderXForwardedFor, remote: "10.0.0.1:1234", xff: []string{"10.0.0.3"}, want: "10.0.0.3"},
+		{name: "no header", header: HeaderXForwardedFor, remote: "10.0.0.1:1234", want: "10.0.0.1"},
+		{name: "xff mode ignores forwarded", header: HeaderXForwardedFor, remote: "10.0.0.1:1234", forwarded: "for=198.51.100.1", want: "10.0.0.1"},
+		{name: "forwarded", header: HeaderForwarded, remote: "10.0.0.1:1234", forwarded: `for=198.51.100.1;proto=https, for=10.0.0.5`, want: "198.51.100.1"},
+		{name: "forwarded ipv6 with port", header: HeaderForwarded, remote: "10.0.0.1:1234", forwarded: `for="[2001:db8::1]:4711"`, want: "2001:db8::1"},
+		{name: "forwarded mode ignores xff", header: HeaderForwarded, remote: "10.0.0.1:1234", xff: []string{"198.51.100.1"}, want: "10.0.0.1"},
+		{name: "remote without port", header: HeaderXForwardedFor, remote: "203.0.113.9", want: "203.0.113.9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetTrustedProxies(trusted, tt.header)
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remote
+			for _, v := range tt.xff {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			if tt.forwarded != "" {
+				req.Header.Set("Forwarded", tt.forwarded)
+			}
+			if got := ClientIP(req); got != tt.want {
+				t.Fatalf("ClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CORSOptions 跨域资源共享配置
+type CORSOptions struct {
+	// AllowedOrigins 允许的来源列表，"*" 表示任意来源
+	AllowedOrigins []string
+	// AllowedMethods 预检请求允许的方法
+	AllowedMethods []string
+	// AllowedHeaders 预检请求允许的请求头
+	AllowedHeaders []string
+	// ExposedHeaders 允许浏览器读取的响应头
+	ExposedHeaders []string
+	// AllowCredentials 是否允许携带凭证
+	AllowCredentials bool
+	// MaxAge 预检结果缓存秒数，0表示不设置
+	MaxAge int
+}
+
+// allowOrigin 判断来源是否被允许
+func (o CORSOptions) allowOrigin(origin string) bool {
+	for _, allowed := range o.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// CORS 跨域中间件
+// 功能：
+// 1. 对允许的来源添加 Access-Control-Allow-* 响应头
+// 2. 直接响应预检请求（OPTIONS + Access-Control-Request-Method），不转发到后端
+// 参数：
+//   - opts CORSOptions: 跨域配置
+// 返回值：
+//   - Middleware: 跨域中间件
+func CORS(opts CORSOptions) Middleware {
+	methods := strings.Join(opts.AllowedMethods, ", ")
+	headers := strings.Join(opts.AllowedHeaders, ", ")
+	exposed := strings.Join(opts.ExposedHeaders, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			origin := req.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, req)
+				return
+			}
+			h := w.Header()
+			h.Add("Vary", "Origin")
+			if !opts.allowOrigin(origin) {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			// 回显具体来源；通配符来源不能与 allow_credentials 同时配置，由工厂函数校验
+			h.Set("Access-Control-Allow-Origin", origin)
+			if opts.AllowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			preflight := req.Method == http.MethodOptions && req.Header.Get("Access-Control-Request-Method") != ""
+			if !preflight {
+				if exposed != "" {
+					h.Set("Access-Control-Expose-Headers", exposed)
+				}
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+			h.Set("Access-Control-Allow-Methods", methods)
+			if headers != "" {
+				h.Set("Access-Control-Allow-Headers", headers)
+			} else if reqHeaders := req.Header.Get("Access-Control-Request-Headers"); reqHeaders != "" {
+				h.Set("Access-Control-Allow-Headers", reqHeaders)
+			}
+			if opts.MaxAge > 0 {
+				h.Set("Access-Control-Max-Age", strconv.Itoa(opts.MaxAge))
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+func init() {
+	Register("cors", func(options map[string]interface{}) (Middleware, error) {
+		var opts CORSOptions
+		var err error
+		if opts.AllowedOrigins, err = OptStrings(options, "allowed_origins", []string{"*"}); err != nil {
+			return nil, err
+		}
+		if opts.AllowedMethods, err = OptStrings(options, "allowed_methods",
+			[]string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}); err != nil {
+			return nil, err
+		}
+		if opts.AllowedHeaders, err = OptStrings(options, "allowed_headers", nil); err != nil {
+			return nil, err
+		}
+		if opts.ExposedHeaders, err = OptStrings(options, "exposed_headers", []string{RequestIDHeader}); err != nil {
+			return nil, err
+		}
+		if opts.AllowCredentials, err = OptBool(options, "allow_credentials", false); err != nil {
+			return nil, err
+		}
+		if opts.MaxAge, err = OptInt(options, "max_age", 600); err != nil {
+			return nil, err
+		}
+		// 允许任意来源携带凭证会让任何网站都能以用户身份读取响应
+		if opts.AllowCredentials {
+			for _, origin := range opts.AllowedOrigins {
+				if origin == "*" {
+					return nil, fmt.Errorf("cors: allow_credentials cannot be used with allowed_origins \"*\"")
+				}
+			}
+		}
+		return CORS(opts), nil
+	})
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// errorBody 网关错误响应体
+// 与插件 Response 的结构保持一致，便于客户端统一解析
+type errorBody struct {
+	Status  int         `json:"status"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data"`
+}
+
+// WriteError 以JSON格式写入网关错误响应
+// 参数：
+//   - w http.ResponseWriter: 响应写入器
+//   - status int: HTTP状态码
+//   - message string: 错误描述
+//   - data interface{}: 附加数据，可为nil
+// 返回值：无
+func WriteError(w http.ResponseWriter, status int, message string, data interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorBody{Status: status, Message: message, Data: data})
+}
@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// gzipResponseWriter 压缩响应体的 ResponseWriter 包装器
+// 在写入响应头时决定是否压缩：已编码、无响应体或过小的响应保持原样
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	pool        *sync.Pool
+	minLength   int
+	gz          *gzip.Writer
+	decided     bool
+	wroteHeader bool
+}
+
+// decide 根据响应头决定是否启用压缩
+func (g *gzipResponseWriter) decide(status int) {
+	if g.decided {
+		return
+	}
+	g.decided = true
+	h := g.ResponseWriter.Header()
+	if h.Get("Content-Encoding") != "" || status < 200 || status == http.StatusNoContent ||
+		status == http.StatusNotModified || status == http.StatusPartialContent {
+		return
+	}
+	if cl := h.Get("Content-Length"); cl != "" {
+		var n int
+		if _, err := fmt.Sscanf(cl, "%d", &n); err == nil && n < g.minLength {
+			return
+		}
+	}
+	h.Del("Content-Length")
+	h.Set("Content-Encoding", "gzip")
+	g.gz = g.pool.Get().(*gzip.Writer)
+	g.gz.Reset(g.ResponseWriter)
+}
+
+func (g *gzipResponseWriter) WriteHeader(status int) {
+	if g.wroteHeader {
+		return
+	}
+	g.wroteHeader = true
+	g.decide(status)
+	g.ResponseWriter.WriteHeader(status)
+}
+
+func (g *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !g.wroteHeader {
+		g.WriteHeader(http.StatusOK)
+	}
+	if g.gz == nil {
+		return g.ResponseWriter.Write(b)
+	}
+	return g.gz.Write(b)
+}
+
+// Flush 刷新压缩缓冲区并实现 http.Flusher 接口
+func (g *gzipResponseWriter) Flush() {
+	if !g.wroteHeader {
+		g.WriteHeader(http.StatusOK)
+	}
+	if g.gz != nil {
+		g.gz.Flush()
+	}
+	if f, ok := g.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack 实现 http.Hijacker 接口
+func (g *gzipResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := g.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	g.decided, g.wroteHeader = true, true
+	return h.Hijack()
+}
+
+// Unwrap 返回底层 ResponseWriter
+func (g *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return g.ResponseWriter
+}
+
+// close 结束压缩流并归还压缩器
+func (g *gzipResponseWriter) close() {
+	if g.gz == nil {
+		return
+	}
+	if err := g.gz.Close(); err != nil {
+		fmt.Println("关闭gzip压缩流失败:", err)
+	}
+	g.pool.Put(g.gz)
+	g.gz = nil
+}
+
+// Gzip 响应压缩中间件
+// 功能：
+// 1. 客户端声明 Accept-Encoding: gzip 时压缩响应体
+// 2. 跳过协议升级请求和已编码的响应
+// 参数：
+//   - level int: 压缩级别（gzip.BestSpeed ~ gzip.BestCompression）
+//   - minLength int: 已知 Content-Length 小于该值时不压缩
+// 返回值：
+//   - Middleware: 压缩中间件
+//   - error: 压缩级别无效时返回的错误信息
+func Gzip(level, minLength int) (Middleware, error) {
+	if _, err := gzip.NewWriterLevel(nil, level); err != nil {
+		return nil, err
+	}
+	pool := &sync.Pool{New: func() interface{} {
+		gz, _ := gzip.NewWriterLevel(nil, level)
+		return gz
+	}}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			if !strings.Contains(req.Header.Get("Accept-Encoding"), "gzip") || req.Header.Get("Upgrade") != "" {
+				next.ServeHTTP(w, req)
+				return
+			}
+			gw := &gzipResponseWriter{ResponseWriter: w, pool: pool, minLength: minLength}
+			defer gw.close()
+			next.ServeHTTP(gw, req)
+		})
+	}, nil
+}
+
+func init() {
+	Register("gzip", func(options map[string]interface{}) (Middleware, error) {
+		level, err := OptInt(options, "level", gzip.DefaultCompression)
+		if err != nil {
+			return nil, err
+		}
+		minLength, err := OptInt(options, "min_length", 256)
+		if err != nil {
+			return nil, err
+		}
+		return Gzip(level, minLength)
+	})
+}
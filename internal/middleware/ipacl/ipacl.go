@@ -0,0 +1,110 @@
+package ipacl
+
+import (
+	"bigHammer/internal/accesslog"
+	"bigHammer/internal/middleware"
+	"fmt"
+	"log"
+	"net/http"
+	"net/netip"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var decisions = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "gateway_ip_acl_decisions_total",
+	Help: "IP访问控制决策次数，scope 取值 global / route，decision 取值 allowed / denied",
+}, []string{"route", "scope", "decision"})
+
+// Rules IP访问控制规则，对应 router.json 中全局、分组和路由的 acl 字段
+// 拒绝列表优先；配置了允许列表时，只有位于其中的地址可以访问
+type Rules struct {
+	// Allow 允许访问的网段或IP，例如 10.0.0.0/8、192.168.1.10，为空时允许所有未被拒绝的地址
+	Allow []string `json:"allow,omitempty"`
+	// Deny 拒绝访问的网段或IP
+	Deny []string `json:"deny,omitempty"`
+}
+
+// ACL 编译后的IP访问控制规则
+type ACL struct {
+	// scope 规则作用范围：global 或 route，用于日志和指标
+	scope string
+	allow []netip.Prefix
+	deny  []netip.Prefix
+}
+
+// Compile 编译IP访问控制规则
+// 参数：
+//   - rules *Rules: 规则配置，为nil或为空时返回nil
+//   - scope string: 规则作用范围：global 或 route
+// 返回值：
+//   - *ACL: 编译后的规则
+//   - error: 网段格式无效时返回的错误信息
+func Compile(rules *Rules, scope string) (*ACL, error) {
+	if rules == nil || (len(rules.Allow) == 0 && len(rules.Deny) == 0) {
+		return nil, nil
+	}
+	allow, err := middleware.ParseCIDRs(rules.Allow)
+	if err != nil {
+		return nil, fmt.Errorf("acl allow: %v", err)
+	}
+	deny, err := middleware.ParseCIDRs(rules.Deny)
+	if err != nil {
+		return nil, fmt.Errorf("acl deny: %v", err)
+	}
+	return &ACL{scope: scope, allow: allow, deny: deny}, nil
+}
+
+// Allowed 判断客户端IP是否允许访问
+// 返回值：
+//   - bool: 是否允许
+//   - string: 拒绝原因，允许时为空
+func (a *ACL) Allowed(ip string) (bool, string) {
+	if a == nil {
+		return true, ""
+	}
+	if middleware.ContainsIP(a.deny, ip) {
+		return false, "deny list"
+	}
+	if len(a.allow) > 0 && !middleware.ContainsIP(a.allow, ip) {
+		return false, "not in allow list"
+	}
+	return true, ""
+}
+
+// Middleware 创建IP访问控制中间件，请求需要依次通过所有规则，否则返回403
+// 客户端IP由 middleware.ClientIP 按受信任代理配置解析
+// 参数：
+//   - acls ...*ACL: 按顺序检查的规则，nil 会被忽略
+// 返回值：
+//   - middleware.Middleware: 没有任何规则时为nil
+func Middleware(acls ...*ACL) middleware.Middleware {
+	var active []*ACL
+	for _, a := range acls {
+		if a != nil {
+			active = append(active, a)
+		}
+	}
+	if len(active) == 0 {
+		return nil
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ip := middleware.ClientIP(req)
+			route := middleware.RouteFromContext(req.Context())
+			for _, a := range active {
+				if ok, reason := a.Allowed(ip); !ok {
+					decisions.WithLabelValues(route, a.scope, "denied").Inc()
+					log.Printf("IP访问被拒绝 [%s]: ip=%s route=%s scope=%s reason=%s",
+						middleware.RequestIDFromContext(req.Context()), ip, route, a.scope, reason)
+					accesslog.FromContext(req.Context()).SetError(fmt.Errorf("ip %s denied by %s acl (%s)", ip, a.scope, reason))
+					middleware.WriteError(w, http.StatusForbidden, "Forbidden", nil)
+					return
+				}
+				decisions.WithLabelValues(route, a.scope, "allowed").Inc()
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package ipacl
+
+import (
+	"bigHammer/internal/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules Rules
+		ip    string
+		want  bool
+	}{
+		{name: "allow list hit", rules: Rules{Allow: []string{"10.0.0.0/8"}}, ip: "10.1.1.1", want: true},
+		{name: "allow list miss", rules: Rules{Allow: []string{"10.0.0.0/8"}}, ip: "192.168.0.1"},
+		{name: "deny list hit", rules: Rules{Deny: []string{"192.168.0.0/16"}}, ip: "192.168.0.1"},
+		{name: "deny list miss", rules: Rules{Deny: []string{"192.168.0.0/16"}}, ip: "10.1.1.1", want: true},
+		{name: "deny wins over allow", rules: Rules{Allow: []string{"10.0.0.0/8"}, Deny: []string{"10.0.0.5"}}, ip: "10.0.0.5"},
+		{name: "ipv4 mapped ipv6", rules: Rules{Allow: []string{"10.0.0.0/8"}}, ip: "::ffff:10.0.0.1", want: true},
+		{name: "unparsable ip with allow list", rules: Rules{Allow: []string{"10.0.0.0/8"}}, ip: "unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			acl, err := Compile(&tt.rules, "route")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := acl.Allowed(tt.ip); got != tt.want {
+				t.Fatalf("Allowed(%q) = %v, want %v", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompile(t *testing.T) {
+	if acl, err := Compile(nil, "global"); acl != nil || err != nil {
+		t.Fatalf("Compile(nil) = %v, %v; want nil, nil", acl, err)
+	}
+	if acl, err := Compile(&Rules{}, "global"); acl != nil || err != nil {
+		t.Fatalf("Compile(empty) = %v, %v; want nil, nil", acl, err)
+	}
+	if _, err := Compile(&Rules{Deny: []string{"10.0.0.0/99"}}, "global"); err == nil {
+		t.Fatal("invalid cidr accepted")
+	}
+	if Middleware(nil, nil) != nil {
+		t.Fatal("Middleware without rules should be nil")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	global, err := Compile(&Rules{Deny: []string{"203.0.113.0/24"}}, "global")
+	if err != nil {
+		t.Fatal(err)
+	}
+	route, err := Compile(&Rules{Allow: []string{"198.51.100.0/24"}}, "route")
+	if err != nil {
+		t.Fatal(err)
+	}
+	trusted, err := middleware.ParseCIDRs([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	middleware.SetTrustedProxies(trusted, middleware.HeaderXForwardedFor)
+	defer middleware.SetTrustedProxies(nil, middleware.HeaderXForwardedFor)
+
+	handler := Middleware(global, nil, route)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	tests := []struct {
+		name   string
+		remote string
+		xff    string
+		status int
+	}{
+		{name: "allowed", remote: "198.51.100.7:1000", status: http.StatusNoContent},
+		{name: "denied by global", remote: "203.0.113.7:1000", status: http.StatusForbidden},
+		{name: "not in route allow list", remote: "192.0.2.1:1000", status: http.StatusForbidden},
+		{name: "allowed via trusted proxy", remote: "10.0.0.1:1000", xff: "198.51.100.7", status: http.StatusNoContent},
+		{name: "denied via trusted proxy", remote: "10.0.0.1:1000", xff: "203.0.113.7", status: http.StatusForbidden},
+		{name: "spoofed xff from untrusted peer", remote: "192.0.2.1:1000", xff: "198.51.100.7", status: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remote
+			if tt.xff != "" {
+				req.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"time"
+)
+
+// Logging 请求日志中间件
+// 记录每个请求的方法、路径、状态码、响应大小和耗时
+func Logging() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			rec := NewStatusRecorder(w)
+			next.ServeHTTP(rec, req)
+			log.Printf("%s %s %d %dB %s [%s]",
+				req.Method, req.URL.Path, rec.Status, rec.Bytes, time.Since(start), RequestIDFromContext(req.Context()))
+		})
+	}
+}
+
+func init() {
+	Register("logging", func(options map[string]interface{}) (Middleware, error) {
+		return Logging(), nil
+	})
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// Middleware 中间件函数类型
+// 接收下一个处理器并返回包装后的处理器，
+// 请求按注册顺序依次经过中间件，响应按相反顺序返回
+type Middleware func(next http.Handler) http.Handler
+
+// Factory 中间件工厂函数类型
+// 根据 router.json 中的中间件选项创建中间件实例
+// 参数：
+//   - options map[string]interface{}: 中间件选项，未配置时为nil
+// 返回值：
+//   - Middleware: 创建的中间件
+//   - error: 选项无效时返回的错误信息
+type Factory func(options map[string]interface{}) (Middleware, error)
+
+// registry 中间件注册表
+// key: 中间件名称
+// value: 中间件工厂函数
+var (
+	registry   = make(map[string]Factory)
+	registryMu sync.RWMutex
+)
+
+// Register 注册中间件工厂
+// 功能：
+// 1. 将中间件工厂存储到注册表中
+// 2. 同名中间件会被覆盖，便于插件替换内置实现
+// 参数：
+//   - name string: 中间件名称，即 router.json 中引用的名称
+//   - factory Factory: 中间件工厂函数
+// 返回值：无
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = factory
+}
+
+// Names 返回所有已注册的中间件名称（按字母排序）
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Build 根据名称和选项创建中间件
+// 功能：
+// 1. 查找中间件工厂
+// 2. 使用选项创建中间件实例
+// 参数：
+//   - name string: 中间件名称
+//   - options map[string]interface{}: 中间件选项
+// 返回值：
+//   - Middleware: 创建的中间件
+//   - error: 中间件未注册或创建失败时返回的错误信息
+func Build(name string, options map[string]interface{}) (Middleware, error) {
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("middleware %s not registered", name)
+	}
+
+	mw, err := factory(options)
+	if err != nil {
+		return nil, fmt.Errorf("error building middleware %s: %v", name, err)
+	}
+	return mw, nil
+}
+
+// Chain 将中间件链接到处理器上
+// 功能：
+// 1. 按相反顺序包装处理器，使第一个中间件成为最外层
+// 参数：
+//   - handler http.Handler: 最终处理请求的处理器（后端）
+//   - middlewares ...Middleware: 中间件列表
+// 返回值：
+//   - http.Handler: 包装后的处理器
+func Chain(handler http.Handler, middlewares ...Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import "fmt"
+
+// 中间件选项读取辅助函数
+// router.json 经 encoding/json 解码后，数字为 float64、数组为 []interface{}，
+// 以下函数负责类型转换并在缺省时返回默认值
+
+// OptString 读取字符串选项
+func OptString(options map[string]interface{}, key, def string) (string, error) {
+	v, ok := options[key]
+	if !ok || v == nil {
+		return def, nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("option %s must be a string", key)
+	}
+	return s, nil
+}
+
+// OptBool 读取布尔选项
+func OptBool(options map[string]interface{}, key string, def bool) (bool, error) {
+	v, ok := options[key]
+	if !ok || v == nil {
+		return def, nil
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("option %s must be a boolean", key)
+	}
+	return b, nil
+}
+
+// OptInt 读取整数选项
+func OptInt(options map[string]interface{}, key string, def int) (int, error) {
+	v, ok := options[key]
+	if !ok || v == nil {
+		return def, nil
+	}
+	switch n := v.(type) {
+	case float64:
+		return int(n), nil
+	case int:
+		return n, nil
+	}
+	return 0, fmt.Errorf("option %s must be a number", key)
+}
+
+// OptStrings 读取字符串数组选项
+func OptStrings(options map[string]interface{}, key string, def []string) ([]string, error) {
+	v, ok := options[key]
+	if !ok || v == nil {
+		return def, nil
+	}
+	switch list := v.(type) {
+	case []string:
+		return list, nil
+	case []interface{}:
+		result := make([]string, 0, len(list))
+		for _, item := range list {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("option %s must be an array of strings", key)
+			}
+			result = append(result, s)
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("option %s must be an array of strings", key)
+}
+
+// OptMap 读取嵌套对象选项
+func OptMap(options map[string]interface{}, key string) (map[string]interface{}, error) {
+	v, ok := options[key]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("option %s must be an object", key)
+	}
+	return m, nil
+}
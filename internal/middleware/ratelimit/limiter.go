@@ -0,0 +1,171 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval 清理空闲限流状态的间隔
+const sweepInterval = time.Minute
+
+// Limiter 限流器接口
+type Limiter interface {
+	// Allow 判断指定key的请求是否允许通过
+	// 返回值：
+	//   - bool: 是否允许
+	//   - int: 当前窗口剩余可用次数
+	//   - time.Duration: 被拒绝时建议的重试等待时间
+	Allow(key string, now time.Time) (bool, int, time.Duration)
+}
+
+// bucket 令牌桶状态
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// TokenBucket 令牌桶限流器
+// 以固定速率补充令牌，允许不超过容量的突发流量
+type TokenBucket struct {
+	rate     float64 // 每秒补充的令牌数
+	capacity float64
+	mu       sync.Mutex
+	buckets  map[string]*bucket
+	swept    time.Time
+}
+
+// NewTokenBucket 创建令牌桶限流器
+// 参数：
+//   - limit int: 每个周期补充的令牌数
+//   - period time.Duration: 补充周期
+//   - burst int: 桶容量，小于limit时使用limit
+// 返回值：
+//   - *TokenBucket: 令牌桶限流器
+func NewTokenBucket(limit int, period time.Duration, burst int) *TokenBucket {
+	if burst < limit {
+		burst = limit
+	}
+	return &TokenBucket{
+		rate:     float64(limit) / period.Seconds(),
+		capacity: float64(burst),
+		buckets:  make(map[string]*bucket),
+	}
+}
+
+// Allow 实现 Limiter 接口
+func (tb *TokenBucket) Allow(key string, now time.Time) (bool, int, time.Duration) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+
+	tb.sweep(now)
+	b, ok := tb.buckets[key]
+	if !ok {
+		b = &bucket{tokens: tb.capacity, last: now}
+		tb.buckets[key] = b
+	}
+	b.tokens = math.Min(tb.capacity, b.tokens+now.Sub(b.last).Seconds()*tb.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, int(b.tokens), 0
+	}
+	wait := time.Duration((1 - b.tokens) / tb.rate * float64(time.Second))
+	return false, 0, wait
+}
+
+// sweep 删除已经回满的令牌桶，避免状态无限增长
+func (tb *TokenBucket) sweep(now time.Time) {
+	if now.Sub(tb.swept) < sweepInterval {
+		return
+	}
+	tb.swept = now
+	for key, b := range tb.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*tb.rate >= tb.capacity {
+			delete(tb.buckets, key)
+		}
+	}
+}
+
+// window 滑动窗口计数状态
+type window struct {
+	start    time.Time
+	current  int
+	previous int
+}
+
+// SlidingWindow 滑动窗口限流器
+// 使用当前窗口计数与上一窗口按重叠比例加权的估算值，
+// 避免固定窗口边界处的流量翻倍
+type SlidingWindow struct {
+	limit   int
+	period  time.Duration
+	mu      sync.Mutex
+	windows map[string]*window
+	swept   time.Time
+}
+
+// NewSlidingWindow 创建滑动窗口限流器
+// 参数：
+//   - limit int: 每个窗口允许的请求数
+//   - period time.Duration: 窗口长度
+// 返回值：
+//   - *SlidingWindow: 滑动窗口限流器
+func NewSlidingWindow(limit int, period time.Duration) *SlidingWindow {
+	return &SlidingWindow{
+		limit:   limit,
+		period:  period,
+		windows: make(map[string]*window),
+	}
+}
+
+// Allow 实现 Limiter 接口
+func (sw *SlidingWindow) Allow(key string, now time.Time) (bool, int, time.Duration) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	sw.sweep(now)
+	start := now.Truncate(sw.period)
+	w, ok := sw.windows[key]
+	if !ok {
+		w = &window{start: start}
+		sw.windows[key] = w
+	}
+	if !w.start.Equal(start) {
+		if start.Sub(w.start) == sw.period {
+			w.previous = w.current
+		} else {
+			w.previous = 0
+		}
+		w.current = 0
+		w.start = start
+	}
+
+	overlap := 1 - float64(now.Sub(start))/float64(sw.period)
+	estimated := float64(w.previous)*overlap + float64(w.current)
+	if estimated+1 > float64(sw.limit) {
+		// 等待上一窗口的权重衰减到足以容纳一个请求，最长等到下一个窗口开始
+		wait := start.Add(sw.period).Sub(now)
+		if w.previous > 0 && float64(w.current)+1 <= float64(sw.limit) {
+			needed := (estimated + 1 - float64(sw.limit)) / float64(w.previous)
+			wait = time.Duration(needed * float64(sw.period))
+		}
+		return false, 0, wait
+	}
+	w.current++
+	return true, int(float64(sw.limit) - estimated - 1), 0
+}
+
+// sweep 删除超过两个窗口未使用的状态
+func (sw *SlidingWindow) sweep(now time.Time) {
+	if now.Sub(sw.swept) < sweepInterval {
+		return
+	}
+	sw.swept = now
+	for key, w := range sw.windows {
+		if now.Sub(w.start) >= 2*sw.period {
+			delete(sw.windows, key)
+		}
+	}
+}
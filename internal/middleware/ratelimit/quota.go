@@ -0,0 +1,113 @@
+package ratelimit
+
+import (
+	"bigHammer/internal/interface/database"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// quotaPersistInterval 配额计数持久化到数据文件的间隔
+const quotaPersistInterval = 10 * time.Second
+
+// QuotaPrefix 配额计数在内存数据库中的键前缀
+const QuotaPrefix = "quota:"
+
+// quotaPeriod 配额周期
+type quotaPeriod struct {
+	name  string
+	limit int64
+}
+
+// bucketID 返回时间所在周期的标识和下一周期的开始时间（UTC）
+func (p quotaPeriod) bucketID(now time.Time) (string, time.Time) {
+	now = now.UTC()
+	if p.name == "monthly" {
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start.Format("200601"), start.AddDate(0, 1, 0)
+	}
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return start.Format("20060102"), start.AddDate(0, 0, 1)
+}
+
+// previousID 返回上一周期的标识，用于清理过期计数
+func (p quotaPeriod) previousID(now time.Time) string {
+	now = now.UTC()
+	if p.name == "monthly" {
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0).Format("200601")
+	}
+	return now.AddDate(0, 0, -1).Format("20060102")
+}
+
+// quotaStore 配额计数存储
+// 计数写入 AgilityMemDB，并由后台协程定期持久化，重启后继续累计
+type quotaStore struct {
+	mu      sync.Mutex
+	db      database.IDatabase
+	dirty   bool
+	started sync.Once
+}
+
+// quotas 全局配额存储，所有限流中间件实例共享
+var quotas = &quotaStore{}
+
+// consume 消耗一次配额
+// 参数：
+//   - db database.IDatabase: 数据库实例
+//   - scope string: 配额作用域（路由 + 客户端标识）
+//   - periods []quotaPeriod: 需要同时满足的配额周期
+//   - now time.Time: 当前时间
+// 返回值：
+//   - bool: 是否允许
+//   - time.Duration: 被拒绝时距离配额重置的时间
+func (q *quotaStore) consume(db database.IDatabase, scope string, periods []quotaPeriod, now time.Time) (bool, time.Duration) {
+	q.started.Do(func() {
+		q.db = db
+		go q.persistLoop()
+	})
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	keys := make([]string, len(periods))
+	counts := make([]int64, len(periods))
+	for i, p := range periods {
+		id, reset := p.bucketID(now)
+		keys[i] = QuotaPrefix + p.name + ":" + id + ":" + scope
+		if value, ok := db.Get(keys[i]); ok {
+			counts[i], _ = strconv.ParseInt(value, 10, 64)
+		} else {
+			db.Delete(QuotaPrefix + p.name + ":" + p.previousID(now) + ":" + scope)
+		}
+		if counts[i] >= p.limit {
+			return false, reset.Sub(now)
+		}
+	}
+
+	for i := range periods {
+		if err := db.Put(keys[i], strconv.FormatInt(counts[i]+1, 10)); err != nil {
+			log.Println("写入配额计数失败:", err)
+		}
+	}
+	q.dirty = true
+	return true, 0
+}
+
+// persistLoop 定期将有变更的配额计数持久化
+func (q *quotaStore) persistLoop() {
+	ticker := time.NewTicker(quotaPersistInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		q.mu.Lock()
+		dirty := q.dirty
+		q.dirty = false
+		q.mu.Unlock()
+		if !dirty {
+			continue
+		}
+		if err := q.db.Persist(); err != nil {
+			log.Println("持久化配额计数失败:", err)
+		}
+	}
+}
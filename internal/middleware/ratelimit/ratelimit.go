@@ -0,0 +1,209 @@
+package ratelimit
+
+import (
+	"bigHammer/internal/middleware"
+	"bigHammer/internal/middleware/auth"
+	"bigHammer/internal/shared"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	decisions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_ratelimit_decisions_total",
+		Help: "限流决策次数，decision 取值 allowed / limited / quota_exceeded",
+	}, []string{"route", "limiter", "decision"})
+)
+
+// Options 限流中间件配置
+type Options struct {
+	// Algorithm 限流算法：token_bucket 或 sliding_window
+	Algorithm string
+	// Key 限流维度：ip、api_key、route 或 header:<名称>
+	Key string
+	// Limit 每个周期允许的请求数
+	Limit int
+	// Period 周期长度
+	Period time.Duration
+	// Burst 令牌桶容量，仅 token_bucket 使用
+	Burst int
+	// Daily 每日配额，0表示不限制
+	Daily int64
+	// Monthly 每月配额，0表示不限制
+	Monthly int64
+}
+
+// clientKey 根据配置的维度提取限流键，无法提取时退回客户端IP
+// api_key 维度需要在限流之前执行认证中间件，未认证的请求按客户端IP计数
+func (o Options) clientKey(req *http.Request) string {
+	switch {
+	case o.Key == "route":
+		return "*"
+	case o.Key == "api_key":
+		// 只使用认证中间件校验过的身份，未经校验的 X-API-Key 可以随意伪造以获得新的计数桶
+		if id := auth.IdentityFromContext(req.Context()); id != nil {
+			return "id:" + id.Method + ":" + id.KeyID + ":" + id.Subject
+		}
+	case strings.HasPrefix(o.Key, "header:"):
+		if value := req.Header.Get(strings.TrimPrefix(o.Key, "header:")); value != "" {
+			return "hdr:" + value
+		}
+	}
+	return "ip:" + middleware.ClientIP(req)
+}
+
+// New 创建限流中间件
+// 功能：
+// 1. 按配置的维度对请求计数，超出速率时返回429和 Retry-After
+// 2. 配置了每日/每月配额时，在 AgilityMemDB 中累计计数，超出时返回429
+// 3. 每次决策都记录到 Prometheus 指标
+// 参数：
+//   - opts Options: 限流配置
+//
+// 返回值：
+//   - middleware.Middleware: 限流中间件
+//   - error: 配置无效时返回的错误信息
+func New(opts Options) (middleware.Middleware, error) {
+	if opts.Period <= 0 {
+		return nil, errors.New("rate limit period must be positive")
+	}
+	switch {
+	case opts.Key == "":
+		opts.Key = "ip"
+	case opts.Key == "ip", opts.Key == "api_key", opts.Key == "route":
+	case strings.HasPrefix(opts.Key, "header:") && strings.TrimPrefix(opts.Key, "header:") != "":
+	default:
+		return nil, fmt.Errorf("unknown rate limit key %q", opts.Key)
+	}
+
+	var limiter Limiter
+	if opts.Limit > 0 {
+		switch opts.Algorithm {
+		case "token_bucket", "":
+			opts.Algorithm = "token_bucket"
+			limiter = NewTokenBucket(opts.Limit, opts.Period, opts.Burst)
+		case "sliding_window":
+			limiter = NewSlidingWindow(opts.Limit, opts.Period)
+		default:
+			return nil, fmt.Errorf("unknown rate limit algorithm %s", opts.Algorithm)
+		}
+	}
+
+	var periods []quotaPeriod
+	if opts.Daily > 0 {
+		periods = append(periods, quotaPeriod{name: "daily", limit: opts.Daily})
+	}
+	if opts.Monthly > 0 {
+		periods = append(periods, quotaPeriod{name: "monthly", limit: opts.Monthly})
+	}
+	if limiter == nil && len(periods) == 0 {
+		return nil, errors.New("rate limit requires limit or quota")
+	}
+
+	limiterLabel := opts.Algorithm
+	if limiter == nil {
+		limiterLabel = "quota"
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			now := time.Now()
+			route := middleware.RouteFromContext(req.Context())
+			key := opts.clientKey(req)
+
+			if limiter != nil {
+				allowed, remaining, retryAfter := limiter.Allow(key, now)
+				w.Header().Set("X-RateLimit-Limit", strconv.Itoa(opts.Limit))
+				w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+				if !allowed {
+					decisions.WithLabelValues(route, opts.Algorithm, "limited").Inc()
+					reject(w, retryAfter, "Rate limit exceeded")
+					return
+				}
+			}
+
+			if len(periods) > 0 {
+				db, err := shared.ResolveDatabase()
+				if err != nil {
+					log.Println("限流配额不可用:", err)
+				} else if allowed, retryAfter := quotas.consume(db, route+":"+key, periods, now); !allowed {
+					decisions.WithLabelValues(route, "quota", "quota_exceeded").Inc()
+					reject(w, retryAfter, "Quota exceeded")
+					return
+				}
+			}
+
+			decisions.WithLabelValues(route, limiterLabel, "allowed").Inc()
+			next.ServeHTTP(w, req)
+		})
+	}, nil
+}
+
+// reject 返回429响应
+func reject(w http.ResponseWriter, retryAfter time.Duration, message string) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	middleware.WriteError(w, http.StatusTooManyRequests, message, map[string]int{"retry_after": seconds})
+}
+
+// newFromOptions 根据 router.json 中的选项创建限流中间件
+// 选项示例：
+//
+//	"rate_limit": {
+//	  "algorithm": "sliding_window",
+//	  "key": "header:X-Device-Id",
+//	  "limit": 100, "period": 60, "burst": 20,
+//	  "quota": {"daily": 10000, "monthly": 200000}
+//	}
+func newFromOptions(options map[string]interface{}) (middleware.Middleware, error) {
+	var opts Options
+	var err error
+	if opts.Algorithm, err = middleware.OptString(options, "algorithm", "token_bucket"); err != nil {
+		return nil, err
+	}
+	if opts.Key, err = middleware.OptString(options, "key", "ip"); err != nil {
+		return nil, err
+	}
+	if opts.Limit, err = middleware.OptInt(options, "limit", 0); err != nil {
+		return nil, err
+	}
+	period, err := middleware.OptInt(options, "period", 1)
+	if err != nil {
+		return nil, err
+	}
+	opts.Period = time.Duration(period) * time.Second
+	if opts.Burst, err = middleware.OptInt(options, "burst", 0); err != nil {
+		return nil, err
+	}
+
+	quota, err := middleware.OptMap(options, "quota")
+	if err != nil {
+		return nil, err
+	}
+	daily, err := middleware.OptInt(quota, "daily", 0)
+	if err != nil {
+		return nil, err
+	}
+	monthly, err := middleware.OptInt(quota, "monthly", 0)
+	if err != nil {
+		return nil, err
+	}
+	opts.Daily, opts.Monthly = int64(daily), int64(monthly)
+	return New(opts)
+}
+
+func init() {
+	middleware.Register("rate_limit", newFromOptions)
+}
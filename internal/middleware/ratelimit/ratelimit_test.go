@@ -0,0 +1,135 @@
+package ratelimit
+
+import (
+	"bigHammer/internal/middleware/auth"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// base 与10秒窗口边界对齐的起始时间
+var base = time.Unix(1_000_000, 0)
+
+func TestTokenBucket(t *testing.T) {
+	tb := NewTokenBucket(2, time.Second, 4)
+	for i := 0; i < 4; i++ {
+		if ok, _, _ := tb.Allow("k", base); !ok {
+			t.Fatalf("burst request %d rejected", i)
+		}
+	}
+	ok, _, wait := tb.Allow("k", base)
+	if ok {
+		t.Fatal("request over burst allowed")
+	}
+	if wait != 500*time.Millisecond {
+		t.Fatalf("retry after = %v, want 500ms", wait)
+	}
+	if ok, _, _ := tb.Allow("other", base); !ok {
+		t.Fatal("keys share a bucket")
+	}
+	if ok, _, _ := tb.Allow("k", base.Add(500*time.Millisecond)); !ok {
+		t.Fatal("refilled token rejected")
+	}
+	if ok, _, _ := tb.Allow("k", base.Add(500*time.Millisecond)); ok {
+		t.Fatal("request allowed before the next refill")
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	tests := []struct {
+		offset  time.Duration
+		allowed bool
+	}{
+		{0, true}, {0, true}, {time.Second, true}, {2 * time.Second, true},
+		{3 * time.Second, false},
+		// 下一个窗口开始时上一窗口权重为1，仍然超限
+		{10 * time.Second, false},
+		// 窗口过半，上一窗口按一半计入：4*0.5 = 2
+		{15 * time.Second, true}, {15 * time.Second, true}, {15 * time.Second, false},
+		// 间隔超过一个窗口，上一窗口不再计入
+		{40 * time.Second, true},
+	}
+	sw := NewSlidingWindow(4, 10*time.Second)
+	for i, tt := range tests {
+		if ok, _, _ := sw.Allow("k", base.Add(tt.offset)); ok != tt.allowed {
+			t.Fatalf("request %d at +%v allowed = %v, want %v", i, tt.offset, ok, tt.allowed)
+		}
+	}
+}
+
+func TestNewOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{name: "defaults", opts: Options{Limit: 1, Period: time.Second}},
+		{name: "header key", opts: Options{Key: "header:X-Device-Id", Limit: 1, Period: time.Second}},
+		{name: "api key", opts: Options{Key: "api_key", Limit: 1, Period: time.Second}},
+		{name: "unknown key", opts: Options{Key: "apikey", Limit: 1, Period: time.Second}, wantErr: true},
+		{name: "empty header name", opts: Options{Key: "header:", Limit: 1, Period: time.Second}, wantErr: true},
+		{name: "unknown algorithm", opts: Options{Algorithm: "leaky", Limit: 1, Period: time.Second}, wantErr: true},
+		{name: "no period", opts: Options{Limit: 1}, wantErr: true},
+		{name: "no limit or quota", opts: Options{Period: time.Second}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.opts); (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClientKey(t *testing.T) {
+	identified := func(req *http.Request) *http.Request {
+		return req.WithContext(auth.WithIdentity(req.Context(), &auth.Identity{Method: "api_key", KeyID: "k1", Subject: "app"}))
+	}
+	tests := []struct {
+		name string
+		key  string
+		req  func(*http.Request) *http.Request
+		want string
+	}{
+		{name: "ip", key: "ip", want: "ip:192.0.2.1"},
+		{name: "route", key: "route", want: "*"},
+		{name: "authenticated api key", key: "api_key", req: identified, want: "id:api_key:k1:app"},
+		{name: "unverified api key header", key: "api_key", want: "ip:192.0.2.1"},
+		{name: "header", key: "header:X-Device-Id", want: "hdr:device-7"},
+		{name: "missing header", key: "header:X-Tenant", want: "ip:192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			req.Header.Set("X-API-Key", "forged")
+			req.Header.Set("X-Device-Id", "device-7")
+			if tt.req != nil {
+				req = tt.req(req)
+			}
+			if got := (Options{Key: tt.key}).clientKey(req); got != tt.want {
+				t.Fatalf("clientKey = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	mw, err := New(Options{Limit: 1, Period: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	for i, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Fatalf("request %d status = %d, want %d", i, rec.Code, want)
+		}
+		if want == http.StatusTooManyRequests && rec.Header().Get("Retry-After") == "" {
+			t.Fatal("missing Retry-After")
+		}
+	}
+}
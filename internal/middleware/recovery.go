@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"runtime/debug"
+)
+
+// Recovery 异常恢复中间件
+// 捕获后续处理器中的 panic，记录堆栈并返回500，防止单个请求导致进程退出
+func Recovery() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			rec := NewStatusRecorder(w)
+			defer func() {
+				if err := recover(); err != nil {
+					if err == http.ErrAbortHandler {
+						panic(err)
+					}
+					log.Printf("处理请求 %s %s 时发生panic: %v\n%s", req.Method, req.URL.Path, err, debug.Stack())
+					if !rec.WroteHeader() {
+						http.Error(rec, "内部服务器错误", http.StatusInternalServerError)
+					}
+				}
+			}()
+			next.ServeHTTP(rec, req)
+		})
+	}
+}
+
+func init() {
+	Register("recovery", func(options map[string]interface{}) (Middleware, error) {
+		return Recovery(), nil
+	})
+}
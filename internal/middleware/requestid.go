@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader 请求ID使用的HTTP头
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength 接受客户端传入请求ID的最大长度
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// RequestIDFromContext 从上下文中获取请求ID，未设置时返回空字符串
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// WithRequestID 返回携带请求ID的上下文
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// validRequestID 检查客户端传入的请求ID是否可以安全复用
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// AssignRequestID 为请求分配请求ID
+// 功能：
+// 1. 上下文中已有请求ID时直接复用
+// 2. 否则复用客户端传入的请求ID，缺失或非法时生成UUIDv4
+// 3. 将请求ID写入请求上下文、请求头和响应头
+// 参数：
+//   - w http.ResponseWriter: 响应写入器
+//   - req *http.Request: HTTP请求
+//   - header string: 读取和回写请求ID的HTTP头名称
+// 返回值：
+//   - *http.Request: 携带请求ID的请求
+func AssignRequestID(w http.ResponseWriter, req *http.Request, header string) *http.Request {
+	id := RequestIDFromContext(req.Context())
+	if id == "" {
+		id = req.Header.Get(header)
+		if !validRequestID(id) {
+			id = uuid.New().String()
+		}
+		req = req.WithContext(WithRequestID(req.Context(), id))
+	}
+	req.Header.Set(header, id)
+	w.Header().Set(header, id)
+	return req
+}
+
+// RequestID 请求ID中间件
+// 功能：
+// 1. 复用客户端传入的 X-Request-ID，缺失或非法时生成UUIDv4
+// 2. 将请求ID写入请求上下文和响应头
+// 参数：
+//   - header string: 读取和回写请求ID的HTTP头名称
+// 返回值：
+//   - Middleware: 请求ID中间件
+func RequestID(header string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			next.ServeHTTP(w, AssignRequestID(w, req, header))
+		})
+	}
+}
+
+func init() {
+	Register("request_id", func(options map[string]interface{}) (Middleware, error) {
+		header, err := OptString(options, "header", RequestIDHeader)
+		if err != nil {
+			return nil, err
+		}
+		return RequestID(http.CanonicalHeaderKey(header)), nil
+	})
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+type routeKey struct{}
+
+// WithRoute 返回携带匹配路由路径的上下文
+func WithRoute(ctx context.Context, path string) context.Context {
+	return context.WithValue(ctx, routeKey{}, path)
+}
+
+// RouteFromContext 从上下文中获取匹配的路由路径，未匹配时返回空字符串
+func RouteFromContext(ctx context.Context) string {
+	path, _ := ctx.Value(routeKey{}).(string)
+	return path
+}
+
+// Route 将匹配的路由路径写入请求上下文，供后续中间件按路由区分统计和限流
+func Route(path string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			next.ServeHTTP(w, req.WithContext(WithRoute(req.Context(), path)))
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// StatusRecorder 记录响应状态码和字节数的 ResponseWriter 包装器
+// 保留底层 Flusher/Hijacker 能力，供流式响应和协议升级使用
+type StatusRecorder struct {
+	http.ResponseWriter
+	// Status 响应状态码，未显式写入时为200
+	Status int
+	// Bytes 已写入的响应体字节数
+	Bytes int64
+	// wroteHeader 是否已写入响应头
+	wroteHeader bool
+}
+
+// NewStatusRecorder 创建状态记录器
+// 如果 w 已经是 StatusRecorder，则直接返回，避免重复包装
+func NewStatusRecorder(w http.ResponseWriter) *StatusRecorder {
+	if rec, ok := w.(*StatusRecorder); ok {
+		return rec
+	}
+	return &StatusRecorder{ResponseWriter: w, Status: http.StatusOK}
+}
+
+// WriteHeader 记录并写入状态码
+func (r *StatusRecorder) WriteHeader(status int) {
+	if r.wroteHeader {
+		return
+	}
+	r.wroteHeader = true
+	r.Status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Write 写入响应体并累计字节数
+func (r *StatusRecorder) Write(b []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.Bytes += int64(n)
+	return n, err
+}
+
+// WroteHeader 返回是否已写入响应头
+func (r *StatusRecorder) WroteHeader() bool {
+	return r.wroteHeader
+}
+
+// Flush 实现 http.Flusher 接口
+func (r *StatusRecorder) Flush() {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack 实现 http.Hijacker 接口
+func (r *StatusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	r.wroteHeader = true
+	r.Status = http.StatusSwitchingProtocols
+	return h.Hijack()
+}
+
+// Unwrap 返回底层 ResponseWriter，供 http.ResponseController 使用
+func (r *StatusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
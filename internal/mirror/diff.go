@@ -0,0 +1,88 @@
+package mirror
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// maxDiffs 单次比较最多记录的差异数
+const maxDiffs = 10
+
+// Diff 比较主请求和影子请求的响应
+// 两者都是JSON时按字段比较，返回以JSON路径描述的差异；否则按字节比较
+// 参数：
+//   - primary []byte: 主请求响应
+//   - shadow []byte: 影子请求响应
+// 返回值：
+//   - []string: 差异描述，相同时为空
+func Diff(primary, shadow []byte) []string {
+	var a, b interface{}
+	if json.Unmarshal(primary, &a) != nil || json.Unmarshal(shadow, &b) != nil {
+		if bytes.Equal(primary, shadow) {
+			return nil
+		}
+		return []string{fmt.Sprintf("body: %d bytes != %d bytes", len(primary), len(shadow))}
+	}
+	var diffs []string
+	diffValue("$", a, b, &diffs)
+	return diffs
+}
+
+// diffValue 递归比较两个JSON值
+func diffValue(path string, a, b interface{}, diffs *[]string) {
+	if len(*diffs) >= maxDiffs {
+		return
+	}
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(av)+len(bv))
+		for k := range av {
+			keys = append(keys, k)
+		}
+		for k := range bv {
+			if _, ok := av[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			x, inA := av[k]
+			y, inB := bv[k]
+			switch {
+			case !inA:
+				*diffs = append(*diffs, path+"."+k+": only in shadow")
+			case !inB:
+				*diffs = append(*diffs, path+"."+k+": only in primary")
+			default:
+				diffValue(path+"."+k, x, y, diffs)
+			}
+			if len(*diffs) >= maxDiffs {
+				return
+			}
+		}
+		return
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+		if len(av) != len(bv) {
+			*diffs = append(*diffs, fmt.Sprintf("%s: length %d != %d", path, len(av), len(bv)))
+			return
+		}
+		for i := range av {
+			diffValue(fmt.Sprintf("%s[%d]", path, i), av[i], bv[i], diffs)
+		}
+		return
+	}
+	if !reflect.DeepEqual(a, b) {
+		*diffs = append(*diffs, fmt.Sprintf("%s: %v != %v", path, a, b))
+	}
+}
@@ -0,0 +1,207 @@
+package mirror
+
+import (
+	"bigHammer/internal/config"
+	ipc "bigHammer/internal/ipc/socket"
+	"bigHammer/internal/upstream"
+	"bigHammer/pkg/utils"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// 镜像请求结果
+const (
+	ResultSent     = "sent"
+	ResultDropped  = "dropped"
+	ResultError    = "error"
+	ResultMatch    = "match"
+	ResultMismatch = "mismatch"
+)
+
+var mirrorRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "gateway_mirror_requests_total",
+	Help: "流量镜像请求数，result 取值 sent / dropped / error / match / mismatch",
+}, []string{"route", "result"})
+
+// Options 流量镜像配置，对应 router.json 中路由的 mirror 字段
+type Options struct {
+	// Command 影子请求使用的业务命令，为空时使用路由的 command
+	Command string `json:"command,omitempty"`
+	// Backend 接收影子请求的后端，为空时使用 bussiness_socket_path
+	Backend string `json:"backend,omitempty"`
+	// Percentage 镜像的请求百分比（0-100），默认100
+	Percentage *float64 `json:"percentage,omitempty"`
+	// Compare 是否比较主请求和影子请求的响应，并记录差异日志
+	Compare bool `json:"compare,omitempty"`
+	// MaxInflight 同时进行中的影子请求上限，超出时丢弃镜像，默认32
+	MaxInflight int `json:"max_inflight,omitempty"`
+	// Timeout 比较响应时等待主请求结果的最大秒数，默认30
+	Timeout int `json:"timeout,omitempty"`
+}
+
+// Validate 校验镜像配置
+func (o Options) Validate() error {
+	if o.Percentage != nil && (*o.Percentage < 0 || *o.Percentage > 100) {
+		return fmt.Errorf("mirror: percentage must be between 0 and 100")
+	}
+	if o.Command == "" && o.Backend == "" {
+		return fmt.Errorf("mirror: command or backend is required")
+	}
+	return nil
+}
+
+func (o Options) percentage() float64 {
+	if o.Percentage == nil {
+		return 100
+	}
+	return *o.Percentage
+}
+
+func (o Options) maxInflight() int {
+	if o.MaxInflight <= 0 {
+		return 32
+	}
+	return o.MaxInflight
+}
+
+func (o Options) timeout() time.Duration {
+	if o.Timeout <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(o.Timeout) * time.Second
+}
+
+// Mirror 路由的流量镜像器
+type Mirror struct {
+	route   string
+	command string
+	opts    Options
+	// pool 接收影子请求的后端，使用默认业务Socket时为nil
+	pool *upstream.Pool
+	// slots 限制同时进行中的影子请求数
+	slots chan struct{}
+}
+
+// New 创建流量镜像器
+// 参数：
+//   - route string: 路由路径，用于日志和指标标签
+//   - command string: 路由的业务命令，配置未指定 command 时使用
+//   - opts Options: 镜像配置
+//   - pool *upstream.Pool: 接收影子请求的后端，使用默认业务Socket时为nil
+// 返回值：
+//   - *Mirror: 流量镜像器
+func New(route, command string, opts Options, pool *upstream.Pool) *Mirror {
+	if opts.Command != "" {
+		command = opts.Command
+	}
+	return &Mirror{
+		route:   route,
+		command: command,
+		opts:    opts,
+		pool:    pool,
+		slots:   make(chan struct{}, opts.maxInflight()),
+	}
+}
+
+// Shadow 一次进行中的影子请求
+type Shadow struct {
+	// requestID 主请求的请求ID，随影子请求发送
+	requestID string
+	// primary 主请求的结果，仅在比较响应时使用
+	primary chan result
+}
+
+type result struct {
+	output []byte
+	err    error
+}
+
+// Start 按比例抽样并在后台发送影子请求，不阻塞主请求
+// 参数：
+//   - requestID string: 主请求的请求ID（X-Request-ID）
+//   - params interface{}: 发送给业务进程的请求数据（与主请求相同）
+// 返回值：
+//   - *Shadow: 影子请求，未抽中或进行中的影子请求过多时返回nil
+func (m *Mirror) Start(requestID string, params interface{}) *Shadow {
+	if rand.Float64()*100 >= m.opts.percentage() {
+		return nil
+	}
+	select {
+	case m.slots <- struct{}{}:
+	default:
+		mirrorRequests.WithLabelValues(m.route, ResultDropped).Inc()
+		return nil
+	}
+
+	s := &Shadow{requestID: requestID}
+	if m.opts.Compare {
+		s.primary = make(chan result, 1)
+	}
+	go m.send(s, params)
+	return s
+}
+
+// Finish 提交主请求的结果，用于比较响应
+// s 为nil时不做任何处理
+func (s *Shadow) Finish(output []byte, err error) {
+	if s == nil || s.primary == nil {
+		return
+	}
+	s.primary <- result{output: output, err: err}
+}
+
+// send 发送影子请求并丢弃响应，开启比较时记录与主请求响应的差异
+func (m *Mirror) send(s *Shadow, params interface{}) {
+	defer func() { <-m.slots }()
+
+	output, err := m.transmit(s.requestID, params)
+	if err != nil {
+		mirrorRequests.WithLabelValues(m.route, ResultError).Inc()
+		log.Printf("流量镜像请求失败 route=%s request_id=%s: %v", m.route, s.requestID, err)
+		return
+	}
+	mirrorRequests.WithLabelValues(m.route, ResultSent).Inc()
+	if s.primary == nil {
+		return
+	}
+
+	var primary result
+	select {
+	case primary = <-s.primary:
+	case <-time.After(m.opts.timeout()):
+		return
+	}
+	if primary.err != nil {
+		return
+	}
+	if diffs := Diff(primary.output, output); len(diffs) > 0 {
+		mirrorRequests.WithLabelValues(m.route, ResultMismatch).Inc()
+		log.Printf("流量镜像响应不一致 route=%s command=%s request_id=%s: %v", m.route, m.command, s.requestID, diffs)
+		return
+	}
+	mirrorRequests.WithLabelValues(m.route, ResultMatch).Inc()
+}
+
+// transmit 选择影子端点并通过IPC发送请求
+func (m *Mirror) transmit(requestID string, params interface{}) ([]byte, error) {
+	if m.pool == nil {
+		socketPath, err := utils.ResolvePath(config.GlobalConfig.BussinessSocketPath)
+		if err != nil {
+			return nil, err
+		}
+		output, _, err := ipc.TransmitIPC(false, requestID, m.command, params, socketPath)
+		return output, err
+	}
+	e, err := m.pool.Pick("")
+	if err != nil {
+		return nil, err
+	}
+	output, _, err := ipc.TransmitIPC(false, requestID, m.command, params, e.Address)
+	m.pool.Done(e, err)
+	return output, err
+}
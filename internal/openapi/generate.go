@@ -0,0 +1,408 @@
+package openapi
+
+import (
+	"bigHammer/internal/config"
+	"bigHammer/internal/jobs"
+	"bigHammer/internal/middleware"
+	"bigHammer/internal/router"
+	"bigHammer/internal/schema"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	pathParam     = regexp.MustCompile(`\{([^}]+)\}`)
+	operationChar = regexp.MustCompile(`[^A-Za-z0-9]+`)
+)
+
+// Generate 根据路由配置生成 OpenAPI 文档
+// 功能：
+// 1. 每条路由按 methods 生成操作，未配置时有请求体的路由为POST，其他为GET
+// 2. 路径模板生成路径参数，路由的 schema 配置生成参数、请求体及其校验规则
+// 3. 根据路由使用的 auth 中间件生成认证要求
+// 4. 存在异步路由时加入任务状态查询接口
+// WebSocket路由不在HTTP端口上提供服务，不写入文档
+// 参数：
+//   - r *router.Router: 路由配置
+//   - cfg config.OpenAPIConfig: 文档信息
+// 返回值：
+//   - *Document: OpenAPI 文档
+//   - error: schema 文件无法读取或认证选项无效时返回的错误信息
+func Generate(r *router.Router, cfg config.OpenAPIConfig) (*Document, error) {
+	g := &generator{
+		r:      r,
+		bundle: schema.NewBundle(),
+		doc: &Document{
+			OpenAPI: Version,
+			Info:    Info{Title: cfg.Title, Version: cfg.Version, Description: cfg.Description},
+			Paths:   make(map[string]map[string]*Operation),
+		},
+		schemes: make(map[string]SecurityScheme),
+	}
+	if g.doc.Info.Title == "" {
+		g.doc.Info.Title = "bigHammer API"
+	}
+	if g.doc.Info.Version == "" {
+		g.doc.Info.Version = "1.0.0"
+	}
+	for _, url := range cfg.Servers {
+		g.doc.Servers = append(g.doc.Servers, Server{URL: url})
+	}
+
+	hasJobs := false
+	for _, route := range r.AllRoutes() {
+		if route.WebSocket != nil {
+			continue
+		}
+		if err := g.route(route); err != nil {
+			return nil, fmt.Errorf("openapi: route %s: %v", route.Path, err)
+		}
+		hasJobs = hasJobs || route.Async != nil
+	}
+	if hasJobs {
+		g.jobStatus()
+	}
+
+	if len(g.bundle.Schemas) > 0 {
+		g.doc.Components.Schemas = g.bundle.Schemas
+	}
+	if len(g.schemes) > 0 {
+		g.doc.Components.SecuritySchemes = g.schemes
+	}
+	return g.doc, nil
+}
+
+type generator struct {
+	r       *router.Router
+	bundle  *schema.Bundle
+	doc     *Document
+	schemes map[string]SecurityScheme
+}
+
+// route 生成一条路由的所有操作
+func (g *generator) route(route router.Route) error {
+	var bodyRef, queryRef, pathRef string
+	var err error
+	if route.Schema != nil {
+		if bodyRef, err = g.add(route.Schema.Body); err != nil {
+			return err
+		}
+		if queryRef, err = g.add(route.Schema.Query); err != nil {
+			return err
+		}
+		if pathRef, err = g.add(route.Schema.Path); err != nil {
+			return err
+		}
+	}
+	security, authRequired, err := g.security(route)
+	if err != nil {
+		return err
+	}
+	_, rateLimited := g.r.MiddlewareConfig(route, "rate_limit")
+
+	methods := route.Methods
+	if len(methods) == 0 {
+		methods = []string{"GET"}
+		if bodyRef != "" || (route.Files != nil && route.Files.Mode != "download") {
+			methods = []string{"POST"}
+		}
+	}
+
+	// 版本化路由按带版本前缀的路径记录
+	path := g.r.VersionedPath(route)
+	item := g.doc.Paths[path]
+	if item == nil {
+		item = make(map[string]*Operation)
+		g.doc.Paths[path] = item
+	}
+	for _, method := range methods {
+		method = strings.ToLower(method)
+		op := &Operation{
+			OperationID: operationID(method, path),
+			Summary:     route.Summary,
+			Description: route.Description,
+			Tags:        tags(route),
+			Security:    security,
+			Responses:   g.responses(route),
+			Deprecated:  route.Deprecation != nil,
+		}
+		op.Parameters = append(g.pathParameters(route.Path, pathRef), g.queryParameters(queryRef)...)
+		if method != "get" && method != "head" && method != "delete" {
+			op.RequestBody = requestBody(route, bodyRef)
+		}
+		if bodyRef != "" || queryRef != "" || pathRef != "" {
+			op.Responses["400"] = g.errorResponse("Request validation failed")
+		}
+		if authRequired {
+			op.Responses["401"] = g.errorResponse("Authentication required")
+		}
+		if len(route.Methods) > 0 {
+			op.Responses["405"] = g.errorResponse("Method not allowed")
+		}
+		if rateLimited {
+			op.Responses["429"] = g.errorResponse("Too many requests")
+		}
+		item[method] = op
+	}
+	return nil
+}
+
+// add 将 schema 文件加入组件，文件为空时返回空字符串
+func (g *generator) add(file string) (string, error) {
+	if file == "" {
+		return "", nil
+	}
+	return g.bundle.Add(file)
+}
+
+// pathParameters 根据路径模板生成路径参数，参数 schema 取自 path schema 的同名属性
+func (g *generator) pathParameters(path, ref string) []Parameter {
+	var params []Parameter
+	for _, m := range pathParam.FindAllStringSubmatch(path, -1) {
+		var s interface{} = map[string]interface{}{"type": "string"}
+		if prop := g.property(ref, m[1]); prop != "" {
+			s = map[string]interface{}{"$ref": prop}
+		}
+		params = append(params, Parameter{Name: m[1], In: "path", Required: true, Schema: s})
+	}
+	return params
+}
+
+// queryParameters 根据 query schema 的属性生成查询参数
+func (g *generator) queryParameters(ref string) []Parameter {
+	if ref == "" {
+		return nil
+	}
+	root, _ := g.bundle.Lookup(ref)
+	obj, _ := root.(map[string]interface{})
+	props, _ := obj["properties"].(map[string]interface{})
+	required := make(map[string]bool)
+	if list, ok := obj["required"].([]interface{}); ok {
+		for _, name := range list {
+			if str, ok := name.(string); ok {
+				required[str] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	params := make([]Parameter, 0, len(names))
+	for _, name := range names {
+		param := Parameter{
+			Name:     name,
+			In:       "query",
+			Required: required[name],
+			Schema:   map[string]interface{}{"$ref": ref + "/properties/" + escape(name)},
+		}
+		if prop, ok := props[name].(map[string]interface{}); ok {
+			param.Description, _ = prop["description"].(string)
+		}
+		params = append(params, param)
+	}
+	return params
+}
+
+// property 返回 schema 中属性的 $ref，属性不存在时返回空字符串
+func (g *generator) property(ref, name string) string {
+	if ref == "" {
+		return ""
+	}
+	prop := ref + "/properties/" + escape(name)
+	if _, ok := g.bundle.Lookup(prop); !ok {
+		return ""
+	}
+	return prop
+}
+
+// requestBody 生成请求体描述
+func requestBody(route router.Route, bodyRef string) *RequestBody {
+	switch {
+	case route.Files != nil && route.Files.Mode != "download":
+		return &RequestBody{Required: true, Content: map[string]MediaType{
+			"multipart/form-data": {Schema: map[string]interface{}{
+				"type": "object",
+				"additionalProperties": map[string]interface{}{
+					"type": "string", "format": "binary",
+				},
+			}},
+		}}
+	case bodyRef != "":
+		return &RequestBody{Required: true, Content: map[string]MediaType{
+			"application/json": {Schema: map[string]interface{}{"$ref": bodyRef}},
+		}}
+	default:
+		return nil
+	}
+}
+
+// responses 根据路由类型生成成功响应
+func (g *generator) responses(route router.Route) map[string]Response {
+	switch {
+	case route.SSE != nil:
+		return map[string]Response{"200": {
+			Description: "Server-Sent Events stream",
+			Content:     map[string]MediaType{"text/event-stream": {Schema: map[string]interface{}{"type": "string"}}},
+		}}
+	case route.Async != nil:
+		return map[string]Response{"202": {
+			Description: "Job accepted, poll the status URL for the result",
+			Headers: map[string]Header{
+				"Location": {Description: "Job status URL", Schema: map[string]interface{}{"type": "string"}},
+			},
+			Content: map[string]MediaType{"application/json": {Schema: envelope(map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"job_id":     map[string]interface{}{"type": "string"},
+					"status":     map[string]interface{}{"type": "string"},
+					"status_url": map[string]interface{}{"type": "string"},
+				},
+			})}},
+		}}
+	case route.Files != nil && route.Files.Mode == "download":
+		return map[string]Response{"200": {
+			Description: "File content",
+			Content:     map[string]MediaType{"application/octet-stream": {Schema: map[string]interface{}{"type": "string", "format": "binary"}}},
+		}}
+	default:
+		return map[string]Response{"200": {
+			Description: "Successful response",
+			Content:     map[string]MediaType{"application/json": {}},
+		}}
+	}
+}
+
+// jobStatus 加入异步任务状态查询接口
+func (g *generator) jobStatus() {
+	path := jobs.PathPrefix + "{id}"
+	g.doc.Paths[path] = map[string]*Operation{"get": {
+		OperationID: "getJobStatus",
+		Summary:     "Get asynchronous job status",
+		Tags:        []string{"jobs"},
+		Parameters:  []Parameter{{Name: "id", In: "path", Required: true, Schema: map[string]interface{}{"type": "string"}}},
+		Responses: map[string]Response{
+			"200": {Description: "Job status", Content: map[string]MediaType{"application/json": {}}},
+			"404": g.errorResponse("Job not found"),
+		},
+	}}
+}
+
+// security 根据路由使用的 auth 中间件生成认证要求
+// 返回值：
+//   - []map[string][]string: 任一认证方式通过即可；可选认证时包含空要求
+//   - bool: 是否必须认证
+//   - error: 认证选项无效时返回的错误信息
+func (g *generator) security(route router.Route) ([]map[string][]string, bool, error) {
+	options, ok := g.r.MiddlewareConfig(route, "auth")
+	if !ok {
+		return nil, false, nil
+	}
+	methods, err := middleware.OptStrings(options, "methods", []string{"api_key"})
+	if err != nil {
+		return nil, false, err
+	}
+	optional, err := middleware.OptBool(options, "optional", false)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var requirements []map[string][]string
+	for _, method := range methods {
+		methodOptions, err := middleware.OptMap(options, method)
+		if err != nil {
+			return nil, false, err
+		}
+		var scheme SecurityScheme
+		switch method {
+		case "api_key":
+			header, err := middleware.OptString(methodOptions, "header", "X-API-Key")
+			if err != nil {
+				return nil, false, err
+			}
+			scheme = SecurityScheme{Type: "apiKey", In: "header", Name: header}
+		case "jwt":
+			scheme = SecurityScheme{Type: "http", Scheme: "bearer", BearerFormat: "JWT"}
+		case "hmac":
+			header, err := middleware.OptString(methodOptions, "signature_header", "X-Signature")
+			if err != nil {
+				return nil, false, err
+			}
+			scheme = SecurityScheme{Type: "apiKey", In: "header", Name: header,
+				Description: "HMAC-SHA256 request signature with key id, timestamp and nonce headers"}
+		default:
+			return nil, false, fmt.Errorf("unknown auth method %s", method)
+		}
+		requirements = append(requirements, map[string][]string{g.scheme(method, scheme): {}})
+	}
+	if optional {
+		requirements = append(requirements, map[string][]string{})
+	}
+	return requirements, !optional, nil
+}
+
+// scheme 登记认证方式并返回名称，同名但配置不同的认证方式追加序号
+func (g *generator) scheme(base string, scheme SecurityScheme) string {
+	name := base
+	for i := 2; ; i++ {
+		existing, ok := g.schemes[name]
+		if !ok {
+			g.schemes[name] = scheme
+			return name
+		}
+		if reflect.DeepEqual(existing, scheme) {
+			return name
+		}
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+}
+
+// envelope 业务响应外层结构，与插件 Response 一致
+func envelope(data interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"status":  map[string]interface{}{"type": "integer"},
+			"message": map[string]interface{}{"type": "string"},
+			"data":    data,
+		},
+	}
+}
+
+// errorSchema 网关错误响应 schema 的组件名称
+const errorSchema = "GatewayError"
+
+// errorResponse 网关错误响应（middleware.WriteError 的格式），schema 作为组件只写一次
+func (g *generator) errorResponse(description string) Response {
+	g.bundle.Schemas[errorSchema] = envelope(map[string]interface{}{})
+	return Response{
+		Description: description,
+		Content:     map[string]MediaType{"application/json": {Schema: map[string]interface{}{"$ref": schema.ComponentsPrefix + errorSchema}}},
+	}
+}
+
+// operationID 根据方法和路径生成操作ID，例如 get_users_id
+func operationID(method, path string) string {
+	id := strings.Trim(operationChar.ReplaceAllString(path, "_"), "_")
+	if id == "" {
+		id = "root"
+	}
+	return method + "_" + id
+}
+
+// escape 按 JSON Pointer 规则转义属性名
+func escape(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
+
+// tags 返回操作的分组标签，路由未配置 tags 时使用所属路由分组的名称
+func tags(route router.Route) []string {
+	if len(route.Tags) == 0 && route.Group != "" {
+		return []string{route.Group}
+	}
+	return route.Tags
+}
@@ -0,0 +1,23 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Handler 返回提供 OpenAPI 文档的处理器，文档在创建时序列化一次
+func Handler(doc *Document) (http.Handler, error) {
+	body, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write(body)
+	}), nil
+}
@@ -0,0 +1,16 @@
+package plugin
+
+import "bigHammer/internal/middleware"
+
+// RegisterMiddleware 注册插件提供的自定义HTTP中间件
+// 功能：
+// 1. 将中间件工厂注册到网关中间件注册表
+// 2. 注册后即可在 router.json 的 middlewares 中按名称引用
+// 插件应在 init 函数中调用，确保路由编译前完成注册
+// 参数：
+//   - name string: 中间件名称
+//   - factory middleware.Factory: 中间件工厂函数
+// 返回值：无
+func RegisterMiddleware(name string, factory middleware.Factory) {
+	middleware.Register(name, factory)
+}
@@ -12,6 +12,9 @@ type Request struct {
 	// Params 请求参数
 	// 存储请求的参数键值对
 	Params  map[string]string `json:"params"`
+	// RequestID 网关请求ID（X-Request-ID）
+	// 业务进程处理网关转发的请求时调用插件，应带回IPC请求中的 request_id，用于关联日志
+	RequestID string          `json:"request_id,omitempty"`
 }
 
 // Response 定义了插件返回的响应结构
@@ -59,8 +62,9 @@ func RegisterPlugin(name string, plugin ServicePlugin) {
 // DispatchRequest 分发请求到对应的插件
 // 功能：
 // 1. 根据请求中的服务名称查找对应的插件
-// 2. 调用插件的处理方法
-// 3. 返回处理结果
+// 2. 按注册的参数 schema 校验请求参数
+// 3. 调用插件的处理方法
+// 4. 返回处理结果
 // 参数：
 //   - req Request: 要处理的请求
 // 返回值：
@@ -73,5 +77,12 @@ func DispatchRequest(req Request) Response {
 			Message: "Service not found",
 		}
 	}
+	if errs := validateParams(req); len(errs) > 0 {
+		return Response{
+			Status:  400,
+			Message: "Invalid params",
+			Data:    map[string]interface{}{"errors": errs},
+		}
+	}
 	return plugin.HandleRequest(req)
 }
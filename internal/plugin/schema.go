@@ -0,0 +1,63 @@
+package plugin
+
+import (
+	"bigHammer/internal/schema"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var (
+	schemasMu sync.RWMutex
+	// schemas 插件方法参数 schema
+	// key: 服务名.方法名
+	schemas = make(map[string]*schema.Schema)
+)
+
+// RegisterSchema 注册插件方法的参数 schema
+// 注册后 DispatchRequest 在调用插件前校验 Request.Params，不通过时返回400
+// 参数：
+//   - service string: 服务名称
+//   - method string: 方法名称
+//   - s *schema.Schema: 参数 schema，参数值按属性类型转换后校验
+// 返回值：无
+func RegisterSchema(service, method string, s *schema.Schema) {
+	schemasMu.Lock()
+	defer schemasMu.Unlock()
+	schemas[service+"."+method] = s
+}
+
+// LoadSchemas 加载配置文件 plugin_schemas 中的参数 schema
+// 参数：
+//   - files map[string]string: 服务名.方法名 到 schema 文件路径的映射
+// 返回值：
+//   - error: 名称格式错误或 schema 文件无效时返回的错误信息
+func LoadSchemas(files map[string]string) error {
+	for name, file := range files {
+		service, method, ok := strings.Cut(name, ".")
+		if !ok || service == "" || method == "" {
+			return fmt.Errorf("plugin schema %q: expected service.method", name)
+		}
+		s, err := schema.Load(file)
+		if err != nil {
+			return fmt.Errorf("plugin schema %s: %v", name, err)
+		}
+		RegisterSchema(service, method, s)
+	}
+	return nil
+}
+
+// validateParams 按注册的 schema 校验请求参数，未注册 schema 时返回nil
+func validateParams(req Request) []schema.ValidationError {
+	schemasMu.RLock()
+	s := schemas[req.Service+"."+req.Method]
+	schemasMu.RUnlock()
+	if s == nil {
+		return nil
+	}
+	values := make(map[string][]string, len(req.Params))
+	for name, value := range req.Params {
+		values[name] = []string{value}
+	}
+	return s.Validate("params", s.Coerce(values))
+}
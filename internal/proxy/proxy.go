@@ -0,0 +1,231 @@
+package proxy
+
+import (
+	"bigHammer/internal/transform"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options HTTP反向代理配置，对应 router.json 中 language 为 http 的路由的 proxy 字段
+type Options struct {
+	// URL 上游服务地址，例如 http://127.0.0.1:8080/api，请求路径追加在其路径之后
+	URL string `json:"url"`
+	// StripPrefix 转发前从请求路径中去掉的前缀，例如 /users
+	StripPrefix string `json:"strip_prefix,omitempty"`
+	// PreserveHost 是否保留客户端请求的 Host 头，默认使用上游地址的 Host
+	PreserveHost bool `json:"preserve_host,omitempty"`
+	// Timeout 等待上游响应头的超时秒数，默认30（不限制流式响应体的传输时间）
+	Timeout int `json:"timeout,omitempty"`
+	// ConnectTimeout 连接上游的超时秒数，默认5
+	ConnectTimeout int `json:"connect_timeout,omitempty"`
+	// MaxIdleConns 每个上游主机保持的最大空闲连接数，默认32
+	MaxIdleConns int `json:"max_idle_conns,omitempty"`
+	// IdleTimeout 空闲连接保持秒数，默认90
+	IdleTimeout int `json:"idle_timeout,omitempty"`
+	// FlushInterval 响应体刷新间隔毫秒数，-1 表示每次写入后立即刷新；
+	// 默认0（text/event-stream 和未知长度的响应总是立即刷新）
+	FlushInterval int `json:"flush_interval,omitempty"`
+	// InsecureSkipVerify 是否跳过上游HTTPS证书校验（仅用于测试环境）
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+}
+
+// Validate 校验反向代理配置
+func (o Options) Validate() error {
+	if o.URL == "" {
+		return fmt.Errorf("proxy url is required")
+	}
+	u, err := url.Parse(o.URL)
+	if err != nil {
+		return fmt.Errorf("invalid proxy url: %v", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("proxy url scheme must be http or https")
+	}
+	if u.Host == "" {
+		return fmt.Errorf("proxy url has no host")
+	}
+	if o.StripPrefix != "" && !strings.HasPrefix(o.StripPrefix, "/") {
+		return fmt.Errorf("strip_prefix must start with /")
+	}
+	return nil
+}
+
+func (o Options) timeout() time.Duration {
+	if o.Timeout <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(o.Timeout) * time.Second
+}
+
+func (o Options) connectTimeout() time.Duration {
+	if o.ConnectTimeout <= 0 {
+		return 5 * time.Second
+	}
+	return time.Duration(o.ConnectTimeout) * time.Second
+}
+
+func (o Options) maxIdleConns() int {
+	if o.MaxIdleConns <= 0 {
+		return 32
+	}
+	return o.MaxIdleConns
+}
+
+func (o Options) idleTimeout() time.Duration {
+	if o.IdleTimeout <= 0 {
+		return 90 * time.Second
+	}
+	return time.Duration(o.IdleTimeout) * time.Second
+}
+
+// Proxy 单个路由的HTTP反向代理
+type Proxy struct {
+	target *url.URL
+	opts   Options
+	rp     *httputil.ReverseProxy
+}
+
+// errKey 上下文中保存代理错误的键，由 Forward 读取
+type errKey struct{}
+
+// New 创建HTTP反向代理
+// 功能：
+// 1. 按 strip_prefix 改写请求路径，拼接到上游地址
+// 2. 设置 X-Forwarded-For/Host/Proto，执行路由配置的请求头/查询参数/响应头变换
+// 3. 流式转发请求体和响应体，支持 WebSocket 等协议升级
+// 参数：
+//   - opts Options: 反向代理配置
+//   - t *transform.Transformer: 路由的变换规则，可为nil
+// 返回值：
+//   - *Proxy: 反向代理
+//   - error: 配置无效时返回的错误信息
+func New(opts Options, t *transform.Transformer) (*Proxy, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	target, _ := url.Parse(opts.URL)
+	p := &Proxy{target: target, opts: opts}
+	p.rp = &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			if opts.StripPrefix != "" {
+				pr.Out.URL.Path = stripPrefix(pr.Out.URL.Path, opts.StripPrefix)
+				pr.Out.URL.RawPath = ""
+			}
+			// 保留客户端传入的转发链，追加当前客户端地址
+			pr.Out.Header["X-Forwarded-For"] = pr.In.Header["X-Forwarded-For"]
+			pr.SetURL(target)
+			pr.SetXForwarded()
+			if opts.PreserveHost {
+				pr.Out.Host = pr.In.Host
+			}
+			if t != nil {
+				query := pr.Out.URL.Query()
+				t.TransformRequestHeaders(pr.Out.Header, query)
+				if t.ModifiesQuery() {
+					pr.Out.URL.RawQuery = query.Encode()
+				}
+			}
+		},
+		Transport:     transportFor(opts),
+		FlushInterval: time.Duration(opts.FlushInterval) * time.Millisecond,
+		ModifyResponse: func(resp *http.Response) error {
+			t.TransformResponseHeaders(resp.Header)
+			return nil
+		},
+		// 错误交由调用方按统一规则返回，这里只记录
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			if slot, ok := req.Context().Value(errKey{}).(*error); ok {
+				*slot = err
+			}
+		},
+	}
+	return p, nil
+}
+
+// Target 返回上游服务地址
+func (p *Proxy) Target() string {
+	return p.target.String()
+}
+
+// Host 返回上游服务的主机名和端口
+func (p *Proxy) Host() string {
+	return p.target.Host
+}
+
+// Forward 将请求转发到上游服务并流式写回响应
+// 参数：
+//   - w http.ResponseWriter: 响应写入器
+//   - req *http.Request: 客户端请求
+// 返回值：
+//   - error: 连接上游、等待响应头或协议升级失败时返回的错误信息，此时尚未写入响应
+func (p *Proxy) Forward(w http.ResponseWriter, req *http.Request) error {
+	var err error
+	p.rp.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), errKey{}, &err)))
+	return err
+}
+
+// stripPrefix 去掉路径前缀，结果总是以 / 开头
+func stripPrefix(path, prefix string) string {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if path != prefix && !strings.HasPrefix(path, prefix+"/") {
+		return path
+	}
+	path = strings.TrimPrefix(path, prefix)
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+var (
+	transportsMu sync.Mutex
+	// transports 按连接参数共享的 Transport，路由重新加载后继续复用已建立的 keepalive 连接
+	transports = make(map[transportKey]*http.Transport)
+)
+
+type transportKey struct {
+	timeout, connectTimeout, idleTimeout time.Duration
+	maxIdleConns                         int
+	insecureSkipVerify                   bool
+}
+
+// transportFor 返回与配置的连接参数对应的 Transport
+func transportFor(opts Options) *http.Transport {
+	key := transportKey{
+		timeout:            opts.timeout(),
+		connectTimeout:     opts.connectTimeout(),
+		idleTimeout:        opts.idleTimeout(),
+		maxIdleConns:       opts.maxIdleConns(),
+		insecureSkipVerify: opts.InsecureSkipVerify,
+	}
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+	if t, ok := transports[key]; ok {
+		return t
+	}
+	t := &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout:   key.connectTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConnsPerHost:   key.maxIdleConns,
+		IdleConnTimeout:       key.idleTimeout,
+		ResponseHeaderTimeout: key.timeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	if key.insecureSkipVerify {
+		t.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	transports[key] = t
+	return t
+}
@@ -0,0 +1,234 @@
+package resilience
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// 熔断器状态
+const (
+	StateClosed   = "closed"
+	StateOpen     = "open"
+	StateHalfOpen = "half_open"
+)
+
+// ErrCircuitOpen 熔断器处于打开状态，请求被快速拒绝
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+var (
+	breakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gateway_circuit_breaker_state",
+		Help: "熔断器状态：0=closed，1=open，2=half_open",
+	}, []string{"route"})
+	breakerTransitions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_circuit_breaker_transitions_total",
+		Help: "熔断器状态切换次数",
+	}, []string{"route", "to"})
+	breakerRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_circuit_breaker_rejections_total",
+		Help: "熔断器打开时被快速拒绝的请求数",
+	}, []string{"route"})
+)
+
+// stateValue 状态对应的指标值
+var stateValue = map[string]float64{StateClosed: 0, StateOpen: 1, StateHalfOpen: 2}
+
+// BreakerOptions 熔断器配置，对应 router.json 中路由的 circuit_breaker 字段
+type BreakerOptions struct {
+	// FailureThreshold 连续失败多少次后打开，默认5
+	FailureThreshold int `json:"failure_threshold,omitempty"`
+	// OpenTimeout 打开后经过多少秒进入半开状态，默认30
+	OpenTimeout int `json:"open_timeout,omitempty"`
+	// HalfOpenRequests 半开状态允许同时通过的试探请求数，默认1
+	HalfOpenRequests int `json:"half_open_requests,omitempty"`
+	// SuccessThreshold 半开状态连续成功多少次后关闭，默认1
+	SuccessThreshold int `json:"success_threshold,omitempty"`
+}
+
+func (o BreakerOptions) failureThreshold() int {
+	if o.FailureThreshold <= 0 {
+		return 5
+	}
+	return o.FailureThreshold
+}
+
+func (o BreakerOptions) openTimeout() time.Duration {
+	if o.OpenTimeout <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(o.OpenTimeout) * time.Second
+}
+
+func (o BreakerOptions) halfOpenRequests() int {
+	if o.HalfOpenRequests <= 0 {
+		return 1
+	}
+	return o.HalfOpenRequests
+}
+
+func (o BreakerOptions) successThreshold() int {
+	if o.SuccessThreshold <= 0 {
+		return 1
+	}
+	return o.SuccessThreshold
+}
+
+// Breaker 熔断器
+// closed：正常放行，连续失败达到阈值后打开
+// open：快速拒绝，超时后进入半开
+// half_open：放行有限的试探请求，成功达到阈值后关闭，任一失败重新打开
+type Breaker struct {
+	Name string
+	opts BreakerOptions
+
+	mu        sync.Mutex
+	state     string
+	failures  int
+	successes int
+	probes    int
+	openedAt  time.Time
+	// generation 每次状态切换时递增，Record 忽略切换之前放行的请求的结果
+	generation uint64
+}
+
+var (
+	breakersMu sync.RWMutex
+	// breakers 已创建的熔断器
+	// key: 熔断器名称（路由名称）
+	breakers = make(map[string]*Breaker)
+)
+
+// NewBreaker 创建熔断器，创建后需通过 RegisterBreakers 登记才能被管理接口看到
+func NewBreaker(name string, opts BreakerOptions) *Breaker {
+	return &Breaker{Name: name, opts: opts, state: StateClosed}
+}
+
+// RegisterBreakers 以本次编译的路由熔断器替换全局注册表
+// 路由编译成功后调用，编译失败时注册表仍指向旧路由正在使用的熔断器
+// 参数：
+//   - list []*Breaker: 生效路由使用的所有熔断器
+// 返回值：无
+func RegisterBreakers(list []*Breaker) {
+	next := make(map[string]*Breaker, len(list))
+	for _, b := range list {
+		next[b.Name] = b
+		breakerState.WithLabelValues(b.Name).Set(stateValue[b.State()])
+	}
+	breakersMu.Lock()
+	breakers = next
+	breakersMu.Unlock()
+}
+
+// Breakers 返回所有熔断器的当前状态
+func Breakers() map[string]string {
+	breakersMu.RLock()
+	defer breakersMu.RUnlock()
+	states := make(map[string]string, len(breakers))
+	for name, b := range breakers {
+		states[name] = b.State()
+	}
+	return states
+}
+
+// State 返回当前状态
+func (b *Breaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance(time.Now())
+	return b.state
+}
+
+// Allow 判断请求是否可以通过
+// 返回值：
+//   - uint64: 放行时的状态代数，传给 Record 或 Release
+//   - time.Duration: 被拒绝时距离进入半开状态的时间，用于 Retry-After
+//   - error: 被拒绝时返回 ErrCircuitOpen
+func (b *Breaker) Allow() (uint64, time.Duration, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.advance(now)
+	switch b.state {
+	case StateOpen:
+		breakerRejections.WithLabelValues(b.Name).Inc()
+		return 0, b.openedAt.Add(b.opts.openTimeout()).Sub(now), ErrCircuitOpen
+	case StateHalfOpen:
+		if b.probes >= b.opts.halfOpenRequests() {
+			breakerRejections.WithLabelValues(b.Name).Inc()
+			return 0, time.Second, ErrCircuitOpen
+		}
+		b.probes++
+	}
+	return b.generation, 0, nil
+}
+
+// Record 记录一次请求结果，必须与成功的 Allow 调用成对出现
+// 放行之后熔断器已切换过状态时忽略结果，例如关闭状态放行、半开状态才完成的请求不计为试探结果
+// 参数：
+//   - generation uint64: Allow 返回的状态代数
+//   - success bool: 请求是否成功
+func (b *Breaker) Record(generation uint64, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if generation != b.generation {
+		return
+	}
+	switch b.state {
+	case StateClosed:
+		if success {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.opts.failureThreshold() {
+			b.transition(StateOpen)
+		}
+	case StateHalfOpen:
+		if b.probes > 0 {
+			b.probes--
+		}
+		if !success {
+			b.transition(StateOpen)
+			return
+		}
+		b.successes++
+		if b.successes >= b.opts.successThreshold() {
+			b.transition(StateClosed)
+		}
+	}
+}
+
+// Release 归还放行名额而不记录结果，用于与后端健康无关的失败（如请求体过大）
+// 半开状态下释放试探名额，否则名额会一直被占用
+func (b *Breaker) Release(generation uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if generation == b.generation && b.state == StateHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+// advance 打开超时后切换到半开状态
+func (b *Breaker) advance(now time.Time) {
+	if b.state == StateOpen && !now.Before(b.openedAt.Add(b.opts.openTimeout())) {
+		b.transition(StateHalfOpen)
+	}
+}
+
+// transition 切换状态并重置计数
+func (b *Breaker) transition(state string) {
+	b.state = state
+	b.generation++
+	b.failures, b.successes, b.probes = 0, 0, 0
+	if state == StateOpen {
+		b.openedAt = time.Now()
+	}
+	breakerState.WithLabelValues(b.Name).Set(stateValue[state])
+	breakerTransitions.WithLabelValues(b.Name, state).Inc()
+	log.Printf("路由 %s 熔断器切换为 %s", b.Name, state)
+}
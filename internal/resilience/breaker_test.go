@@ -0,0 +1,149 @@
+package resilience
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// expire 让打开状态的熔断器立即到达半开时间
+func expire(b *Breaker) {
+	b.mu.Lock()
+	b.openedAt = time.Now().Add(-time.Hour)
+	b.mu.Unlock()
+}
+
+// allow 调用 Allow 并要求放行
+func allow(t *testing.T, b *Breaker) uint64 {
+	t.Helper()
+	generation, _, err := b.Allow()
+	if err != nil {
+		t.Fatalf("Allow in state %s: %v", b.State(), err)
+	}
+	return generation
+}
+
+func TestBreakerOpensAfterFailures(t *testing.T) {
+	b := NewBreaker("test-open", BreakerOptions{FailureThreshold: 3, OpenTimeout: 10})
+	for i := 0; i < 2; i++ {
+		b.Record(allow(t, b), false)
+	}
+	// 成功请求重置连续失败计数
+	b.Record(allow(t, b), true)
+	for i := 0; i < 2; i++ {
+		b.Record(allow(t, b), false)
+	}
+	if state := b.State(); state != StateClosed {
+		t.Fatalf("state = %s, want %s", state, StateClosed)
+	}
+	b.Record(allow(t, b), false)
+	if state := b.State(); state != StateOpen {
+		t.Fatalf("state = %s, want %s", state, StateOpen)
+	}
+
+	_, wait, err := b.Allow()
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("err = %v, want ErrCircuitOpen", err)
+	}
+	if wait <= 9*time.Second || wait > 10*time.Second {
+		t.Fatalf("wait = %v, want about 10s", wait)
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    BreakerOptions
+		results []bool
+		want    string
+	}{
+		{name: "probe succeeds", opts: BreakerOptions{FailureThreshold: 1}, results: []bool{true}, want: StateClosed},
+		{name: "probe fails", opts: BreakerOptions{FailureThreshold: 1}, results: []bool{false}, want: StateOpen},
+		{name: "needs consecutive successes", opts: BreakerOptions{FailureThreshold: 1, SuccessThreshold: 3}, results: []bool{true, true}, want: StateHalfOpen},
+		{name: "reaches success threshold", opts: BreakerOptions{FailureThreshold: 1, SuccessThreshold: 3}, results: []bool{true, true, true}, want: StateClosed},
+		{name: "failure after successes", opts: BreakerOptions{FailureThreshold: 1, SuccessThreshold: 3}, results: []bool{true, true, false}, want: StateOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBreaker("test-half-open", tt.opts)
+			b.Record(allow(t, b), false)
+			expire(b)
+			if state := b.State(); state != StateHalfOpen {
+				t.Fatalf("state = %s, want %s", state, StateHalfOpen)
+			}
+			for _, ok := range tt.results {
+				b.Record(allow(t, b), ok)
+			}
+			if state := b.State(); state != tt.want {
+				t.Fatalf("state = %s, want %s", state, tt.want)
+			}
+		})
+	}
+}
+
+func TestBreakerHalfOpenProbes(t *testing.T) {
+	b := NewBreaker("test-probes", BreakerOptions{FailureThreshold: 1, HalfOpenRequests: 2, SuccessThreshold: 2})
+	b.Record(allow(t, b), false)
+	expire(b)
+
+	first, second := allow(t, b), allow(t, b)
+	if _, _, err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("third probe err = %v, want ErrCircuitOpen", err)
+	}
+
+	// Release 归还名额但不计入结果
+	b.Release(second)
+	third := allow(t, b)
+	b.Record(first, true)
+	if state := b.State(); state != StateHalfOpen {
+		t.Fatalf("state = %s, want %s", state, StateHalfOpen)
+	}
+	b.Record(third, true)
+	if state := b.State(); state != StateClosed {
+		t.Fatalf("state = %s, want %s", state, StateClosed)
+	}
+}
+
+func TestBreakerIgnoresStaleResults(t *testing.T) {
+	b := NewBreaker("test-stale", BreakerOptions{FailureThreshold: 1})
+	closedGen := allow(t, b)
+	b.Record(allow(t, b), false)
+	expire(b)
+	probe := allow(t, b)
+
+	// 关闭状态放行、半开状态才完成的请求不影响试探结果
+	b.Record(closedGen, false)
+	if state := b.State(); state != StateHalfOpen {
+		t.Fatalf("state after stale failure = %s, want %s", state, StateHalfOpen)
+	}
+	b.Release(closedGen)
+	if _, _, err := b.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("stale release freed a probe slot: err = %v", err)
+	}
+
+	b.Record(probe, true)
+	if state := b.State(); state != StateClosed {
+		t.Fatalf("state = %s, want %s", state, StateClosed)
+	}
+	// 半开状态放行的请求在关闭后完成，同样被忽略
+	b.Record(probe, false)
+	if state := b.State(); state != StateClosed {
+		t.Fatalf("state after stale probe = %s, want %s", state, StateClosed)
+	}
+}
+
+func TestRegisterBreakers(t *testing.T) {
+	a := NewBreaker("test-a", BreakerOptions{})
+	b := NewBreaker("test-b", BreakerOptions{FailureThreshold: 1})
+	b.Record(allow(t, b), false)
+	defer RegisterBreakers(nil)
+
+	RegisterBreakers([]*Breaker{a, b})
+	if got := Breakers(); len(got) != 2 || got["test-a"] != StateClosed || got["test-b"] != StateOpen {
+		t.Fatalf("Breakers = %v", got)
+	}
+	RegisterBreakers([]*Breaker{a})
+	if got := Breakers(); len(got) != 1 || got["test-a"] != StateClosed {
+		t.Fatalf("Breakers after reload = %v", got)
+	}
+}
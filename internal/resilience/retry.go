@@ -0,0 +1,125 @@
+package resilience
+
+import (
+	"bigHammer/internal/upstream"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 可重试的错误类别
+const (
+	// ErrorConnect 无法连接业务进程（包括后端没有可用端点），请求一定没有被处理
+	ErrorConnect = "connect"
+	// ErrorTimeout 等待业务进程响应超时
+	ErrorTimeout = "timeout"
+	// ErrorIO 连接建立后读写失败（连接被重置、响应不完整等），请求可能已被处理
+	ErrorIO = "io"
+)
+
+// RetryOptions 重试策略，对应 router.json 中路由的 retry 字段
+type RetryOptions struct {
+	// Attempts 最大尝试次数（包含第一次），默认3
+	Attempts int `json:"attempts,omitempty"`
+	// Backoff 首次重试前的等待毫秒数，之后每次翻倍，默认100
+	Backoff int `json:"backoff,omitempty"`
+	// MaxBackoff 单次等待的最大毫秒数，默认2000
+	MaxBackoff int `json:"max_backoff,omitempty"`
+	// Jitter 等待时间的随机抖动比例（0~1），默认0.5
+	Jitter *float64 `json:"jitter,omitempty"`
+	// On 可重试的错误类别：connect、timeout、io，默认只重试 connect
+	On []string `json:"on,omitempty"`
+	// Methods 允许重试的HTTP方法，默认只重试幂等方法 GET、HEAD、OPTIONS、PUT、DELETE
+	Methods []string `json:"methods,omitempty"`
+}
+
+// MaxAttempts 返回最大尝试次数，未配置重试时为1
+func (o *RetryOptions) MaxAttempts() int {
+	if o == nil {
+		return 1
+	}
+	if o.Attempts <= 0 {
+		return 3
+	}
+	return o.Attempts
+}
+
+// AllowsMethod 判断请求方法是否允许重试
+func (o *RetryOptions) AllowsMethod(method string) bool {
+	if o == nil {
+		return false
+	}
+	methods := o.Methods
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete}
+	}
+	for _, m := range methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// Retryable 判断错误是否属于可重试的类别
+func (o *RetryOptions) Retryable(err error) bool {
+	if o == nil || err == nil {
+		return false
+	}
+	on := o.On
+	if len(on) == 0 {
+		on = []string{ErrorConnect}
+	}
+	class := Classify(err)
+	for _, c := range on {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// Delay 返回第 attempt 次重试（从1开始）前的等待时间
+func (o *RetryOptions) Delay(attempt int) time.Duration {
+	base := time.Duration(o.Backoff) * time.Millisecond
+	if base <= 0 {
+		base = 100 * time.Millisecond
+	}
+	max := time.Duration(o.MaxBackoff) * time.Millisecond
+	if max <= 0 {
+		max = 2 * time.Second
+	}
+	delay := base << uint(attempt-1)
+	if delay > max || delay <= 0 {
+		delay = max
+	}
+	jitter := 0.5
+	if o.Jitter != nil {
+		jitter = *o.Jitter
+	}
+	if jitter > 0 {
+		// 在 [delay*(1-jitter), delay] 范围内随机
+		delay -= time.Duration(rand.Float64() * jitter * float64(delay))
+	}
+	return delay
+}
+
+// Classify 判断IPC错误的类别
+// 返回值：connect、timeout 或 io
+func Classify(err error) string {
+	if errors.Is(err, upstream.ErrNoEndpoint) {
+		return ErrorConnect
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return ErrorConnect
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrorTimeout
+	}
+	return ErrorIO
+}
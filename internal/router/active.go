@@ -0,0 +1,45 @@
+package router
+
+import (
+	"bigHammer/internal/interface/database"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// active 当前生效的路由，由 Reload 原子替换
+	active atomic.Pointer[Router]
+	// reloadMu 保证同一时间只有一次重新加载
+	reloadMu sync.Mutex
+)
+
+// Active 返回当前生效的路由，尚未加载时返回nil
+func Active() *Router {
+	return active.Load()
+}
+
+// Reload 重新加载配置文件和路由配置
+// 功能：
+// 1. 读取 config.json 和 router.json
+// 2. 编译路由处理链
+// 3. 编译成功后替换当前路由，正在处理的请求继续使用旧路由完成
+// 参数：
+//   - db database.IDatabase: 路由使用的数据库实例
+// 返回值：
+//   - *Router: 新的路由
+//   - error: 配置加载或编译失败时返回的错误信息，此时保留旧路由
+func Reload(db database.IDatabase) (*Router, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	r, err := LoadRouterConfig()
+	if err != nil {
+		return nil, err
+	}
+	r.DB = db
+	if err := r.Build(); err != nil {
+		return nil, err
+	}
+	active.Store(&r)
+	return &r, nil
+}
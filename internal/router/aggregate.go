@@ -0,0 +1,132 @@
+package router
+
+import (
+	"bigHammer/internal/accesslog"
+	"bigHammer/internal/aggregate"
+	ipc "bigHammer/internal/ipc/socket"
+	"bigHammer/internal/limits"
+	"bigHammer/internal/middleware"
+	"bigHammer/internal/resilience"
+	"bigHammer/internal/transform"
+	"bigHammer/internal/upstream"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+)
+
+// compileAggregate 校验聚合路由配置并创建聚合器
+// 聚合路由的响应由网关合并各子调用的结果生成，因此不支持替换处理方式或请求体的配置
+func compileAggregate(rt *routeRuntime, pools map[string]*upstream.Pool) error {
+	route := rt.route
+	var requestBody, template bool
+	if t := route.Transform; t != nil {
+		requestBody = t.Request != nil && t.Request.Body != nil
+		template = t.Response != nil && t.Response.Template != nil
+	}
+	for _, c := range []struct {
+		name string
+		set  bool
+	}{
+		{"language http", route.Language == LanguageHTTP},
+		{"files", route.Files != nil},
+		{"sse", route.SSE != nil},
+		{"async", route.Async != nil},
+		{"split", route.Split != nil},
+		{"mirror", route.Mirror != nil},
+		{"retry", route.Retry != nil},
+		{"circuit_breaker", route.CircuitBreaker != nil},
+		{"limits.stream_body", route.Limits != nil && route.Limits.StreamBody},
+		{"transform.request.body", requestBody},
+		{"transform.response.template", template},
+	} {
+		if c.set {
+			return fmt.Errorf("%s is not supported for aggregate routes", c.name)
+		}
+	}
+	a, err := aggregate.New(routeName(route), *route.Aggregate, route.Backend, pools)
+	if err != nil {
+		return err
+	}
+	rt.aggregator = a
+	return nil
+}
+
+// forwardAggregate 并行执行聚合路由的子调用，将结果合并为一个JSON响应
+// 必需子调用失败时按错误类别返回503/504/502，并在 data 中给出失败的子调用；
+// 可选子调用失败时使用 fallback，失败信息写入响应的 errors 字段
+func (r *Router) forwardAggregate(w http.ResponseWriter, req *http.Request, rt *routeRuntime) {
+	requestID := middleware.RequestIDFromContext(req.Context())
+	entry := accesslog.FromContext(req.Context())
+	entry.SetBackend("aggregate")
+
+	bodyBytes, err := io.ReadAll(http.MaxBytesReader(w, req.Body, ipc.MaxPayloadSize))
+	if requestTooLarge(err) {
+		limits.RejectBody(w, req, err)
+		return
+	}
+	if err != nil {
+		log.Println("Error reading request body:", err)
+		http.Error(w, "内部服务器错误", http.StatusInternalServerError)
+		return
+	}
+	var bodyData interface{}
+	var bodyErr error
+	if len(bodyBytes) > 0 {
+		if bodyErr = json.Unmarshal(bodyBytes, &bodyData); bodyErr != nil {
+			bodyData = string(bodyBytes)
+		}
+	}
+
+	pathParams := PathParams(req.Context())
+	if rt.validator != nil {
+		if errs := rt.validator.Validate(bodyData, bodyErr, req.URL.Query(), pathParams); len(errs) > 0 {
+			middleware.WriteError(w, http.StatusBadRequest, "Request validation failed", map[string]interface{}{"errors": errs})
+			return
+		}
+	}
+
+	headers := req.Header.Clone()
+	query := req.URL.Query()
+	rt.transformer.TransformRequestHeaders(headers, query)
+	input := transform.TemplateData(headers, query, bodyData, req.URL.Path)
+	path := make(map[string]interface{}, len(pathParams))
+	for k, v := range pathParams {
+		path[k] = v
+	}
+	input["path"] = path
+
+	result := rt.aggregator.Run(req.Context(), requestID, input)
+	if result.Failed != nil {
+		log.Printf("聚合子调用失败 [%s]: %v", requestID, result.Failed)
+		entry.SetError(result.Failed)
+		status, message := http.StatusBadGateway, "Backend error"
+		switch resilience.Classify(result.Failed) {
+		case resilience.ErrorConnect:
+			status, message = http.StatusServiceUnavailable, "Backend unavailable"
+		case resilience.ErrorTimeout:
+			status, message = http.StatusGatewayTimeout, "Backend timeout"
+		}
+		middleware.WriteError(w, status, message, result.Failed)
+		return
+	}
+
+	body := map[string]interface{}{
+		"status":  http.StatusOK,
+		"message": "OK",
+		"data":    result.Data,
+	}
+	if len(result.Errors) > 0 {
+		body["errors"] = result.Errors
+	}
+	output, err := json.Marshal(body)
+	if err != nil {
+		log.Println("序列化聚合响应失败:", err)
+		http.Error(w, "内部服务器错误", http.StatusInternalServerError)
+		return
+	}
+	output, _ = rt.transformer.TransformResponse(w.Header(), output)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(output)
+}
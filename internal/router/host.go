@@ -0,0 +1,283 @@
+package router
+
+import (
+	"bigHammer/internal/limits"
+	"bigHammer/internal/middleware/ipacl"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// RouteGroup 路由分组，对应 router.json 中的 groups
+// 分组内的路由共享主机名、路径前缀、中间件和后端配置
+type RouteGroup struct {
+	// Name 分组名称
+	Name string `json:"name"`
+	// Hosts 分组匹配的主机名，支持 *.example.com 通配（匹配任意层级子域名，不含 example.com 本身）
+	// 为空时匹配所有主机
+	Hosts []string `json:"hosts,omitempty"`
+	// Prefix 分组内路由路径的公共前缀，例如 /v1
+	Prefix string `json:"prefix,omitempty"`
+	// Middlewares 分组中间件，在全局中间件之后、路由中间件之前执行
+	Middlewares []string `json:"middlewares,omitempty"`
+	// MiddlewareOptions 分组中间件选项，覆盖同名的全局选项，路由选项优先
+	MiddlewareOptions map[string]map[string]interface{} `json:"middleware_options,omitempty"`
+	// Backend 分组内路由的默认后端，路由配置了 backend 时以路由为准
+	Backend string `json:"backend,omitempty"`
+	// ACL 分组内路由的IP访问控制规则，路由配置了 acl 时以路由为准
+	ACL *ipacl.Rules `json:"acl,omitempty"`
+	// Limits 分组内路由的请求大小限制，路由配置了 limits 时以路由为准
+	Limits *limits.Options `json:"limits,omitempty"`
+	// Version 分组内路由的API版本，路由配置了 version 时以路由为准
+	Version string `json:"version,omitempty"`
+	// Deprecation 分组内路由的弃用信息，路由配置了 deprecation 时以路由为准
+	Deprecation *Deprecation `json:"deprecation,omitempty"`
+	// Routes 分组内的路由，路径相对于 Prefix
+	Routes []Route `json:"routes"`
+}
+
+// AllRoutes 返回顶层路由和展开后的分组路由
+// 分组路由的路径加上分组前缀，继承分组的主机名、中间件和后端
+// 返回值：
+//   - []Route: 所有路由，顶层路由在前，分组路由按配置顺序在后
+func (r *Router) AllRoutes() []Route {
+	routes := append([]Route(nil), r.Routes...)
+	for _, group := range r.Groups {
+		for _, route := range group.Routes {
+			routes = append(routes, group.expand(route))
+		}
+	}
+	return routes
+}
+
+// expand 将分组配置合并到路由
+func (g RouteGroup) expand(route Route) Route {
+	route.Group = g.Name
+	if g.Prefix != "" {
+		route.Path = strings.TrimSuffix(g.Prefix, "/") + route.Path
+	}
+	if len(route.Hosts) == 0 {
+		route.Hosts = g.Hosts
+	}
+	if route.Backend == "" {
+		route.Backend = g.Backend
+	}
+	if route.ACL == nil {
+		route.ACL = g.ACL
+	}
+	if route.Limits == nil {
+		route.Limits = g.Limits
+	}
+	if route.Version == "" {
+		route.Version = g.Version
+	}
+	if route.Deprecation == nil {
+		route.Deprecation = g.Deprecation
+	}
+	route.Middlewares = append(append([]string(nil), g.Middlewares...), route.Middlewares...)
+	if len(g.MiddlewareOptions) > 0 {
+		options := make(map[string]map[string]interface{}, len(g.MiddlewareOptions)+len(route.MiddlewareOptions))
+		for name, opts := range g.MiddlewareOptions {
+			options[name] = opts
+		}
+		for name, opts := range route.MiddlewareOptions {
+			options[name] = opts
+		}
+		route.MiddlewareOptions = options
+	}
+	return route
+}
+
+// validateGroup 校验分组配置
+func validateGroup(g RouteGroup) error {
+	if g.Prefix != "" && !strings.HasPrefix(g.Prefix, "/") {
+		return fmt.Errorf("group %s: prefix must start with /", g.Name)
+	}
+	for _, host := range g.Hosts {
+		if err := validateHost(host); err != nil {
+			return fmt.Errorf("group %s: %v", g.Name, err)
+		}
+	}
+	return nil
+}
+
+// validateHost 校验主机名模式，通配符只能出现在最左侧，例如 *.example.com
+func validateHost(host string) error {
+	if host == "" {
+		return fmt.Errorf("empty host")
+	}
+	if strings.Contains(strings.TrimPrefix(host, "*."), "*") {
+		return fmt.Errorf("invalid host %q: wildcard must be the leftmost label", host)
+	}
+	return nil
+}
+
+// normalizeHost 去掉端口和末尾的点并转为小写
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// MatchHost 判断请求的主机名是否匹配路由配置的主机名，hosts 为空时总是匹配
+// 参数：
+//   - hosts []string: 主机名模式，支持 *.example.com 通配
+//   - host string: 请求的 Host（可带端口）
+// 返回值：
+//   - bool: 是否匹配
+func MatchHost(hosts []string, host string) bool {
+	if len(hosts) == 0 {
+		return true
+	}
+	host = normalizeHost(host)
+	for _, pattern := range hosts {
+		pattern = normalizeHost(pattern)
+		if pattern == host || (strings.HasPrefix(pattern, "*.") && strings.HasSuffix(host, pattern[1:])) {
+			return true
+		}
+	}
+	return false
+}
+
+// routeTable 同一主机名模式下的路由表
+type routeTable struct {
+	// handlers 精确匹配的路由处理链
+	// key: 路由路径
+	handlers map[string]http.Handler
+	// templates 带参数的路由路径（如 /users/{id}），按配置顺序匹配
+	templates []pathTemplate
+	// prefixes 按前缀匹配的 http 路由，按路径长度从长到短排列
+	prefixes []prefixRoute
+}
+
+// hostTables 按主机名组织的路由表
+type hostTables struct {
+	// exact 精确主机名的路由表
+	exact map[string]*routeTable
+	// wildcards 通配主机名的路由表，按后缀长度从长到短排列
+	wildcards []wildcardTable
+	// fallback 未配置主机名的路由表，匹配所有主机
+	fallback *routeTable
+}
+
+type wildcardTable struct {
+	// suffix 通配主机名去掉 * 后的后缀，例如 .example.com
+	suffix string
+	table  *routeTable
+}
+
+func newHostTables() *hostTables {
+	return &hostTables{exact: make(map[string]*routeTable), fallback: newRouteTable()}
+}
+
+func newRouteTable() *routeTable {
+	return &routeTable{handlers: make(map[string]http.Handler)}
+}
+
+// tablesFor 返回路由所属的路由表，未配置主机名时为 fallback
+func (h *hostTables) tablesFor(hosts []string) []*routeTable {
+	if len(hosts) == 0 {
+		return []*routeTable{h.fallback}
+	}
+	var tables []*routeTable
+	for _, pattern := range hosts {
+		pattern = normalizeHost(pattern)
+		if strings.HasPrefix(pattern, "*.") {
+			suffix := pattern[1:]
+			found := false
+			for _, w := range h.wildcards {
+				if w.suffix == suffix {
+					tables, found = append(tables, w.table), true
+				}
+			}
+			if !found {
+				w := wildcardTable{suffix: suffix, table: newRouteTable()}
+				h.wildcards = append(h.wildcards, w)
+				tables = append(tables, w.table)
+			}
+			continue
+		}
+		if h.exact[pattern] == nil {
+			h.exact[pattern] = newRouteTable()
+		}
+		tables = append(tables, h.exact[pattern])
+	}
+	return tables
+}
+
+// add 将路由处理链加入其主机名对应的路由表
+func (h *hostTables) add(route Route, handler http.Handler, prefix bool) error {
+	for _, table := range h.tablesFor(route.Hosts) {
+		if isTemplate(route.Path) {
+			for _, t := range table.templates {
+				if t.path == route.Path {
+					return fmt.Errorf("duplicate route %s", route.Path)
+				}
+			}
+			table.templates = append(table.templates, newPathTemplate(route.Path, handler))
+			continue
+		}
+		if _, ok := table.handlers[route.Path]; ok {
+			return fmt.Errorf("duplicate route %s", route.Path)
+		}
+		table.handlers[route.Path] = handler
+		if prefix {
+			table.prefixes = append(table.prefixes, prefixRoute{path: route.Path, handler: handler})
+		}
+	}
+	return nil
+}
+
+// finish 完成路由表编译，对前缀路由和通配主机名排序
+func (h *hostTables) finish() {
+	sort.SliceStable(h.wildcards, func(i, j int) bool {
+		return len(h.wildcards[i].suffix) > len(h.wildcards[j].suffix)
+	})
+	sortPrefixes(h.fallback.prefixes)
+	for _, t := range h.exact {
+		sortPrefixes(t.prefixes)
+	}
+	for _, w := range h.wildcards {
+		sortPrefixes(w.table.prefixes)
+	}
+}
+
+// candidates 按优先级返回请求主机名可以使用的路由表：精确主机名 → 通配主机名（后缀越长越优先） → 未配置主机名
+func (h *hostTables) candidates(host string) []*routeTable {
+	host = normalizeHost(host)
+	var tables []*routeTable
+	if t, ok := h.exact[host]; ok {
+		tables = append(tables, t)
+	}
+	for _, w := range h.wildcards {
+		if strings.HasSuffix(host, w.suffix) {
+			tables = append(tables, w.table)
+		}
+	}
+	return append(tables, h.fallback)
+}
+
+// match 在路由表中查找请求路径对应的处理链
+// 返回值：
+//   - string: 匹配的路由路径
+//   - http.Handler: 处理链，未匹配时为nil
+//   - map[string]string: 路径模板参数，非模板路由为nil
+func (t *routeTable) match(path string) (string, http.Handler, map[string]string) {
+	if handler, ok := t.handlers[path]; ok {
+		return path, handler, nil
+	}
+	for _, tpl := range t.templates {
+		if params, ok := tpl.match(path); ok {
+			return tpl.path, tpl.handler, params
+		}
+	}
+	for _, p := range t.prefixes {
+		if p.match(path) {
+			return p.path, p.handler, nil
+		}
+	}
+	return "", nil, nil
+}
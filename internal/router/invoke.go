@@ -0,0 +1,163 @@
+package router
+
+import (
+	"bigHammer/internal/accesslog"
+	"bigHammer/internal/config"
+	ipc "bigHammer/internal/ipc/socket"
+	"bigHammer/internal/middleware"
+	"bigHammer/internal/resilience"
+	"bigHammer/pkg/utils"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// endpoint 选择处理请求的业务进程端点
+// 返回值：
+//   - string: 端点地址
+//   - func(error): 请求结束后调用，记录结果用于被动健康检测
+//   - error: 后端没有可用端点时返回的错误信息
+func (rt *routeRuntime) endpoint(req *http.Request) (string, func(error), error) {
+	if rt.proxy != nil {
+		return rt.proxy.Target(), func(error) {}, nil
+	}
+	if rt.pool == nil {
+		path, err := utils.ResolvePath(config.GlobalConfig.BussinessSocketPath)
+		return path, func(error) {}, err
+	}
+	e, err := rt.pool.Pick(rt.pool.Key(req))
+	if err != nil {
+		return "", nil, err
+	}
+	return e.Address, func(err error) { rt.pool.Done(e, err) }, nil
+}
+
+// backendName 返回路由使用的后端名称，使用默认业务Socket时为 default，http 路由为上游主机
+func (rt *routeRuntime) backendName() string {
+	if rt.proxy != nil {
+		return rt.proxy.Host()
+	}
+	if rt.route.Backend == "" {
+		return "default"
+	}
+	return rt.route.Backend
+}
+
+// timeout 返回单次调用业务进程的超时时间
+func (rt *routeRuntime) timeout() time.Duration {
+	if rt.route.Timeout <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(rt.route.Timeout) * time.Second
+}
+
+// circuitOpenError 熔断器打开时返回的错误，携带建议的重试等待时间
+type circuitOpenError struct {
+	retryAfter time.Duration
+}
+
+func (e *circuitOpenError) Error() string {
+	return resilience.ErrCircuitOpen.Error()
+}
+
+func (e *circuitOpenError) Unwrap() error {
+	return resilience.ErrCircuitOpen
+}
+
+// invoke 调用业务进程
+// 功能：
+// 1. 熔断器打开时直接返回错误，不连接业务进程
+// 2. 每次尝试重新选择端点，按路由的 retry 配置对可重试的错误退避重试
+// 3. 以最终结果更新熔断器状态
+// 4. 每次尝试的端点和耗时记录到访问日志
+// 参数：
+//   - req *http.Request: 客户端请求
+//   - send func(endpoint string) error: 向指定端点发送请求
+// 返回值：
+//   - error: 最后一次尝试的错误信息
+func (rt *routeRuntime) invoke(req *http.Request, send func(endpoint string) error) error {
+	var generation uint64
+	if rt.breaker != nil {
+		var wait time.Duration
+		var err error
+		if generation, wait, err = rt.breaker.Allow(); err != nil {
+			return &circuitOpenError{retryAfter: wait}
+		}
+	}
+
+	retry := rt.route.Retry
+	attempts := 1
+	if retry.AllowsMethod(req.Method) && rt.replayable(req) {
+		attempts = retry.MaxAttempts()
+	}
+	entry := accesslog.FromContext(req.Context())
+	var err error
+	for attempt := 1; ; attempt++ {
+		var endpoint string
+		var done func(error)
+		if endpoint, done, err = rt.endpoint(req); err == nil {
+			start := time.Now()
+			err = send(endpoint)
+			entry.Upstream(endpoint, time.Since(start))
+			done(err)
+		}
+		if err == nil || attempt >= attempts || requestTooLarge(err) || !retry.Retryable(err) {
+			break
+		}
+		timer := time.NewTimer(retry.Delay(attempt))
+		select {
+		case <-timer.C:
+			continue
+		case <-req.Context().Done():
+			timer.Stop()
+		}
+		break
+	}
+
+	// 请求过大和客户端断开是客户端的问题，不计入熔断统计
+	if rt.breaker != nil {
+		if requestTooLarge(err) || req.Context().Err() != nil {
+			rt.breaker.Release(generation)
+		} else {
+			rt.breaker.Record(generation, err == nil)
+		}
+	}
+	return err
+}
+
+// replayable 判断请求失败后能否重发
+// http 路由和开启 stream_body 的路由以流的方式转发请求体，请求体读取后无法重发
+func (rt *routeRuntime) replayable(req *http.Request) bool {
+	return (rt.proxy == nil && !rt.stream) || req.Body == nil || req.Body == http.NoBody
+}
+
+// requestTooLarge 判断错误是否由请求体超过大小限制引起
+func requestTooLarge(err error) bool {
+	var maxErr *http.MaxBytesError
+	return errors.As(err, &maxErr) || errors.Is(err, ipc.ErrPayloadTooLarge)
+}
+
+// writeBackendError 根据调用业务进程的错误类别返回响应
+// 熔断打开或无法连接时返回503，超时返回504，其他读写错误返回502
+func writeBackendError(w http.ResponseWriter, err error) {
+	var open *circuitOpenError
+	if errors.As(err, &open) {
+		seconds := int(math.Ceil(open.retryAfter.Seconds()))
+		if seconds < 1 {
+			seconds = 1
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		middleware.WriteError(w, http.StatusServiceUnavailable, "Circuit breaker open", nil)
+		return
+	}
+	switch resilience.Classify(err) {
+	case resilience.ErrorConnect:
+		middleware.WriteError(w, http.StatusServiceUnavailable, "Backend unavailable", nil)
+	case resilience.ErrorTimeout:
+		middleware.WriteError(w, http.StatusGatewayTimeout, "Backend timeout", nil)
+	default:
+		middleware.WriteError(w, http.StatusBadGateway, "Backend error", nil)
+	}
+}
@@ -0,0 +1,54 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// paramsKey 路径参数在请求上下文中的键
+type paramsKey struct{}
+
+// PathParams 返回路径模板匹配得到的参数，非模板路由返回nil
+func PathParams(ctx context.Context) map[string]string {
+	params, _ := ctx.Value(paramsKey{}).(map[string]string)
+	return params
+}
+
+// pathTemplate 带参数的路由路径，例如 /users/{id}
+type pathTemplate struct {
+	path     string
+	segments []string
+	handler  http.Handler
+}
+
+// isTemplate 判断路由路径是否包含 {name} 参数
+func isTemplate(path string) bool {
+	return strings.Contains(path, "{")
+}
+
+func newPathTemplate(path string, handler http.Handler) pathTemplate {
+	return pathTemplate{path: path, segments: strings.Split(strings.Trim(path, "/"), "/"), handler: handler}
+}
+
+// match 匹配请求路径，成功时返回参数
+func (t pathTemplate) match(path string) (map[string]string, bool) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) != len(t.segments) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for i, segment := range t.segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if segments[i] == "" {
+				return nil, false
+			}
+			params[segment[1:len(segment)-1]] = segments[i]
+			continue
+		}
+		if segment != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
@@ -0,0 +1,592 @@
+package router
+
+import (
+	"bigHammer/internal/accesslog"
+	"bigHammer/internal/attachment"
+	"bigHammer/internal/cache"
+	"bigHammer/internal/config"
+	"bigHammer/internal/jobs"
+	"bigHammer/internal/limits"
+	"bigHammer/internal/lifecycle"
+	"bigHammer/internal/middleware"
+	"bigHammer/internal/middleware/ipacl"
+	"bigHammer/internal/mirror"
+	"bigHammer/internal/resilience"
+	"bigHammer/internal/split"
+	"bigHammer/internal/sse"
+	"bigHammer/internal/transform"
+	"bigHammer/internal/upstream"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	// 注册内置的扩展中间件
+	_ "bigHammer/internal/middleware/ratelimit"
+)
+
+// Build 编译路由处理链
+// 功能：
+// 1. 展开路由分组，为每条路由创建 全局中间件 → 分组中间件 → 路由中间件 → 后端 的处理链，按主机名组织路由表
+// 2. 为异步路由创建任务状态查询处理链
+// 3. 为未匹配的请求创建仅包含全局中间件的404处理链
+// 4. 创建并注册 backends 中配置的后端
+// 5. 为 http 路由创建反向代理，按前缀匹配
+// 6. 版本化路由按 versioning 配置以 /<version><path> 注册，或在路由路径上按请求头/Accept 选择版本
+// 参数：无
+// 返回值：
+//   - error: 引用了未注册的中间件或中间件选项无效时返回的错误信息
+func (r *Router) Build() error {
+	for _, group := range r.Groups {
+		if err := validateGroup(group); err != nil {
+			return err
+		}
+	}
+	trusted, err := middleware.ParseCIDRs(r.TrustedProxies)
+	if err != nil {
+		return fmt.Errorf("trusted_proxies: %v", err)
+	}
+	forwarded, err := middleware.ParseForwardedHeader(r.ForwardedHeader)
+	if err != nil {
+		return fmt.Errorf("forwarded_header: %v", err)
+	}
+	if r.Limits != nil {
+		if err := r.Limits.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := r.Versioning.validate(); err != nil {
+		return err
+	}
+	pools, err := r.buildPools()
+	if err != nil {
+		return err
+	}
+	tables := newHostTables()
+	versions := newVersionSets()
+	jobHandlers := make(map[string]http.Handler)
+	// 编译成功后再登记缓存和熔断器，编译失败时管理接口仍看到旧路由使用的实例
+	var caches []*cache.Cache
+	var breakers []*resilience.Breaker
+	var jobStore *jobs.Store
+	for _, route := range r.AllRoutes() {
+		for _, host := range route.Hosts {
+			if err := validateHost(host); err != nil {
+				return fmt.Errorf("route %s: %v", route.Path, err)
+			}
+		}
+		// WebSocket路由由 websocket 服务在独立端口上处理
+		if route.WebSocket != nil {
+			continue
+		}
+		if route.Version = normalizeVersion(route.Version); route.Version != "" {
+			if err := validateVersion(route.Version); err != nil {
+				return fmt.Errorf("route %s: %v", route.Path, err)
+			}
+		}
+		if err := checkSystemPath(r.VersionedPath(route)); err != nil {
+			return fmt.Errorf("route %s: %v", route.Path, err)
+		}
+		if err := checkSystemPath(route.Path); err != nil {
+			return fmt.Errorf("route %s: %v", route.Path, err)
+		}
+		rt, err := compileRoute(route)
+		if err != nil {
+			return fmt.Errorf("route %s: %v", route.Path, err)
+		}
+		if route.Backend != "" {
+			if rt.pool = pools[route.Backend]; rt.pool == nil {
+				return fmt.Errorf("route %s: unknown backend %s", route.Path, route.Backend)
+			}
+		}
+		if route.Aggregate != nil {
+			if err := compileAggregate(rt, pools); err != nil {
+				return fmt.Errorf("route %s: %v", route.Path, err)
+			}
+		}
+		if route.Mirror != nil {
+			if err := compileMirror(rt, pools); err != nil {
+				return fmt.Errorf("route %s: %v", route.Path, err)
+			}
+		}
+		if route.Split != nil {
+			if err := compileVariants(rt, pools); err != nil {
+				return fmt.Errorf("route %s: %v", route.Path, err)
+			}
+		}
+		handler, err := r.BuildHandler(route, allowMethods(route.Methods, r.backend(rt)))
+		if err != nil {
+			return err
+		}
+		for _, v := range append([]*routeRuntime{rt}, rt.variants...) {
+			if v.cache != nil {
+				caches = append(caches, v.cache)
+			}
+			if v.breaker != nil {
+				breakers = append(breakers, v.breaker)
+			}
+		}
+
+		// 任务状态查询使用与创建任务相同的中间件（认证、限流等）
+		var status http.Handler
+		if rt.jobs != nil {
+			if status, err = r.BuildHandler(route, jobs.StatusHandler(rt.jobs)); err != nil {
+				return err
+			}
+			jobStore = rt.jobs
+		}
+		if err := r.addRoute(tables, versions, jobHandlers, route, handler, status, rt.proxy != nil); err != nil {
+			return err
+		}
+	}
+	if err := versions.register(tables, jobHandlers, r.Versioning); err != nil {
+		return err
+	}
+	tables.finish()
+
+	mws, err := r.middlewaresFor(Route{})
+	if err != nil {
+		return err
+	}
+	acl, err := r.aclFor(Route{})
+	if err != nil {
+		return err
+	}
+	if acl != nil {
+		mws = append([]middleware.Middleware{acl}, mws...)
+	}
+	r.notFound = middleware.Chain(http.HandlerFunc(http.NotFound), mws...)
+	r.acl = acl
+	r.tables = tables
+	r.jobHandlers = jobHandlers
+	r.jobStore = jobStore
+	// 路由编译成功后再注册后端，替换旧后端并启动健康检查，停止已从配置中删除的后端
+	registered := make([]*upstream.Pool, 0, len(pools))
+	for _, pool := range pools {
+		registered = append(registered, pool)
+	}
+	upstream.Register(registered)
+	cache.Register(caches)
+	resilience.RegisterBreakers(breakers)
+	middleware.SetTrustedProxies(trusted, forwarded)
+	return nil
+}
+
+// checkSystemPath 检查路由路径是否与网关自身的端点（指标、就绪和存活检查、OpenAPI文档）相同
+// 这些端点直接挂载在HTTP服务上，同名路由永远不会被匹配
+func checkSystemPath(path string) error {
+	if config.GlobalConfig == nil {
+		return nil
+	}
+	for _, system := range config.GlobalConfig.SystemPaths() {
+		if path == system {
+			return fmt.Errorf("path conflicts with gateway endpoint %s", system)
+		}
+	}
+	return nil
+}
+
+// ServeSystem 处理网关自身端点的请求，请求先经过全局IP访问控制
+// 参数：
+//   - handler http.Handler: 端点处理器
+func (r *Router) ServeSystem(w http.ResponseWriter, req *http.Request, handler http.Handler) {
+	if r.acl != nil {
+		handler = r.acl(handler)
+	}
+	handler.ServeHTTP(w, req)
+}
+
+// buildPools 根据 backends 配置创建后端
+func (r *Router) buildPools() (map[string]*upstream.Pool, error) {
+	pools := make(map[string]*upstream.Pool, len(r.Backends))
+	for name, opts := range r.Backends {
+		pool, err := upstream.NewPool(name, opts)
+		if err != nil {
+			return nil, err
+		}
+		pools[name] = pool
+	}
+	return pools, nil
+}
+
+// BuildHandler 为路由创建 IP访问控制 → 请求大小限制 → 全局中间件 → 路由中间件 → backend 的处理链
+// 供在其他端口上提供服务的路由类型（如WebSocket）复用中间件配置
+// 参数：
+//   - route Route: 路由配置
+//   - backend http.Handler: 最终处理请求的处理器
+// 返回值：
+//   - http.Handler: 处理链
+//   - error: 中间件未注册或选项无效时返回的错误信息
+func (r *Router) BuildHandler(route Route, backend http.Handler) (http.Handler, error) {
+	mws, err := r.middlewaresFor(route)
+	if err != nil {
+		return nil, fmt.Errorf("route %s: %v", route.Path, err)
+	}
+	acl, err := r.aclFor(route)
+	if err != nil {
+		return nil, fmt.Errorf("route %s: %v", route.Path, err)
+	}
+	if limit := limits.Middleware(limits.Merge(r.Limits, route.Limits)); limit != nil {
+		mws = append([]middleware.Middleware{limit}, mws...)
+	}
+	if acl != nil {
+		mws = append([]middleware.Middleware{acl}, mws...)
+	}
+	version, err := r.versionMiddleware(route)
+	if err != nil {
+		return nil, fmt.Errorf("route %s: %v", route.Path, err)
+	}
+	if version != nil {
+		mws = append([]middleware.Middleware{version}, mws...)
+	}
+	mws = append([]middleware.Middleware{middleware.Route(route.Path)}, mws...)
+	return middleware.Chain(backend, mws...), nil
+}
+
+// aclFor 返回路由的IP访问控制中间件，依次检查全局规则和路由规则，都未配置时为nil
+func (r *Router) aclFor(route Route) (middleware.Middleware, error) {
+	global, err := ipacl.Compile(r.ACL, "global")
+	if err != nil {
+		return nil, err
+	}
+	local, err := ipacl.Compile(route.ACL, "route")
+	if err != nil {
+		return nil, err
+	}
+	return ipacl.Middleware(global, local), nil
+}
+
+// MiddlewareConfig 返回路由实际使用的中间件选项（路由选项覆盖全局选项）
+// 参数：
+//   - route Route: 路由配置
+//   - name string: 中间件名称
+// 返回值：
+//   - map[string]interface{}: 中间件选项
+//   - bool: 路由是否使用该中间件
+func (r *Router) MiddlewareConfig(route Route, name string) (map[string]interface{}, bool) {
+	for _, used := range append(append([]string{}, r.Middlewares...), route.Middlewares...) {
+		if used != name {
+			continue
+		}
+		if override, ok := route.MiddlewareOptions[name]; ok {
+			return override, true
+		}
+		return r.MiddlewareOptions[name], true
+	}
+	return nil, false
+}
+
+// middlewaresFor 按顺序创建路由使用的中间件，重复的名称只保留第一次出现
+func (r *Router) middlewaresFor(route Route) ([]middleware.Middleware, error) {
+	seen := make(map[string]bool)
+	var mws []middleware.Middleware
+	for _, name := range append(append([]string{}, r.Middlewares...), route.Middlewares...) {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		options := r.MiddlewareOptions[name]
+		if override, ok := route.MiddlewareOptions[name]; ok {
+			options = override
+		}
+		mw, err := middleware.Build(name, options)
+		if err != nil {
+			return nil, err
+		}
+		mws = append(mws, mw)
+	}
+	return mws, nil
+}
+
+// compileRoute 编译路由配置中的运行时规则
+func compileRoute(route Route) (*routeRuntime, error) {
+	rt := &routeRuntime{route: route}
+	if route.Timeout < 0 {
+		return nil, fmt.Errorf("timeout must not be negative")
+	}
+	var err error
+	if rt.transformer, err = transform.Compile(route.Transform); err != nil {
+		return nil, fmt.Errorf("transform: %v", err)
+	}
+	if route.Files != nil {
+		if err := route.Files.Validate(); err != nil {
+			return nil, err
+		}
+		if rt.storage, err = attachment.DefaultStorage(); err != nil {
+			return nil, err
+		}
+	}
+	if route.Async != nil {
+		if rt.jobs, err = jobs.DefaultStore(); err != nil {
+			return nil, err
+		}
+	}
+	if route.Cache != nil {
+		if rt.cache, err = cache.New(routeName(route), *route.Cache); err != nil {
+			return nil, fmt.Errorf("cache: %v", err)
+		}
+	}
+	if route.Schema != nil {
+		if rt.validator, err = route.Schema.Compile(); err != nil {
+			return nil, err
+		}
+	}
+	if route.CircuitBreaker != nil {
+		rt.breaker = resilience.NewBreaker(routeName(route), *route.CircuitBreaker)
+	}
+	if route.Language == LanguageHTTP {
+		if err := compileProxy(rt); err != nil {
+			return nil, err
+		}
+	} else if route.Proxy != nil {
+		return nil, fmt.Errorf("proxy requires language http")
+	}
+	if route.Limits != nil {
+		if err := compileLimits(rt); err != nil {
+			return nil, err
+		}
+	}
+	return rt, nil
+}
+
+// compileLimits 校验路由的请求大小限制
+// 流式请求体不经过网关解析，因此不支持需要读取请求体的配置
+func compileLimits(rt *routeRuntime) error {
+	route := rt.route
+	if err := route.Limits.Validate(); err != nil {
+		return err
+	}
+	if !route.Limits.StreamBody {
+		return nil
+	}
+	var requestBody, template bool
+	if t := route.Transform; t != nil {
+		requestBody = t.Request != nil && t.Request.Body != nil
+		template = t.Response != nil && t.Response.Template != nil
+	}
+	for _, c := range []struct {
+		name string
+		set  bool
+	}{
+		{"language http", route.Language == LanguageHTTP},
+		{"files", route.Files != nil},
+		{"sse", route.SSE != nil},
+		{"async", route.Async != nil},
+		{"mirror", route.Mirror != nil},
+		{"schema", route.Schema != nil},
+		{"transform.request.body", requestBody},
+		{"transform.response.template", template},
+	} {
+		if c.set {
+			return fmt.Errorf("stream_body cannot be used with %s", c.name)
+		}
+	}
+	rt.stream = true
+	return nil
+}
+
+// compileMirror 创建路由的流量镜像器
+func compileMirror(rt *routeRuntime, pools map[string]*upstream.Pool) error {
+	opts := *rt.route.Mirror
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	var pool *upstream.Pool
+	if opts.Backend != "" {
+		if pool = pools[opts.Backend]; pool == nil {
+			return fmt.Errorf("mirror: unknown backend %s", opts.Backend)
+		}
+	}
+	rt.mirror = mirror.New(routeName(rt.route), rt.route.Command, opts, pool)
+	return nil
+}
+
+// compileVariants 为流量拆分的每个变体创建运行时状态
+// 变体复用路由的变换、任务等配置，只替换命令和后端，并使用独立的熔断器和响应缓存
+// （变体的响应不同，共用缓存会把一个变体的响应返回给分配到其他变体的客户端）
+func compileVariants(rt *routeRuntime, pools map[string]*upstream.Pool) error {
+	var err error
+	if rt.splitter, err = split.New(routeName(rt.route), *rt.route.Split); err != nil {
+		return err
+	}
+	rt.cache = nil
+	for _, v := range rt.route.Split.Variants {
+		variant := *rt
+		variant.splitter, variant.variants = nil, nil
+		if v.Command != "" {
+			variant.route.Command = v.Command
+		}
+		if v.Backend != "" {
+			variant.route.Backend = v.Backend
+			if variant.pool = pools[v.Backend]; variant.pool == nil {
+				return fmt.Errorf("variant %s: unknown backend %s", v.Name, v.Backend)
+			}
+		}
+		if rt.route.CircuitBreaker != nil {
+			variant.breaker = resilience.NewBreaker(routeName(rt.route)+"@"+v.Name, *rt.route.CircuitBreaker)
+		}
+		if rt.route.Cache != nil {
+			if variant.cache, err = cache.New(routeName(rt.route)+"@"+v.Name, *rt.route.Cache); err != nil {
+				return fmt.Errorf("variant %s: cache: %v", v.Name, err)
+			}
+		}
+		rt.variants = append(rt.variants, &variant)
+	}
+	return nil
+}
+
+// backend 返回将请求转发到业务进程的处理器
+// 文件路由由网关处理上传/下载，上传完成后再将元数据转发到业务进程（配置了 command 时）
+// SSE路由由网关直接推送订阅主题的事件，不经过业务进程
+// 配置了响应缓存时，缓存命中的请求不再转发到业务进程
+// 配置了流量拆分时，由所选变体的命令和后端处理请求，每个变体使用自己的缓存
+// http 路由将请求反向代理到上游服务
+// 聚合路由并行执行子调用并合并结果
+func (r *Router) backend(rt *routeRuntime) http.Handler {
+	var ipcHandler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.forwardIPC(w, req, rt)
+	})
+	if rt.proxy != nil {
+		ipcHandler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			r.forwardHTTP(w, req, rt)
+		})
+	}
+	if rt.aggregator != nil {
+		ipcHandler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			r.forwardAggregate(w, req, rt)
+		})
+	}
+	if rt.splitter != nil {
+		ipcHandler = r.splitHandler(rt)
+	}
+	if rt.cache != nil {
+		ipcHandler = cache.Handler(rt.cache, ipcHandler)
+	}
+	if rt.route.SSE != nil {
+		return sse.Handler(sse.DefaultBroker, *rt.route.SSE)
+	}
+	if rt.storage == nil {
+		return ipcHandler
+	}
+	if rt.route.Files.Mode == "download" {
+		return attachment.DownloadHandler(rt.storage, *rt.route.Files)
+	}
+	if rt.route.Command == "" {
+		return attachment.UploadHandler(rt.storage, *rt.route.Files, nil)
+	}
+	return attachment.UploadHandler(rt.storage, *rt.route.Files, ipcHandler)
+}
+
+// allowMethods 限制路由允许的HTTP方法，methods 为空时不限制
+func allowMethods(methods []string, next http.Handler) http.Handler {
+	if len(methods) == 0 {
+		return next
+	}
+	allowed := make(map[string]bool, len(methods))
+	for _, method := range methods {
+		allowed[strings.ToUpper(method)] = true
+	}
+	allow := strings.ToUpper(strings.Join(methods, ", "))
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !allowed[req.Method] {
+			w.Header().Set("Allow", allow)
+			middleware.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// splitHandler 为请求选择变体，在响应头中标记变体并记录变体指标
+// 变体缓存命中的请求同样计入变体指标
+func (r *Router) splitHandler(rt *routeRuntime) http.Handler {
+	handlers := make([]http.Handler, len(rt.variants))
+	for i, variant := range rt.variants {
+		variant := variant
+		handlers[i] = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			r.forwardIPC(w, req, variant)
+		})
+		if variant.cache != nil {
+			handlers[i] = cache.Handler(variant.cache, handlers[i])
+		}
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		i := rt.splitter.Pick(req)
+		w.Header().Set(rt.splitter.Header(), rt.route.Split.Variants[i].Name)
+		accesslog.FromContext(req.Context()).SetVariant(rt.route.Split.Variants[i].Name)
+		start := time.Now()
+		rec := middleware.NewStatusRecorder(w)
+		handlers[i].ServeHTTP(rec, req)
+		rt.splitter.Observe(i, rec.Status, time.Since(start))
+	})
+}
+
+// HandleHTTP HTTP请求入口
+// 功能：
+// 1. 为请求分配 X-Request-ID（复用客户端传入的合法ID），并在响应头中返回
+// 2. 按请求的 Host 选择路由表（精确主机名 → 通配主机名 → 未配置主机名），根据请求路径查找编译后的处理链
+// 3. /jobs/{id} 交由创建该任务的路由的状态查询处理链
+// 4. 按配置顺序匹配带参数的路由路径，参数存入请求上下文
+// 5. 按最长前缀匹配 http 路由
+// 6. 未匹配时交由全局中间件包装的404处理器
+// 7. 请求结束后输出一条访问日志
+// 参数：
+//   - w http.ResponseWriter: 响应写入器
+//   - req *http.Request: HTTP请求
+// 返回值：无
+func (r *Router) HandleHTTP(w http.ResponseWriter, req *http.Request) {
+	defer lifecycle.Track(lifecycle.KindHTTP)()
+	req = middleware.AssignRequestID(w, req, middleware.RequestIDHeader)
+	entry, req := accesslog.Begin(req)
+	rec := middleware.NewStatusRecorder(w)
+	defer func() {
+		entry.Finish(rec.Status, rec.Bytes)
+		accesslog.Log(entry)
+	}()
+
+	if r.tables == nil {
+		log.Println("路由处理链尚未编译，请先调用 Build")
+		http.Error(rec, "内部服务器错误", http.StatusInternalServerError)
+		return
+	}
+	tables := r.tables.candidates(req.Host)
+	for _, t := range tables {
+		if handler, ok := t.handlers[req.URL.Path]; ok {
+			entry.SetRoute(req.URL.Path)
+			handler.ServeHTTP(rec, req)
+			return
+		}
+	}
+	if route, handler := r.jobHandler(req.URL.Path); handler != nil {
+		entry.SetRoute(route)
+		handler.ServeHTTP(rec, req)
+		return
+	}
+	for _, t := range tables {
+		if route, handler, params := t.match(req.URL.Path); handler != nil {
+			entry.SetRoute(route)
+			if params != nil {
+				req = req.WithContext(context.WithValue(req.Context(), paramsKey{}, params))
+			}
+			handler.ServeHTTP(rec, req)
+			return
+		}
+	}
+	r.notFound.ServeHTTP(rec, req)
+}
+
+// jobHandler 返回任务状态查询路径对应的路由和处理链，任务不存在时返回nil
+func (r *Router) jobHandler(path string) (string, http.Handler) {
+	id := jobs.IDFromPath(path)
+	if id == "" || r.jobStore == nil {
+		return "", nil
+	}
+	job, err := r.jobStore.Get(id)
+	if err != nil {
+		return "", nil
+	}
+	return job.Route, r.jobHandlers[job.Route]
+}
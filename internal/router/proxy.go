@@ -0,0 +1,98 @@
+package router
+
+import (
+	"bigHammer/internal/accesslog"
+	"bigHammer/internal/limits"
+	"bigHammer/internal/middleware"
+	"bigHammer/internal/proxy"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// prefixRoute 按前缀匹配的路由，匹配路径本身及其所有子路径
+type prefixRoute struct {
+	path    string
+	handler http.Handler
+}
+
+// match 判断请求路径是否位于路由路径之下
+func (p prefixRoute) match(path string) bool {
+	prefix := strings.TrimSuffix(p.path, "/")
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// sortPrefixes 按路径长度从长到短排序，使更具体的路由优先匹配
+func sortPrefixes(prefixes []prefixRoute) {
+	sort.SliceStable(prefixes, func(i, j int) bool {
+		return len(prefixes[i].path) > len(prefixes[j].path)
+	})
+}
+
+// compileProxy 校验 http 路由配置并创建反向代理
+// 请求体和响应体以流的方式转发，因此不支持需要解析请求体或缓冲响应体的配置
+func compileProxy(rt *routeRuntime) error {
+	route := rt.route
+	if route.Proxy == nil {
+		return fmt.Errorf("language http requires proxy")
+	}
+	if isTemplate(route.Path) {
+		return fmt.Errorf("http routes match by prefix and cannot use path parameters")
+	}
+	var requestBody, responseBody, template bool
+	if t := route.Transform; t != nil {
+		requestBody = t.Request != nil && t.Request.Body != nil
+		responseBody = t.Response != nil && t.Response.Body != nil
+		template = t.Response != nil && t.Response.Template != nil
+	}
+	for _, c := range []struct {
+		name string
+		set  bool
+	}{
+		{"files", route.Files != nil},
+		{"sse", route.SSE != nil},
+		{"async", route.Async != nil},
+		{"split", route.Split != nil},
+		{"mirror", route.Mirror != nil},
+		{"schema", route.Schema != nil},
+		{"transform.request.body", requestBody},
+		{"transform.response.body", responseBody},
+		{"transform.response.template", template},
+	} {
+		if c.set {
+			return fmt.Errorf("%s is not supported for http routes", c.name)
+		}
+	}
+	p, err := proxy.New(*route.Proxy, rt.transformer)
+	if err != nil {
+		return fmt.Errorf("proxy: %v", err)
+	}
+	rt.proxy = p
+	return nil
+}
+
+// forwardHTTP 将请求反向代理到路由配置的HTTP上游服务
+// 与IPC路由一样使用熔断、重试和统一的错误响应，并记录到访问日志
+func (r *Router) forwardHTTP(w http.ResponseWriter, req *http.Request, rt *routeRuntime) {
+	entry := accesslog.FromContext(req.Context())
+	entry.SetBackend(rt.backendName())
+	rec := middleware.NewStatusRecorder(w)
+	err := rt.invoke(req, func(string) error {
+		return rt.proxy.Forward(rec, req)
+	})
+	if err != nil {
+		log.Printf("HTTP反向代理失败 [%s]: %v", middleware.RequestIDFromContext(req.Context()), err)
+		entry.SetError(err)
+		// 协议升级后连接已被接管，不能再写入错误响应
+		if rec.WroteHeader() {
+			return
+		}
+		if requestTooLarge(err) {
+			limits.RejectBody(rec, req, err)
+			return
+		}
+		writeBackendError(rec, err)
+	}
+}
@@ -1,9 +1,16 @@
 package router
 
 import (
+	"bigHammer/internal/accesslog"
 	"bigHammer/internal/config"
 	ipc "bigHammer/internal/ipc/socket"
-	"bigHammer/pkg/utils"
+	"bigHammer/internal/jobs"
+	"bigHammer/internal/limits"
+	"bigHammer/internal/middleware"
+	"bigHammer/internal/middleware/auth"
+	"bigHammer/internal/mirror"
+	"bigHammer/internal/transform"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -12,9 +19,12 @@ import (
 	"time"
 )
 
-func (r *Router) HandleHTTP(w http.ResponseWriter, req *http.Request) {
-	log.Printf("开始处理请求: %s %s", req.Method, req.URL.Path)
-	requestStartTime := time.Now()
+// forwardIPC 将请求组装为 requestData 并通过IPC转发到业务进程
+// 请求ID写入IPC请求的 request_id 字段，请求处理情况由 HandleHTTP 统一记录到访问日志
+func (r *Router) forwardIPC(w http.ResponseWriter, req *http.Request, rt *routeRuntime) {
+	requestID := middleware.RequestIDFromContext(req.Context())
+	entry := accesslog.FromContext(req.Context())
+	entry.SetBackend(rt.backendName())
 
 	// 加载配置
 	err := config.LoadConfig()
@@ -22,21 +32,28 @@ func (r *Router) HandleHTTP(w http.ResponseWriter, req *http.Request) {
 		fmt.Println("Error loading config:", err)
 		return
 	}
-	log.Println("进入请求")
-	// 读取请求体
-	bodyBytes, err := io.ReadAll(req.Body)
-	if err != nil {
-		log.Println("Error reading request body:", err)
-		http.Error(w, "内部服务器错误", http.StatusInternalServerError)
-		return
+	// 读取请求体：开启 stream_body 时在发送IPC请求时流式读取，
+	// 否则读入内存，大小不能超过IPC单条消息的上限
+	var bodyBytes []byte
+	if !rt.stream {
+		bodyBytes, err = io.ReadAll(http.MaxBytesReader(w, req.Body, ipc.MaxPayloadSize))
+		if requestTooLarge(err) {
+			limits.RejectBody(w, req, err)
+			return
+		}
+		if err != nil {
+			log.Println("Error reading request body:", err)
+			http.Error(w, "内部服务器错误", http.StatusInternalServerError)
+			return
+		}
 	}
 	defer req.Body.Close()
 
 	// 获取请求时间戳
 	requestTimestamp := time.Now()
 
-	// 获取客户端的 IP 地址
-	clientIP := req.RemoteAddr
+	// 获取客户端的 IP 地址（经受信任代理解析，与访问控制和访问日志一致）
+	clientIP := middleware.ClientIP(req)
 
 	// 获取Host和URI
 	host := req.Host
@@ -51,9 +68,10 @@ func (r *Router) HandleHTTP(w http.ResponseWriter, req *http.Request) {
 
 	// 尝试解析请求体为JSON
 	var bodyData interface{}
+	var bodyErr error
 	if len(bodyBytes) > 0 {
 		// 尝试解析为JSON
-		if err := json.Unmarshal(bodyBytes, &bodyData); err != nil {
+		if bodyErr = json.Unmarshal(bodyBytes, &bodyData); bodyErr != nil {
 			// 解析失败，作为普通字符串处理
 			bodyData = string(bodyBytes)
 		}
@@ -61,9 +79,40 @@ func (r *Router) HandleHTTP(w http.ResponseWriter, req *http.Request) {
 		bodyData = nil
 	}
 
+	// 按路由配置的 JSON Schema 校验请求，不通过时直接返回400，不调用业务进程
+	pathParams := PathParams(req.Context())
+	if rt.validator != nil {
+		if errs := rt.validator.Validate(bodyData, bodyErr, req.URL.Query(), pathParams); len(errs) > 0 {
+			middleware.WriteError(w, http.StatusBadRequest, "Request validation failed", map[string]interface{}{"errors": errs})
+			return
+		}
+	}
+
+	// 执行路由配置的请求变换（请求头、查询参数、请求体）
+	headers := req.Header.Clone()
+	if rt.transformer != nil {
+		query := req.URL.Query()
+		bodyData = rt.transformer.TransformRequest(headers, query, bodyData)
+		if rt.transformer.ModifiesQuery() {
+			req.URL.RawQuery = query.Encode()
+			uri = req.URL.RequestURI()
+			fullURL = fmt.Sprintf("%s://%s%s", scheme, host, uri)
+		}
+
+		// 静态响应模板直接由网关返回，不调用业务进程
+		if rt.transformer.HasTemplate() {
+			data := transform.TemplateData(headers, req.URL.Query(), bodyData, req.URL.Path)
+			if err := rt.transformer.RenderTemplate(w, data); err != nil {
+				log.Println("渲染响应模板失败:", err)
+				http.Error(w, "内部服务器错误", http.StatusInternalServerError)
+			}
+			return
+		}
+	}
+
 	// 将请求信息组装到map中
 	requestData := map[string]interface{}{
-		"headers":   req.Header,
+		"headers":   headers,
 		"body":      bodyData, // 这里存储解析后的JSON对象或原始字符串
 		"route":     req.URL.Path,
 		"timestamp": requestTimestamp.Format(time.RFC3339Nano),
@@ -71,44 +120,107 @@ func (r *Router) HandleHTTP(w http.ResponseWriter, req *http.Request) {
 		"host":      host,
 		"uri":       uri,
 		"url":       fullURL,
+		// 网关认证通过的调用方身份，未认证时为null
+		"auth": auth.IdentityFromContext(req.Context()),
+	}
+	if pathParams != nil {
+		requestData["path_params"] = pathParams
+	}
+	// 流式请求体在请求之后以数据块（0x07）发送，body 为null
+	if rt.stream {
+		requestData["body_stream"] = true
+		requestData["content_length"] = req.ContentLength
 	}
 
-	// 获取请求路径
-	path := req.URL.Path
+	// 异步路由：创建任务后立即返回202，结果通过 /jobs/{id} 查询或回调通知
+	if rt.jobs != nil {
+		submitJob(w, req, rt, requestData)
+		return
+	}
 
-	// 查找路由表中的对应配置
-	var route Route
-	for _, r := range r.Routes {
-		if r.Path == path {
-			route = r
-			break
-		}
+	// 按比例将请求副本发送到影子后端，不等待其响应
+	var shadow *mirror.Shadow
+	if rt.mirror != nil {
+		shadow = rt.mirror.Start(requestID, requestData)
 	}
 
-	// 如果未找到路由，则返回404
-	if route.Path == "" {
-		http.NotFound(w, req)
+	// 执行Socket通信（按路由配置熔断和重试）
+	var output []byte
+	err = rt.invoke(req, func(endpoint string) error {
+		// 每次尝试单独计时，客户端断开时同时中断与业务进程的通信
+		ctx, cancel := context.WithTimeout(req.Context(), rt.timeout())
+		defer cancel()
+		var err error
+		if rt.stream {
+			output, err = ipc.TransmitStreamIPC(ctx, requestID, rt.route.Command, requestData, req.Body, endpoint)
+			return err
+		}
+		output, err = ipc.TransmitIPCContext(ctx, requestID, rt.route.Command, requestData, endpoint)
+		return err
+	})
+	shadow.Finish(output, err)
+	if requestTooLarge(err) {
+		limits.RejectBody(w, req, err)
 		return
 	}
-
-	// 执行Socket通信
-	configFilePath, _ := utils.ResolvePath(config.GlobalConfig.BussinessSocketPath)
-	output, _, err := ipc.TransmitIPC(false, route.Command, requestData, configFilePath)
 	if err != nil {
-		log.Println("执行Socket通信失败:", err)
-		http.Error(w, "内部服务器错误", http.StatusInternalServerError)
+		log.Printf("执行Socket通信失败 [%s]: %v", requestID, err)
+		entry.SetError(err)
+		writeBackendError(w, err)
 		return
 	}
 
-	// 计算请求处理时间
-	requestProcessTime := time.Since(requestStartTime)
+	// 执行路由配置的响应变换
+	w.Header().Set("Content-Type", "text/plain")
+	output, transformed := rt.transformer.TransformResponse(w.Header(), output)
+	if transformed {
+		w.Header().Set("Content-Type", "application/json")
+	}
 
 	// 返回输出结果给客户端
-	w.Header().Set("Content-Type", "text/plain")
 	w.Write(output)
+}
 
-	// 打印请求处理时间
-	log.Printf("请求处理时间: %s", requestProcessTime)
+// submitJob 为异步路由创建任务并返回202
+// 调用方可通过 X-Callback-URL 请求头指定任务完成后的通知地址
+func submitJob(w http.ResponseWriter, req *http.Request, rt *routeRuntime, requestData map[string]interface{}) {
+	callbackURL := req.Header.Get("X-Callback-URL")
+	if callbackURL != "" {
+		if err := rt.route.Async.ValidateCallback(callbackURL); err != nil {
+			middleware.WriteError(w, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+	}
+	owner := ""
+	if identity := auth.IdentityFromContext(req.Context()); identity != nil {
+		owner = identity.Subject
+	}
+
+	requestID := middleware.RequestIDFromContext(req.Context())
+	var job *jobs.Job
+	err := rt.invoke(req, func(endpoint string) error {
+		var err error
+		job, err = jobs.Submit(rt.jobs, *rt.route.Async, req.URL.Path, rt.route.Command, requestData, endpoint, owner, callbackURL, requestID)
+		return err
+	})
+	if err != nil {
+		log.Printf("提交异步任务失败 [%s]: %v", requestID, err)
+		accesslog.FromContext(req.Context()).SetError(err)
+		writeBackendError(w, err)
+		return
+	}
 
-	log.Printf("结束处理请求: %s %s", req.Method, req.URL.Path)
+	statusURL := jobs.StatusURL(job.ID)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Location", statusURL)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  http.StatusAccepted,
+		"message": "Accepted",
+		"data": map[string]string{
+			"job_id":     job.ID,
+			"status":     job.Status,
+			"status_url": statusURL,
+		},
+	})
 }
@@ -1,23 +1,184 @@
 package router
 
 import (
+	"bigHammer/internal/aggregate"
+	"bigHammer/internal/attachment"
+	"bigHammer/internal/cache"
 	"bigHammer/internal/config"
 	"bigHammer/internal/interface/database"
+	"bigHammer/internal/jobs"
+	"bigHammer/internal/limits"
+	"bigHammer/internal/middleware"
+	"bigHammer/internal/middleware/ipacl"
+	"bigHammer/internal/mirror"
+	"bigHammer/internal/proxy"
+	"bigHammer/internal/resilience"
+	"bigHammer/internal/schema"
+	"bigHammer/internal/split"
+	"bigHammer/internal/sse"
+	"bigHammer/internal/transform"
+	"bigHammer/internal/upstream"
 	"bigHammer/pkg/utils"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 )
 
 type Route struct {
-	Path     string `json:"path"`
+	Path string `json:"path"`
+	// Language 业务进程语言；为 http 时将请求反向代理到 proxy 配置的上游服务，
+	// 并匹配该路径及其所有子路径（最长前缀优先）
 	Language string `json:"language"`
 	Command  string `json:"command"`
+	// Hosts 路由匹配的主机名，支持 *.example.com 通配，为空时匹配所有主机
+	Hosts []string `json:"hosts,omitempty"`
+	// Group 路由所属分组名称，由 groups 展开时填写
+	Group string `json:"group,omitempty"`
+	// Methods 允许的HTTP方法，为空时不限制；配置后其他方法返回405
+	Methods []string `json:"methods,omitempty"`
+	// Summary 路由简介，用于生成 OpenAPI 文档
+	Summary string `json:"summary,omitempty"`
+	// Description 路由详细说明，用于生成 OpenAPI 文档
+	Description string `json:"description,omitempty"`
+	// Tags OpenAPI 文档中的分组标签
+	Tags []string `json:"tags,omitempty"`
+	// Middlewares 路由专属中间件名称，追加在全局中间件之后执行
+	Middlewares []string `json:"middlewares,omitempty"`
+	// MiddlewareOptions 路由级中间件选项，覆盖同名的全局选项
+	MiddlewareOptions map[string]map[string]interface{} `json:"middleware_options,omitempty"`
+	// Transform 请求/响应声明式变换规则
+	Transform *transform.Rules `json:"transform,omitempty"`
+	// Files 文件上传/下载路由配置，文件由网关直接存储到 attachment_storage
+	Files *attachment.Options `json:"files,omitempty"`
+	// WebSocket WebSocket路由配置，配置后该路由只在 websocket_port 上提供服务
+	WebSocket *WebSocketOptions `json:"websocket,omitempty"`
+	// SSE Server-Sent Events 订阅路由配置，事件由业务进程或插件发布到主题
+	SSE *sse.Options `json:"sse,omitempty"`
+	// Async 异步路由配置，请求通过异步IPC发送并立即返回202和任务ID
+	Async *jobs.Options `json:"async,omitempty"`
+	// Cache 响应缓存配置
+	Cache *cache.Options `json:"cache,omitempty"`
+	// Backend 处理请求的后端名称（对应 backends 中的配置），为空时使用 bussiness_socket_path
+	Backend string `json:"backend,omitempty"`
+	// Retry 调用业务进程失败时的重试策略
+	Retry *resilience.RetryOptions `json:"retry,omitempty"`
+	// Timeout 每次调用业务进程等待响应的超时秒数，默认30；超时返回504并计入熔断统计
+	// http 路由使用 proxy 的 timeout
+	Timeout int `json:"timeout,omitempty"`
+	// CircuitBreaker 熔断器配置
+	CircuitBreaker *resilience.BreakerOptions `json:"circuit_breaker,omitempty"`
+	// Split 按权重或匹配规则将流量拆分到多个命令/后端（灰度发布）
+	Split *split.Options `json:"split,omitempty"`
+	// Mirror 将请求副本异步发送到另一个命令/后端（影子流量），丢弃其响应
+	Mirror *mirror.Options `json:"mirror,omitempty"`
+	// Schema 请求体、查询参数和路径参数的 JSON Schema 校验配置
+	Schema *schema.Options `json:"schema,omitempty"`
+	// Proxy HTTP上游服务配置，仅用于 language 为 http 的路由
+	Proxy *proxy.Options `json:"proxy,omitempty"`
+	// ACL 路由IP访问控制规则，在全局规则之后检查
+	ACL *ipacl.Rules `json:"acl,omitempty"`
+	// Limits 路由请求大小限制，覆盖全局限制；开启 stream_body 时请求体流式发送到业务进程
+	Limits *limits.Options `json:"limits,omitempty"`
+	// Aggregate 聚合路由配置，并行调用多个IPC命令、插件或HTTP服务并合并为一个JSON响应
+	Aggregate *aggregate.Options `json:"aggregate,omitempty"`
+	// Version 路由的API版本，例如 v2；同一路径可以配置多个版本，按 versioning 配置的方式选择
+	Version string `json:"version,omitempty"`
+	// Deprecation 路由弃用信息，配置后响应中添加 Deprecation、Sunset 和 Link 头
+	Deprecation *Deprecation `json:"deprecation,omitempty"`
+}
+
+// LanguageHTTP 反向代理到HTTP上游服务的路由语言
+const LanguageHTTP = "http"
+
+// WebSocketOptions WebSocket路由配置
+// 连接的 connect、message、close 事件通过IPC转发到路由的 command
+type WebSocketOptions struct {
+	// MaxConnections 路由最大连接数，0表示不限制
+	MaxConnections int `json:"max_connections,omitempty"`
+	// MaxConnectionsPerIP 单个客户端IP最大连接数，0表示不限制
+	MaxConnectionsPerIP int `json:"max_connections_per_ip,omitempty"`
+	// MaxMessageSize 单条消息最大字节数，默认64KB
+	MaxMessageSize int64 `json:"max_message_size,omitempty"`
+	// MessagesPerSecond 单个连接每秒最多消息数，0表示不限制
+	MessagesPerSecond int `json:"messages_per_second,omitempty"`
+	// PingInterval 服务端发送ping的间隔秒数，默认30
+	PingInterval int `json:"ping_interval,omitempty"`
+	// PongTimeout 等待pong的超时秒数，默认10
+	PongTimeout int `json:"pong_timeout,omitempty"`
+	// SendQueue 单个连接发送队列长度，默认64
+	SendQueue int `json:"send_queue,omitempty"`
+	// AllowedOrigins 允许发起连接的页面来源，例如 https://app.example.com，"*" 表示任意来源
+	// 为空时只允许与请求 Host 相同的来源；不带 Origin 的非浏览器客户端不受限制
+	AllowedOrigins []string `json:"allowed_origins,omitempty"`
+}
+
+// routeRuntime 路由运行时状态，由 Build 根据路由配置编译
+type routeRuntime struct {
+	route Route
+	// transformer 编译后的变换规则，未配置时为nil
+	transformer *transform.Transformer
+	// storage 文件路由使用的附件存储，非文件路由为nil
+	storage *attachment.Storage
+	// jobs 异步路由使用的任务存储，非异步路由为nil
+	jobs *jobs.Store
+	// cache 路由响应缓存，未配置时为nil
+	cache *cache.Cache
+	// pool 路由使用的后端，使用默认业务Socket时为nil
+	pool *upstream.Pool
+	// breaker 路由熔断器，未配置时为nil
+	breaker *resilience.Breaker
+	// splitter 流量拆分器，未配置时为nil
+	splitter *split.Splitter
+	// variants 各变体的运行时状态，与 Split.Variants 一一对应
+	variants []*routeRuntime
+	// mirror 流量镜像器，未配置时为nil
+	mirror *mirror.Mirror
+	// validator 请求校验器，未配置时为nil
+	validator *schema.RequestValidator
+	// proxy HTTP反向代理，非 http 路由为nil
+	proxy *proxy.Proxy
+	// stream 是否将请求体流式发送到业务进程
+	stream bool
+	// aggregator 聚合路由的聚合器，非聚合路由为nil
+	aggregator *aggregate.Aggregator
 }
 
 type Router struct {
 	Routes []Route `json:"routes"`
-	DB     database.IDatabase
+	// Groups 路由分组，分组内的路由共享主机名、路径前缀、中间件和后端
+	Groups []RouteGroup `json:"groups,omitempty"`
+	// Middlewares 全局默认中间件名称，作用于所有请求（包括未匹配路由的请求）
+	Middlewares []string `json:"middlewares"`
+	// MiddlewareOptions 全局中间件选项
+	// key: 中间件名称
+	// value: 传给中间件工厂的选项
+	MiddlewareOptions map[string]map[string]interface{} `json:"middleware_options"`
+	// Backends 由多个 worker 端点组成的后端
+	// key: 后端名称，路由通过 backend 字段引用
+	Backends map[string]upstream.Options `json:"backends"`
+	// ACL 全局IP访问控制规则，作用于所有请求（包括未匹配路由的请求）
+	ACL *ipacl.Rules `json:"acl,omitempty"`
+	// Limits 全局请求大小限制，路由可以覆盖
+	Limits *limits.Options `json:"limits,omitempty"`
+	// TrustedProxies 受信任的反向代理网段，来自这些地址的请求按 forwarded_header 解析客户端IP
+	TrustedProxies []string `json:"trusted_proxies,omitempty"`
+	// ForwardedHeader 受信任代理写入客户端地址的请求头：x-forwarded-for（默认）或 forwarded
+	ForwardedHeader string `json:"forwarded_header,omitempty"`
+	// Versioning API版本选择方式，未配置时启用路径前缀、请求头和 Accept 媒体类型三种方式
+	Versioning *VersioningOptions `json:"versioning,omitempty"`
+	DB                database.IDatabase
+	// tables 编译后按主机名组织的路由表，由 Build 生成
+	tables *hostTables
+	// notFound 未匹配路由时使用的处理链
+	notFound http.Handler
+	// acl 全局IP访问控制中间件，未配置时为nil
+	acl middleware.Middleware
+	// jobStore 任务存储，没有异步路由时为nil
+	jobStore *jobs.Store
+	// jobHandlers 任务状态查询处理链，使用创建任务的路由的中间件
+	// key: 路由路径
+	jobHandlers map[string]http.Handler
 }
 
 func NewRouter(db database.IDatabase) *Router {
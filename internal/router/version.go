@@ -0,0 +1,408 @@
+package router
+
+import (
+	"bigHammer/internal/accesslog"
+	"bigHammer/internal/middleware"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// 版本选择方式
+const (
+	// VersionByPath 按路径前缀选择版本，例如 /v2/users
+	VersionByPath = "path"
+	// VersionByHeader 按请求头选择版本，例如 API-Version: 2
+	VersionByHeader = "header"
+	// VersionByMediaType 按 Accept 媒体类型选择版本，例如 application/vnd.bighammer.v2+json 或 application/json; version=2
+	VersionByMediaType = "media_type"
+)
+
+var versionRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "gateway_api_version_requests_total",
+	Help: "各API版本的请求次数，deprecated 表示该版本的路由是否已弃用",
+}, []string{"route", "version", "deprecated"})
+
+// VersioningOptions API版本配置，对应 router.json 中的 versioning
+type VersioningOptions struct {
+	// Sources 版本选择方式：path、header、media_type，默认全部启用
+	// 启用 path 时版本化路由同时以 /<version><path> 提供服务；
+	// 启用 header 或 media_type 时，未带版本前缀的路径按请求中的版本选择路由
+	Sources []string `json:"sources,omitempty"`
+	// Header 指定版本的请求头，默认 API-Version；响应中同名响应头返回实际使用的版本
+	Header string `json:"header,omitempty"`
+	// Vendor Accept 媒体类型中的厂商名，例如 bighammer 匹配 application/vnd.bighammer.v2+json，默认 bighammer
+	Vendor string `json:"vendor,omitempty"`
+	// Default 请求未指定版本时使用的版本，为空时使用最新版本
+	Default string `json:"default,omitempty"`
+}
+
+// Deprecation 路由弃用信息，配置后响应中添加 Deprecation/Sunset/Link 头
+type Deprecation struct {
+	// Date 弃用日期（2006-01-02 或 RFC3339），为空时 Deprecation 头为 true
+	Date string `json:"date,omitempty"`
+	// Sunset 计划下线日期（2006-01-02 或 RFC3339）
+	Sunset string `json:"sunset,omitempty"`
+	// Link 迁移说明文档地址
+	Link string `json:"link,omitempty"`
+}
+
+func (o *VersioningOptions) enabled(source string) bool {
+	if o == nil || len(o.Sources) == 0 {
+		return true
+	}
+	for _, s := range o.Sources {
+		if s == source {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *VersioningOptions) header() string {
+	if o == nil || o.Header == "" {
+		return "API-Version"
+	}
+	return o.Header
+}
+
+func (o *VersioningOptions) vendor() string {
+	if o == nil || o.Vendor == "" {
+		return "bighammer"
+	}
+	return o.Vendor
+}
+
+// validate 校验版本配置
+func (o *VersioningOptions) validate() error {
+	if o == nil {
+		return nil
+	}
+	for _, s := range o.Sources {
+		if s != VersionByPath && s != VersionByHeader && s != VersionByMediaType {
+			return fmt.Errorf("versioning: unknown source %q", s)
+		}
+	}
+	if o.Default != "" {
+		if err := validateVersion(normalizeVersion(o.Default)); err != nil {
+			return fmt.Errorf("versioning: %v", err)
+		}
+	}
+	return nil
+}
+
+// normalizeVersion 统一版本号写法：2、V2、v2 → v2
+func normalizeVersion(version string) string {
+	version = strings.ToLower(strings.TrimSpace(version))
+	if version == "" {
+		return ""
+	}
+	if !strings.HasPrefix(version, "v") {
+		version = "v" + version
+	}
+	return version
+}
+
+// validateVersion 校验路由版本号，版本号用作路径前缀，只能包含字母、数字、点和连字符
+func validateVersion(version string) error {
+	for _, c := range strings.TrimPrefix(version, "v") {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c == '.' || c == '-') {
+			return fmt.Errorf("invalid version %q", version)
+		}
+	}
+	if version == "v" {
+		return fmt.Errorf("invalid version %q", version)
+	}
+	return nil
+}
+
+// versionLess 按数字比较版本号（v1 < v2 < v10 < v10.1），无法按数字比较时按字符串比较
+func versionLess(a, b string) bool {
+	pa := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	pb := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		na, errA := strconv.Atoi(pa[i])
+		nb, errB := strconv.Atoi(pb[i])
+		if errA != nil || errB != nil {
+			if pa[i] != pb[i] {
+				return pa[i] < pb[i]
+			}
+			continue
+		}
+		if na != nb {
+			return na < nb
+		}
+	}
+	return len(pa) < len(pb)
+}
+
+// VersionedPath 返回版本化路由按路径前缀访问的路径，例如 /v2/users；未配置版本或未启用 path 方式时返回路由路径
+func (r *Router) VersionedPath(route Route) string {
+	if route.Version == "" || !r.Versioning.enabled(VersionByPath) {
+		return route.Path
+	}
+	return "/" + normalizeVersion(route.Version) + route.Path
+}
+
+// routeName 返回路由的唯一名称，用于缓存、熔断器等按路由区分的组件
+// 配置了主机名的路由以主机名开头，同一路径的多个版本名称不同，例如 api.example.com/v2/users
+func routeName(route Route) string {
+	name := route.Path
+	if route.Version != "" {
+		name = "/" + normalizeVersion(route.Version) + route.Path
+	}
+	return strings.Join(route.Hosts, ",") + name
+}
+
+// parseDate 解析弃用和下线日期
+func parseDate(value string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// versionHeaders 根据路由弃用信息生成响应头
+func versionHeaders(d *Deprecation) (http.Header, error) {
+	h := make(http.Header)
+	if d == nil {
+		return h, nil
+	}
+	// Deprecation 头使用 RFC 9745 的结构化日期格式（@Unix时间戳）
+	h.Set("Deprecation", "true")
+	if d.Date != "" {
+		t, err := parseDate(d.Date)
+		if err != nil {
+			return nil, fmt.Errorf("deprecation: invalid date %q", d.Date)
+		}
+		h.Set("Deprecation", "@"+strconv.FormatInt(t.Unix(), 10))
+	}
+	if d.Sunset != "" {
+		t, err := parseDate(d.Sunset)
+		if err != nil {
+			return nil, fmt.Errorf("deprecation: invalid sunset %q", d.Sunset)
+		}
+		h.Set("Sunset", t.UTC().Format(http.TimeFormat))
+	}
+	if d.Link != "" {
+		h.Add("Link", fmt.Sprintf("<%s>; rel=\"deprecation\"; type=\"text/html\"", d.Link))
+	}
+	return h, nil
+}
+
+// versionMiddleware 为版本化或已弃用的路由添加版本响应头、弃用响应头，并记录版本使用指标
+func (r *Router) versionMiddleware(route Route) (middleware.Middleware, error) {
+	if route.Version == "" && route.Deprecation == nil {
+		return nil, nil
+	}
+	headers, err := versionHeaders(route.Deprecation)
+	if err != nil {
+		return nil, err
+	}
+	version := normalizeVersion(route.Version)
+	if version != "" {
+		headers.Set(r.Versioning.header(), version)
+	}
+	label := version
+	if label == "" {
+		label = "unversioned"
+	}
+	deprecated := strconv.FormatBool(route.Deprecation != nil)
+	counter := versionRequests.WithLabelValues(route.Path, label, deprecated)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			counter.Inc()
+			accesslog.FromContext(req.Context()).SetVersion(version)
+			for name, values := range headers {
+				w.Header()[name] = values
+			}
+			next.ServeHTTP(w, req)
+		})
+	}, nil
+}
+
+// versionSet 同一主机名和路径下的多个版本化路由，按请求中的版本选择
+type versionSet struct {
+	route  Route
+	prefix bool
+	// handlers 各版本的处理链
+	// key: 版本号
+	handlers map[string]http.Handler
+	// jobs 各版本的任务状态查询处理链，非异步路由没有
+	jobs map[string]http.Handler
+}
+
+// versionSets 按主机名和路径收集版本化路由，所有路由编译完成后统一注册
+type versionSets struct {
+	keys []string
+	sets map[string]*versionSet
+}
+
+func newVersionSets() *versionSets {
+	return &versionSets{sets: make(map[string]*versionSet)}
+}
+
+// add 加入一个版本的处理链
+func (v *versionSets) add(route Route, handler, status http.Handler, prefix bool) error {
+	key := strings.Join(route.Hosts, ",") + " " + route.Path
+	set := v.sets[key]
+	if set == nil {
+		set = &versionSet{route: route, prefix: prefix, handlers: make(map[string]http.Handler), jobs: make(map[string]http.Handler)}
+		v.sets[key] = set
+		v.keys = append(v.keys, key)
+	}
+	if set.prefix != prefix {
+		return fmt.Errorf("route %s: all versions must use the same language (http or not)", route.Path)
+	}
+	if _, ok := set.handlers[route.Version]; ok {
+		return fmt.Errorf("duplicate route %s version %s", route.Path, route.Version)
+	}
+	set.handlers[route.Version] = handler
+	if status != nil {
+		set.jobs[route.Version] = status
+	}
+	return nil
+}
+
+// register 为每个路径注册按请求版本分发的处理器
+func (v *versionSets) register(tables *hostTables, jobHandlers map[string]http.Handler, opts *VersioningOptions) error {
+	for _, key := range v.keys {
+		set := v.sets[key]
+		if err := tables.add(set.route, dispatchVersion(set.handlers, opts), set.prefix); err != nil {
+			return err
+		}
+		if len(set.jobs) > 0 {
+			jobHandlers[set.route.Path] = dispatchVersion(set.jobs, opts)
+		}
+	}
+	return nil
+}
+
+// addRoute 将路由处理链加入路由表
+// 未配置版本的路由按路由路径注册；版本化路由在启用 path 方式时按 /<version><path> 注册，
+// 启用 header 或 media_type 方式时加入 sets，由 sets.register 在路由路径上注册分发处理器
+// 参数：
+//   - status http.Handler: 异步路由的任务状态查询处理链，非异步路由为nil
+//   - prefix bool: 是否按前缀匹配（http 路由）
+func (r *Router) addRoute(tables *hostTables, sets *versionSets, jobHandlers map[string]http.Handler, route Route, handler, status http.Handler, prefix bool) error {
+	if route.Version == "" {
+		if err := tables.add(route, handler, prefix); err != nil {
+			return err
+		}
+		if status != nil {
+			jobHandlers[route.Path] = status
+		}
+		return nil
+	}
+	if r.Versioning.enabled(VersionByPath) {
+		versioned := route
+		versioned.Path = r.VersionedPath(route)
+		if err := tables.add(versioned, handler, prefix); err != nil {
+			return err
+		}
+		if status != nil {
+			jobHandlers[versioned.Path] = status
+		}
+	}
+	if r.Versioning.enabled(VersionByHeader) || r.Versioning.enabled(VersionByMediaType) {
+		return sets.add(route, handler, status, prefix)
+	}
+	return nil
+}
+
+// sortVersions 返回所有版本，从旧到新排列
+func sortVersions(handlers map[string]http.Handler) []string {
+	versions := make([]string, 0, len(handlers))
+	for v := range handlers {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versionLess(versions[i], versions[j]) })
+	return versions
+}
+
+// dispatchVersion 返回按请求版本分发的处理器
+// 请求未指定版本时使用配置的默认版本（该路径没有默认版本时使用最新版本），指定了不存在的版本时返回400
+func dispatchVersion(handlers map[string]http.Handler, opts *VersioningOptions) http.Handler {
+	versions := sortVersions(handlers)
+	fallback := versions[len(versions)-1]
+	if opts != nil && opts.Default != "" {
+		if _, ok := handlers[normalizeVersion(opts.Default)]; ok {
+			fallback = normalizeVersion(opts.Default)
+		}
+	}
+	// 响应随版本请求头或 Accept 变化，告知下游缓存按这些请求头区分
+	var vary []string
+	if opts.enabled(VersionByHeader) {
+		vary = append(vary, opts.header())
+	}
+	if opts.enabled(VersionByMediaType) {
+		vary = append(vary, "Accept")
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		for _, name := range vary {
+			w.Header().Add("Vary", name)
+		}
+		version := requestedVersion(req, opts)
+		if version == "" {
+			version = fallback
+		}
+		handler, ok := handlers[version]
+		if !ok {
+			middleware.WriteError(w, http.StatusBadRequest, "Unsupported API version", map[string]interface{}{
+				"version":   version,
+				"supported": versions,
+			})
+			return
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// requestedVersion 按配置的方式读取请求指定的版本：请求头优先，其次是 Accept 媒体类型
+func requestedVersion(req *http.Request, opts *VersioningOptions) string {
+	if opts.enabled(VersionByHeader) {
+		if v := req.Header.Get(opts.header()); v != "" {
+			return normalizeVersion(v)
+		}
+	}
+	if opts.enabled(VersionByMediaType) {
+		if v := mediaTypeVersion(req.Header.Values("Accept"), opts.vendor()); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// mediaTypeVersion 从 Accept 头中读取版本
+// 支持 application/vnd.<vendor>.v2+json 和 application/json; version=2 两种写法
+func mediaTypeVersion(accepts []string, vendor string) string {
+	prefix := "application/vnd." + strings.ToLower(vendor) + "."
+	for _, accept := range accepts {
+		for _, item := range strings.Split(accept, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(item))
+			if err != nil {
+				continue
+			}
+			if v := params["version"]; v != "" {
+				return normalizeVersion(v)
+			}
+			if strings.HasPrefix(mediaType, prefix) {
+				v := strings.TrimPrefix(mediaType, prefix)
+				if end := strings.IndexByte(v, '+'); end >= 0 {
+					v = v[:end]
+				}
+				if v != "" {
+					return normalizeVersion(v)
+				}
+			}
+		}
+	}
+	return ""
+}
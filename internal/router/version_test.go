@@ -0,0 +1,191 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeVersion(t *testing.T) {
+	tests := map[string]string{"": "", "2": "v2", "V2": "v2", " v2.1 ": "v2.1", "beta": "vbeta"}
+	for in, want := range tests {
+		if got := normalizeVersion(in); got != want {
+			t.Errorf("normalizeVersion(%q) = %q, want %q", in, got, want)
+		}
+	}
+	for _, v := range []string{"v", "v2/admin", "v2 beta", "v2_1"} {
+		if validateVersion(v) == nil {
+			t.Errorf("validateVersion(%q) succeeded, want error", v)
+		}
+	}
+	for _, v := range []string{"v1", "v2.1", "v2-beta"} {
+		if err := validateVersion(v); err != nil {
+			t.Errorf("validateVersion(%q): %v", v, err)
+		}
+	}
+}
+
+func TestSortVersions(t *testing.T) {
+	handlers := make(map[string]http.Handler)
+	for _, v := range []string{"v10", "v2", "v1", "v10.1", "v2.0", "vbeta"} {
+		handlers[v] = nil
+	}
+	want := []string{"v1", "v2", "v2.0", "v10", "v10.1", "vbeta"}
+	if got := sortVersions(handlers); !reflect.DeepEqual(got, want) {
+		t.Fatalf("sortVersions = %v, want %v", got, want)
+	}
+}
+
+func TestMediaTypeVersion(t *testing.T) {
+	tests := []struct {
+		accept []string
+		vendor string
+		want   string
+	}{
+		{accept: []string{"application/vnd.bighammer.v2+json"}, vendor: "bighammer", want: "v2"},
+		{accept: []string{"application/vnd.bighammer.v3"}, vendor: "bighammer", want: "v3"},
+		{accept: []string{"application/vnd.BigHammer.v2+json"}, vendor: "BigHammer", want: "v2"},
+		{accept: []string{"application/json; version=2"}, vendor: "bighammer", want: "v2"},
+		{accept: []string{"text/html, application/vnd.bighammer.v4+json;q=0.9"}, vendor: "bighammer", want: "v4"},
+		{accept: []string{"text/html", "application/json;version=5"}, vendor: "bighammer", want: "v5"},
+		{accept: []string{"application/vnd.other.v2+json"}, vendor: "bighammer"},
+		{accept: []string{"application/json"}, vendor: "bighammer"},
+		{accept: []string{"invalid;;"}, vendor: "bighammer"},
+	}
+	for _, tt := range tests {
+		if got := mediaTypeVersion(tt.accept, tt.vendor); got != tt.want {
+			t.Errorf("mediaTypeVersion(%q) = %q, want %q", tt.accept, got, tt.want)
+		}
+	}
+}
+
+// versionHandlers 返回以版本号作为响应体的处理器
+func versionHandlers(versions ...string) map[string]http.Handler {
+	handlers := make(map[string]http.Handler)
+	for _, v := range versions {
+		v := v
+		handlers[v] = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(v))
+		})
+	}
+	return handlers
+}
+
+func TestDispatchVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    *VersioningOptions
+		headers map[string]string
+		status  int
+		want    string
+		vary    []string
+	}{
+		{name: "latest by default", want: "v10", vary: []string{"API-Version", "Accept"}},
+		{name: "header", headers: map[string]string{"API-Version": "2"}, want: "v2", vary: []string{"API-Version", "Accept"}},
+		{name: "accept vendor", headers: map[string]string{"Accept": "application/vnd.bighammer.v1+json"}, want: "v1", vary: []string{"API-Version", "Accept"}},
+		{name: "accept parameter", headers: map[string]string{"Accept": "application/json; version=2"}, want: "v2", vary: []string{"API-Version", "Accept"}},
+		{name: "header before accept", headers: map[string]string{"API-Version": "v1", "Accept": "application/json; version=2"}, want: "v1", vary: []string{"API-Version", "Accept"}},
+		{name: "configured default", opts: &VersioningOptions{Default: "2"}, want: "v2", vary: []string{"API-Version", "Accept"}},
+		{name: "default missing on path", opts: &VersioningOptions{Default: "v3"}, want: "v10", vary: []string{"API-Version", "Accept"}},
+		{name: "custom header", opts: &VersioningOptions{Header: "X-Api"}, headers: map[string]string{"X-Api": "1", "API-Version": "2"}, want: "v1", vary: []string{"X-Api", "Accept"}},
+		{name: "custom vendor", opts: &VersioningOptions{Vendor: "acme"}, headers: map[string]string{"Accept": "application/vnd.acme.v2+json"}, want: "v2", vary: []string{"API-Version", "Accept"}},
+		{name: "header source only", opts: &VersioningOptions{Sources: []string{VersionByPath, VersionByHeader}}, headers: map[string]string{"Accept": "application/json; version=1"}, want: "v10", vary: []string{"API-Version"}},
+		{name: "media type source only", opts: &VersioningOptions{Sources: []string{VersionByMediaType}}, headers: map[string]string{"API-Version": "1"}, want: "v10", vary: []string{"Accept"}},
+		{name: "unsupported version", headers: map[string]string{"API-Version": "9"}, status: http.StatusBadRequest, vary: []string{"API-Version", "Accept"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := dispatchVersion(versionHandlers("v1", "v2", "v10"), tt.opts)
+			req := httptest.NewRequest(http.MethodGet, "/users", nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			status := tt.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if rec.Code != status {
+				t.Fatalf("status = %d, want %d", rec.Code, status)
+			}
+			if status == http.StatusOK && rec.Body.String() != tt.want {
+				t.Fatalf("version = %q, want %q", rec.Body.String(), tt.want)
+			}
+			if got := rec.Header().Values("Vary"); !reflect.DeepEqual(got, tt.vary) {
+				t.Fatalf("Vary = %v, want %v", got, tt.vary)
+			}
+		})
+	}
+}
+
+func TestDispatchVersionUnsupported(t *testing.T) {
+	handler := dispatchVersion(versionHandlers("v2", "v1"), nil)
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set("API-Version", "3")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var body struct {
+		Data struct {
+			Version   string   `json:"version"`
+			Supported []string `json:"supported"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Data.Version != "v3" || !reflect.DeepEqual(body.Data.Supported, []string{"v1", "v2"}) {
+		t.Fatalf("error data = %+v", body.Data)
+	}
+}
+
+func TestVersionMiddleware(t *testing.T) {
+	r := &Router{}
+	mw, err := r.versionMiddleware(Route{Path: "/users", Version: "2", Deprecation: &Deprecation{
+		Date:   "2024-01-01",
+		Sunset: "2025-01-01",
+		Link:   "https://example.com/migrate",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	mw(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users", nil))
+
+	want := map[string]string{
+		"API-Version": "v2",
+		"Deprecation": "@1704067200",
+		"Sunset":      "Wed, 01 Jan 2025 00:00:00 GMT",
+		"Link":        `<https://example.com/migrate>; rel="deprecation"; type="text/html"`,
+	}
+	for name, value := range want {
+		if got := rec.Header().Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+
+	if mw, err := r.versionMiddleware(Route{Path: "/users"}); mw != nil || err != nil {
+		t.Fatalf("unversioned route middleware = %v, %v; want nil, nil", mw != nil, err)
+	}
+	if _, err := r.versionMiddleware(Route{Path: "/users", Deprecation: &Deprecation{Sunset: "soon"}}); err == nil {
+		t.Fatal("invalid sunset accepted")
+	}
+}
+
+func TestVersionedPath(t *testing.T) {
+	route := Route{Path: "/users", Version: "2"}
+	if got := (&Router{}).VersionedPath(route); got != "/v2/users" {
+		t.Fatalf("VersionedPath = %q, want /v2/users", got)
+	}
+	r := &Router{Versioning: &VersioningOptions{Sources: []string{VersionByHeader}}}
+	if got := r.VersionedPath(route); got != "/users" {
+		t.Fatalf("VersionedPath without path source = %q, want /users", got)
+	}
+	if got := routeName(Route{Path: "/users", Version: "V2", Hosts: []string{"api.example.com"}}); got != "api.example.com/v2/users" {
+		t.Fatalf("routeName = %q", got)
+	}
+}
@@ -0,0 +1,132 @@
+package schema
+
+import (
+	"bigHammer/pkg/utils"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ComponentsPrefix 打包后的 schema 在 OpenAPI 文档中的位置
+const ComponentsPrefix = "#/components/schemas/"
+
+var componentName = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// Bundle 将 schema 文件及其通过 $ref 引用的文件收集为 OpenAPI components
+// 每个文件作为一个组件，$ref 改写为指向组件内部的 JSON Pointer
+type Bundle struct {
+	// Schemas 组件名称到 schema 内容的映射
+	Schemas map[string]interface{}
+	// names 已收集的文件
+	// key: 文件绝对路径
+	names map[string]string
+}
+
+// NewBundle 创建空的 schema 组件集合
+func NewBundle() *Bundle {
+	return &Bundle{Schemas: make(map[string]interface{}), names: make(map[string]string)}
+}
+
+// Add 收集 schema 文件
+// 参数：
+//   - path string: schema 文件路径（相对于项目根目录）
+// 返回值：
+//   - string: 指向该文件组件的 $ref
+//   - error: 文件读取失败或JSON无效时返回的错误信息
+func (b *Bundle) Add(path string) (string, error) {
+	resolved, err := utils.ResolvePath(path)
+	if err != nil {
+		return "", err
+	}
+	name, err := b.add(resolved)
+	if err != nil {
+		return "", err
+	}
+	return ComponentsPrefix + name, nil
+}
+
+// Lookup 返回组件内 JSON Pointer 位置的内容
+func (b *Bundle) Lookup(ref string) (interface{}, bool) {
+	name, pointer, _ := strings.Cut(strings.TrimPrefix(ref, ComponentsPrefix), "/")
+	root, ok := b.Schemas[name]
+	if !ok {
+		return nil, false
+	}
+	if pointer != "" {
+		pointer = "/" + pointer
+	}
+	v, err := lookup(root, pointer)
+	return v, err == nil
+}
+
+func (b *Bundle) add(path string) (string, error) {
+	if name, ok := b.names[path]; ok {
+		return name, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("schema: %v", err)
+	}
+	var root interface{}
+	if err := json.Unmarshal(data, &root); err != nil {
+		return "", fmt.Errorf("schema %s: %v", path, err)
+	}
+
+	base := componentName.ReplaceAllString(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), "_")
+	name := base
+	for i := 2; b.Schemas[name] != nil; i++ {
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+	b.names[path] = name
+	b.Schemas[name] = true
+
+	if m, ok := root.(map[string]interface{}); ok {
+		delete(m, "$schema")
+		delete(m, "$id")
+	}
+	if b.Schemas[name], err = b.rewrite(path, name, root); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// rewrite 将 $ref 改写为指向组件的引用，引用其他文件时一并收集
+func (b *Bundle) rewrite(path, name string, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if ref, ok := child.(string); ok && key == "$ref" {
+				file, fragment, _ := strings.Cut(ref, "#")
+				target := name
+				if file != "" {
+					if filepath.IsAbs(file) || strings.Contains(file, "://") {
+						return nil, fmt.Errorf("schema %s: unsupported $ref %q", path, ref)
+					}
+					var err error
+					if target, err = b.add(filepath.Join(filepath.Dir(path), file)); err != nil {
+						return nil, err
+					}
+				}
+				v[key] = ComponentsPrefix + target + fragment
+				continue
+			}
+			rewritten, err := b.rewrite(path, name, child)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = rewritten
+		}
+	case []interface{}:
+		for i := range v {
+			rewritten, err := b.rewrite(path, name, v[i])
+			if err != nil {
+				return nil, err
+			}
+			v[i] = rewritten
+		}
+	}
+	return value, nil
+}
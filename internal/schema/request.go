@@ -0,0 +1,74 @@
+package schema
+
+import (
+	"net/url"
+)
+
+// Options 路由请求校验配置，对应 router.json 中路由的 schema 字段
+// 各字段为 schema 文件路径（相对于项目根目录），为空时不校验对应部分
+type Options struct {
+	// Body 请求体 schema
+	Body string `json:"body,omitempty"`
+	// Query 查询参数 schema，参数值按属性类型转换后校验
+	Query string `json:"query,omitempty"`
+	// Path 路径参数 schema，参数值按属性类型转换后校验
+	Path string `json:"path,omitempty"`
+}
+
+// RequestValidator 编译后的路由请求校验规则
+type RequestValidator struct {
+	body  *Schema
+	query *Schema
+	path  *Schema
+}
+
+// Compile 加载并编译配置引用的 schema 文件
+// 返回值：
+//   - *RequestValidator: 请求校验器
+//   - error: schema 文件无法加载或无效时返回的错误信息
+func (o Options) Compile() (*RequestValidator, error) {
+	r := &RequestValidator{}
+	var err error
+	for _, part := range []struct {
+		file string
+		dst  **Schema
+	}{{o.Body, &r.body}, {o.Query, &r.query}, {o.Path, &r.path}} {
+		if part.file == "" {
+			continue
+		}
+		if *part.dst, err = Load(part.file); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Validate 校验请求
+// 参数：
+//   - body interface{}: 解析后的JSON请求体
+//   - bodyErr error: 请求体不是有效JSON时的解析错误，配置了 body schema 时视为校验失败
+//   - query url.Values: 查询参数
+//   - params map[string]string: 路径参数
+// 返回值：
+//   - []ValidationError: 校验错误，路径分别以 body、query、path 开头
+func (r *RequestValidator) Validate(body interface{}, bodyErr error, query url.Values, params map[string]string) []ValidationError {
+	var errs []ValidationError
+	if r.body != nil {
+		if bodyErr != nil {
+			errs = append(errs, ValidationError{Path: "body", Keyword: "json", Message: "invalid JSON: " + bodyErr.Error()})
+		} else {
+			errs = append(errs, r.body.Validate("body", body)...)
+		}
+	}
+	if r.query != nil {
+		errs = append(errs, r.query.Validate("query", r.query.Coerce(query))...)
+	}
+	if r.path != nil {
+		values := make(map[string][]string, len(params))
+		for name, value := range params {
+			values[name] = []string{value}
+		}
+		errs = append(errs, r.path.Validate("path", r.path.Coerce(values))...)
+	}
+	return errs
+}
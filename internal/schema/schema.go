@@ -0,0 +1,453 @@
+package schema
+
+import (
+	"bigHammer/pkg/utils"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Schema 编译后的 JSON Schema（draft-07）
+type Schema struct {
+	// always 布尔 schema：true 接受任何值，false 拒绝任何值
+	always *bool
+	// ref $ref 指向的 schema，draft-07 中存在 $ref 时忽略同级关键字
+	ref *Schema
+
+	types    []string
+	enum     []interface{}
+	constVal interface{}
+	hasConst bool
+
+	minimum          *float64
+	maximum          *float64
+	exclusiveMinimum *float64
+	exclusiveMaximum *float64
+	multipleOf       *float64
+
+	minLength *int
+	maxLength *int
+	pattern   *regexp.Regexp
+	format    string
+
+	items           *Schema
+	itemsList       []*Schema
+	additionalItems *Schema
+	minItems        *int
+	maxItems        *int
+	uniqueItems     bool
+	contains        *Schema
+
+	properties           map[string]*Schema
+	patternProperties    []patternSchema
+	additionalProperties *Schema
+	required             []string
+	minProperties        *int
+	maxProperties        *int
+	propertyNames        *Schema
+	dependencies         map[string]dependency
+
+	allOf []*Schema
+	anyOf []*Schema
+	oneOf []*Schema
+	not   *Schema
+	ifS   *Schema
+	thenS *Schema
+	elseS *Schema
+}
+
+type patternSchema struct {
+	pattern *regexp.Regexp
+	schema  *Schema
+}
+
+// dependency dependencies 关键字的值：属性列表或 schema
+type dependency struct {
+	properties []string
+	schema     *Schema
+}
+
+// document 一个 schema 文件
+type document struct {
+	path string
+	root interface{}
+	// compiled 已编译的子 schema
+	// key: JSON Pointer
+	compiled map[string]*Schema
+}
+
+// compiler 编译 schema 文件及其通过 $ref 引用的其他文件
+type compiler struct {
+	// docs 已加载的文件
+	// key: 文件绝对路径
+	docs map[string]*document
+}
+
+// Load 加载并编译 schema 文件
+// 参数：
+//   - path string: schema 文件路径（相对于项目根目录，与其他配置路径一致）
+// 返回值：
+//   - *Schema: 编译后的 schema
+//   - error: 文件读取失败、JSON无效或 schema 关键字无效时返回的错误信息
+func Load(path string) (*Schema, error) {
+	resolved, err := utils.ResolvePath(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &compiler{docs: make(map[string]*document)}
+	doc, err := c.document(resolved)
+	if err != nil {
+		return nil, err
+	}
+	return c.compile(doc, "", doc.root)
+}
+
+// Compile 编译 JSON 格式的 schema，$ref 只能引用文档内部
+func Compile(data []byte) (*Schema, error) {
+	var root interface{}
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("schema: %v", err)
+	}
+	c := &compiler{docs: make(map[string]*document)}
+	doc := &document{root: root, compiled: make(map[string]*Schema)}
+	return c.compile(doc, "", root)
+}
+
+// document 读取并解析 schema 文件，同一文件只读取一次
+func (c *compiler) document(path string) (*document, error) {
+	if doc, ok := c.docs[path]; ok {
+		return doc, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("schema: %v", err)
+	}
+	var root interface{}
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("schema %s: %v", path, err)
+	}
+	doc := &document{path: path, root: root, compiled: make(map[string]*Schema)}
+	c.docs[path] = doc
+	return doc, nil
+}
+
+// compile 编译文档中 pointer 位置的 schema
+// 编译前先登记到 compiled，使递归引用指向同一个 schema
+func (c *compiler) compile(doc *document, pointer string, raw interface{}) (*Schema, error) {
+	if s, ok := doc.compiled[pointer]; ok {
+		return s, nil
+	}
+	s := &Schema{}
+	doc.compiled[pointer] = s
+
+	if b, ok := raw.(bool); ok {
+		s.always = &b
+		return s, nil
+	}
+	m, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("schema %s: expected object or boolean", location(doc, pointer))
+	}
+
+	if ref, ok := m["$ref"].(string); ok {
+		target, err := c.resolve(doc, ref)
+		if err != nil {
+			return nil, err
+		}
+		s.ref = target
+		return s, nil
+	}
+
+	p := &parser{c: c, doc: doc, pointer: pointer, m: m}
+	p.types(&s.types)
+	if v, ok := m["enum"].([]interface{}); ok {
+		s.enum = v
+	}
+	if v, ok := m["const"]; ok {
+		s.constVal, s.hasConst = v, true
+	}
+	s.minimum = p.number("minimum")
+	s.maximum = p.number("maximum")
+	s.exclusiveMinimum = p.number("exclusiveMinimum")
+	s.exclusiveMaximum = p.number("exclusiveMaximum")
+	s.multipleOf = p.number("multipleOf")
+	s.minLength = p.integer("minLength")
+	s.maxLength = p.integer("maxLength")
+	s.pattern = p.regexp("pattern")
+	s.format, _ = m["format"].(string)
+
+	if items, ok := m["items"].([]interface{}); ok {
+		for i := range items {
+			s.itemsList = append(s.itemsList, p.schema(fmt.Sprintf("items/%d", i), items[i]))
+		}
+	} else {
+		s.items = p.sub("items")
+	}
+	s.additionalItems = p.sub("additionalItems")
+	s.minItems = p.integer("minItems")
+	s.maxItems = p.integer("maxItems")
+	s.uniqueItems, _ = m["uniqueItems"].(bool)
+	s.contains = p.sub("contains")
+
+	s.properties = p.schemaMap("properties")
+	for pattern, sub := range p.schemaMap("patternProperties") {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			p.fail("patternProperties", err)
+			continue
+		}
+		s.patternProperties = append(s.patternProperties, patternSchema{pattern: re, schema: sub})
+	}
+	s.additionalProperties = p.sub("additionalProperties")
+	s.required = p.strings("required")
+	s.minProperties = p.integer("minProperties")
+	s.maxProperties = p.integer("maxProperties")
+	s.propertyNames = p.sub("propertyNames")
+	if deps, ok := m["dependencies"].(map[string]interface{}); ok {
+		s.dependencies = make(map[string]dependency, len(deps))
+		for name, dep := range deps {
+			if list, ok := dep.([]interface{}); ok {
+				var props []string
+				for _, v := range list {
+					if str, ok := v.(string); ok {
+						props = append(props, str)
+					}
+				}
+				s.dependencies[name] = dependency{properties: props}
+				continue
+			}
+			s.dependencies[name] = dependency{schema: p.schema("dependencies/"+escape(name), dep)}
+		}
+	}
+
+	s.allOf = p.schemaList("allOf")
+	s.anyOf = p.schemaList("anyOf")
+	s.oneOf = p.schemaList("oneOf")
+	s.not = p.sub("not")
+	s.ifS = p.sub("if")
+	s.thenS = p.sub("then")
+	s.elseS = p.sub("else")
+
+	if p.err != nil {
+		return nil, p.err
+	}
+	return s, nil
+}
+
+// resolve 解析 $ref，支持文档内的 JSON Pointer 和相对于当前文件的其他文件
+func (c *compiler) resolve(doc *document, ref string) (*Schema, error) {
+	file, fragment, _ := strings.Cut(ref, "#")
+	target := doc
+	if file != "" {
+		if doc.path == "" || filepath.IsAbs(file) || strings.Contains(file, "://") {
+			return nil, fmt.Errorf("schema %s: unsupported $ref %q", location(doc, ""), ref)
+		}
+		var err error
+		if target, err = c.document(filepath.Join(filepath.Dir(doc.path), file)); err != nil {
+			return nil, err
+		}
+	}
+	raw, err := lookup(target.root, fragment)
+	if err != nil {
+		return nil, fmt.Errorf("schema %s: $ref %q: %v", location(doc, ""), ref, err)
+	}
+	return c.compile(target, fragment, raw)
+}
+
+// lookup 按 JSON Pointer 查找文档中的值
+func lookup(root interface{}, pointer string) (interface{}, error) {
+	if pointer == "" || pointer == "/" {
+		return root, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("only JSON Pointer fragments are supported")
+	}
+	current := root
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch v := current.(type) {
+		case map[string]interface{}:
+			next, ok := v[token]
+			if !ok {
+				return nil, fmt.Errorf("%s not found", pointer)
+			}
+			current = next
+		case []interface{}:
+			var i int
+			if _, err := fmt.Sscanf(token, "%d", &i); err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("%s not found", pointer)
+			}
+			current = v[i]
+		default:
+			return nil, fmt.Errorf("%s not found", pointer)
+		}
+	}
+	return current, nil
+}
+
+// escape 按 JSON Pointer 规则转义名称
+func escape(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
+
+// location 返回用于错误信息的 schema 位置
+func location(doc *document, pointer string) string {
+	return doc.path + "#" + pointer
+}
+
+// parser 读取 schema 对象中的关键字，记录遇到的第一个错误
+type parser struct {
+	c       *compiler
+	doc     *document
+	pointer string
+	m       map[string]interface{}
+	err     error
+}
+
+func (p *parser) fail(keyword string, err error) {
+	if p.err == nil {
+		p.err = fmt.Errorf("schema %s/%s: %v", location(p.doc, p.pointer), keyword, err)
+	}
+}
+
+func (p *parser) schema(path string, raw interface{}) *Schema {
+	s, err := p.c.compile(p.doc, p.pointer+"/"+path, raw)
+	if err != nil && p.err == nil {
+		p.err = err
+	}
+	return s
+}
+
+func (p *parser) sub(keyword string) *Schema {
+	raw, ok := p.m[keyword]
+	if !ok {
+		return nil
+	}
+	return p.schema(keyword, raw)
+}
+
+func (p *parser) schemaList(keyword string) []*Schema {
+	raw, ok := p.m[keyword]
+	if !ok {
+		return nil
+	}
+	list, ok := raw.([]interface{})
+	if !ok || len(list) == 0 {
+		p.fail(keyword, fmt.Errorf("expected non-empty array"))
+		return nil
+	}
+	schemas := make([]*Schema, len(list))
+	for i := range list {
+		schemas[i] = p.schema(fmt.Sprintf("%s/%d", keyword, i), list[i])
+	}
+	return schemas
+}
+
+func (p *parser) schemaMap(keyword string) map[string]*Schema {
+	raw, ok := p.m[keyword]
+	if !ok {
+		return nil
+	}
+	m, ok := raw.(map[string]interface{})
+	if !ok {
+		p.fail(keyword, fmt.Errorf("expected object"))
+		return nil
+	}
+	schemas := make(map[string]*Schema, len(m))
+	for name, sub := range m {
+		schemas[name] = p.schema(keyword+"/"+escape(name), sub)
+	}
+	return schemas
+}
+
+func (p *parser) number(keyword string) *float64 {
+	raw, ok := p.m[keyword]
+	if !ok {
+		return nil
+	}
+	v, ok := raw.(float64)
+	if !ok {
+		p.fail(keyword, fmt.Errorf("expected number"))
+		return nil
+	}
+	return &v
+}
+
+func (p *parser) integer(keyword string) *int {
+	v := p.number(keyword)
+	if v == nil {
+		return nil
+	}
+	if *v < 0 || *v != float64(int(*v)) {
+		p.fail(keyword, fmt.Errorf("expected non-negative integer"))
+		return nil
+	}
+	n := int(*v)
+	return &n
+}
+
+func (p *parser) regexp(keyword string) *regexp.Regexp {
+	raw, ok := p.m[keyword]
+	if !ok {
+		return nil
+	}
+	str, ok := raw.(string)
+	if !ok {
+		p.fail(keyword, fmt.Errorf("expected string"))
+		return nil
+	}
+	re, err := regexp.Compile(str)
+	if err != nil {
+		p.fail(keyword, err)
+		return nil
+	}
+	return re
+}
+
+func (p *parser) strings(keyword string) []string {
+	raw, ok := p.m[keyword]
+	if !ok {
+		return nil
+	}
+	list, ok := raw.([]interface{})
+	if !ok {
+		p.fail(keyword, fmt.Errorf("expected array of strings"))
+		return nil
+	}
+	var values []string
+	for _, v := range list {
+		str, ok := v.(string)
+		if !ok {
+			p.fail(keyword, fmt.Errorf("expected array of strings"))
+			return nil
+		}
+		values = append(values, str)
+	}
+	return values
+}
+
+func (p *parser) types(dst *[]string) {
+	switch v := p.m["type"].(type) {
+	case nil:
+	case string:
+		*dst = []string{v}
+	case []interface{}:
+		for _, t := range v {
+			if str, ok := t.(string); ok {
+				*dst = append(*dst, str)
+			}
+		}
+	default:
+		p.fail("type", fmt.Errorf("expected string or array"))
+	}
+	for _, t := range *dst {
+		switch t {
+		case "null", "boolean", "object", "array", "number", "string", "integer":
+		default:
+			p.fail("type", fmt.Errorf("unknown type %q", t))
+		}
+	}
+}
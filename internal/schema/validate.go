@@ -0,0 +1,442 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxErrors 单次校验最多返回的错误数
+const maxErrors = 50
+
+// ValidationError 校验错误
+type ValidationError struct {
+	// Path 出错的值的位置，例如 body.items[0].name
+	Path string `json:"path"`
+	// Keyword 未通过的 schema 关键字
+	Keyword string `json:"keyword"`
+	// Message 错误描述
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// Validate 校验值是否满足 schema
+// 参数：
+//   - root string: 根位置名称，用作错误路径的前缀
+//   - value interface{}: 由 encoding/json 解码得到的值
+// 返回值：
+//   - []ValidationError: 校验错误，通过时为空
+func (s *Schema) Validate(root string, value interface{}) []ValidationError {
+	v := &validator{}
+	v.validate(s, root, value)
+	return v.errs
+}
+
+type validator struct {
+	errs []ValidationError
+}
+
+func (v *validator) add(path, keyword, format string, args ...interface{}) {
+	if len(v.errs) < maxErrors {
+		v.errs = append(v.errs, ValidationError{Path: path, Keyword: keyword, Message: fmt.Sprintf(format, args...)})
+	}
+}
+
+// valid 判断值是否满足子 schema，不记录错误（用于 anyOf/oneOf/not/if 等）
+func valid(s *Schema, value interface{}) bool {
+	v := &validator{}
+	v.validate(s, "", value)
+	return len(v.errs) == 0
+}
+
+func (v *validator) validate(s *Schema, path string, value interface{}) {
+	for s.ref != nil {
+		s = s.ref
+	}
+	if s.always != nil {
+		if !*s.always {
+			v.add(path, "false", "no value is allowed")
+		}
+		return
+	}
+
+	if len(s.types) > 0 && !matchesType(s.types, value) {
+		v.add(path, "type", "expected %s, got %s", strings.Join(s.types, " or "), typeOf(value))
+		return
+	}
+	if s.enum != nil && !contains(s.enum, value) {
+		v.add(path, "enum", "value must be one of %v", s.enum)
+	}
+	if s.hasConst && !equal(s.constVal, value) {
+		v.add(path, "const", "value must be %v", s.constVal)
+	}
+
+	switch value := value.(type) {
+	case float64:
+		v.number(s, path, value)
+	case string:
+		v.string(s, path, value)
+	case []interface{}:
+		v.array(s, path, value)
+	case map[string]interface{}:
+		v.object(s, path, value)
+	}
+
+	for _, sub := range s.allOf {
+		v.validate(sub, path, value)
+	}
+	if s.anyOf != nil {
+		matched := false
+		for _, sub := range s.anyOf {
+			if valid(sub, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.add(path, "anyOf", "value does not match any of the allowed schemas")
+		}
+	}
+	if s.oneOf != nil {
+		matched := 0
+		for _, sub := range s.oneOf {
+			if valid(sub, value) {
+				matched++
+			}
+		}
+		if matched != 1 {
+			v.add(path, "oneOf", "value must match exactly one schema, matched %d", matched)
+		}
+	}
+	if s.not != nil && valid(s.not, value) {
+		v.add(path, "not", "value must not match the schema")
+	}
+	if s.ifS != nil {
+		if valid(s.ifS, value) {
+			if s.thenS != nil {
+				v.validate(s.thenS, path, value)
+			}
+		} else if s.elseS != nil {
+			v.validate(s.elseS, path, value)
+		}
+	}
+}
+
+func (v *validator) number(s *Schema, path string, n float64) {
+	if s.minimum != nil && n < *s.minimum {
+		v.add(path, "minimum", "must be >= %v", *s.minimum)
+	}
+	if s.maximum != nil && n > *s.maximum {
+		v.add(path, "maximum", "must be <= %v", *s.maximum)
+	}
+	if s.exclusiveMinimum != nil && n <= *s.exclusiveMinimum {
+		v.add(path, "exclusiveMinimum", "must be > %v", *s.exclusiveMinimum)
+	}
+	if s.exclusiveMaximum != nil && n >= *s.exclusiveMaximum {
+		v.add(path, "exclusiveMaximum", "must be < %v", *s.exclusiveMaximum)
+	}
+	if s.multipleOf != nil && *s.multipleOf > 0 {
+		q := n / *s.multipleOf
+		if math.Abs(q-math.Round(q)) > 1e-9 {
+			v.add(path, "multipleOf", "must be a multiple of %v", *s.multipleOf)
+		}
+	}
+}
+
+func (v *validator) string(s *Schema, path string, str string) {
+	length := utf8.RuneCountInString(str)
+	if s.minLength != nil && length < *s.minLength {
+		v.add(path, "minLength", "length must be >= %d", *s.minLength)
+	}
+	if s.maxLength != nil && length > *s.maxLength {
+		v.add(path, "maxLength", "length must be <= %d", *s.maxLength)
+	}
+	if s.pattern != nil && !s.pattern.MatchString(str) {
+		v.add(path, "pattern", "must match pattern %s", s.pattern.String())
+	}
+	if s.format != "" && !checkFormat(s.format, str) {
+		v.add(path, "format", "must be a valid %s", s.format)
+	}
+}
+
+func (v *validator) array(s *Schema, path string, items []interface{}) {
+	if s.minItems != nil && len(items) < *s.minItems {
+		v.add(path, "minItems", "must have at least %d items", *s.minItems)
+	}
+	if s.maxItems != nil && len(items) > *s.maxItems {
+		v.add(path, "maxItems", "must have at most %d items", *s.maxItems)
+	}
+	if s.uniqueItems {
+		for i := 1; i < len(items); i++ {
+			if contains(items[:i], items[i]) {
+				v.add(path, "uniqueItems", "items must be unique, item %d is a duplicate", i)
+				break
+			}
+		}
+	}
+	switch {
+	case s.items != nil:
+		for i, item := range items {
+			v.validate(s.items, fmt.Sprintf("%s[%d]", path, i), item)
+		}
+	case s.itemsList != nil:
+		for i, item := range items {
+			itemPath := fmt.Sprintf("%s[%d]", path, i)
+			if i < len(s.itemsList) {
+				v.validate(s.itemsList[i], itemPath, item)
+			} else if s.additionalItems != nil {
+				v.validate(s.additionalItems, itemPath, item)
+			}
+		}
+	}
+	if s.contains != nil {
+		found := false
+		for _, item := range items {
+			if valid(s.contains, item) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.add(path, "contains", "must contain at least one matching item")
+		}
+	}
+}
+
+func (v *validator) object(s *Schema, path string, obj map[string]interface{}) {
+	if s.minProperties != nil && len(obj) < *s.minProperties {
+		v.add(path, "minProperties", "must have at least %d properties", *s.minProperties)
+	}
+	if s.maxProperties != nil && len(obj) > *s.maxProperties {
+		v.add(path, "maxProperties", "must have at most %d properties", *s.maxProperties)
+	}
+	for _, name := range s.required {
+		if _, ok := obj[name]; !ok {
+			v.add(join(path, name), "required", "is required")
+		}
+	}
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := obj[name]
+		propPath := join(path, name)
+		if s.propertyNames != nil && !valid(s.propertyNames, name) {
+			v.add(propPath, "propertyNames", "property name is not allowed")
+		}
+		matched := false
+		if sub, ok := s.properties[name]; ok {
+			v.validate(sub, propPath, value)
+			matched = true
+		}
+		for _, pp := range s.patternProperties {
+			if pp.pattern.MatchString(name) {
+				v.validate(pp.schema, propPath, value)
+				matched = true
+			}
+		}
+		if !matched && s.additionalProperties != nil {
+			if s.additionalProperties.always != nil && !*s.additionalProperties.always {
+				v.add(propPath, "additionalProperties", "property is not allowed")
+			} else {
+				v.validate(s.additionalProperties, propPath, value)
+			}
+		}
+		if dep, ok := s.dependencies[name]; ok {
+			for _, required := range dep.properties {
+				if _, ok := obj[required]; !ok {
+					v.add(join(path, required), "dependencies", "is required when %s is present", name)
+				}
+			}
+			if dep.schema != nil {
+				v.validate(dep.schema, path, obj)
+			}
+		}
+	}
+}
+
+// join 拼接对象属性路径
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// matchesType 判断值是否属于允许的类型之一
+func matchesType(types []string, value interface{}) bool {
+	actual := typeOf(value)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf 返回值的 JSON Schema 类型，整数值返回 integer
+func typeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func equal(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+func contains(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if equal(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	hostnamePattern = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`)
+	uuidPattern     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// checkFormat 校验 format 关键字，不认识的格式视为通过
+func checkFormat(format, str string) bool {
+	switch format {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, str)
+		return err == nil
+	case "date":
+		_, err := time.Parse("2006-01-02", str)
+		return err == nil
+	case "time":
+		_, err := time.Parse("15:04:05Z07:00", str)
+		return err == nil
+	case "email":
+		addr, err := mail.ParseAddress(str)
+		return err == nil && addr.Address == str
+	case "hostname":
+		return len(str) <= 253 && hostnamePattern.MatchString(str)
+	case "ipv4":
+		ip := net.ParseIP(str)
+		return ip != nil && ip.To4() != nil && !strings.Contains(str, ":")
+	case "ipv6":
+		ip := net.ParseIP(str)
+		return ip != nil && strings.Contains(str, ":")
+	case "uri":
+		u, err := url.Parse(str)
+		return err == nil && u.Scheme != ""
+	case "uri-reference":
+		_, err := url.Parse(str)
+		return err == nil
+	case "uuid":
+		return uuidPattern.MatchString(str)
+	case "regex":
+		_, err := regexp.Compile(str)
+		return err == nil
+	default:
+		return true
+	}
+}
+
+// Coerce 按 schema 的属性类型转换字符串参数（查询参数、路径参数、插件参数）
+// 属性类型为 integer/number/boolean 时解析为对应类型，为 array 时保留所有值，
+// 无法解析时保留原字符串，由 Validate 报告类型错误
+// 参数：
+//   - values map[string][]string: 参数名到参数值的映射
+// 返回值：
+//   - map[string]interface{}: 可以交给 Validate 校验的对象
+func (s *Schema) Coerce(values map[string][]string) map[string]interface{} {
+	for s != nil && s.ref != nil {
+		s = s.ref
+	}
+	obj := make(map[string]interface{}, len(values))
+	for name, vals := range values {
+		if len(vals) == 0 {
+			continue
+		}
+		var prop *Schema
+		if s != nil {
+			prop = s.properties[name]
+		}
+		prop = deref(prop)
+		if prop != nil && hasType(prop, "array") {
+			items := make([]interface{}, len(vals))
+			for i, val := range vals {
+				items[i] = coerceScalar(deref(prop.items), val)
+			}
+			obj[name] = items
+			continue
+		}
+		obj[name] = coerceScalar(prop, vals[0])
+	}
+	return obj
+}
+
+func deref(s *Schema) *Schema {
+	for s != nil && s.ref != nil {
+		s = s.ref
+	}
+	return s
+}
+
+func hasType(s *Schema, t string) bool {
+	for _, candidate := range s.types {
+		if candidate == t {
+			return true
+		}
+	}
+	return false
+}
+
+func coerceScalar(s *Schema, str string) interface{} {
+	if s == nil {
+		return str
+	}
+	if hasType(s, "string") {
+		return str
+	}
+	if hasType(s, "integer") || hasType(s, "number") {
+		if n, err := strconv.ParseFloat(str, 64); err == nil {
+			return n
+		}
+	}
+	if hasType(s, "boolean") {
+		if b, err := strconv.ParseBool(str); err == nil {
+			return b
+		}
+	}
+	if hasType(s, "null") && str == "" {
+		return nil
+	}
+	return str
+}
@@ -0,0 +1,221 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// errorKeys 将校验错误转换为 路径:关键字 列表，便于比较
+func errorKeys(errs []ValidationError) []string {
+	keys := make([]string, 0, len(errs))
+	for _, e := range errs {
+		keys = append(keys, e.Path+":"+e.Keyword)
+	}
+	return keys
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		value  string
+		want   []string
+	}{
+		// 类型
+		{name: "type ok", schema: `{"type": "string"}`, value: `"a"`},
+		{name: "type mismatch", schema: `{"type": "string"}`, value: `1`, want: []string{"body:type"}},
+		{name: "type list", schema: `{"type": ["string", "null"]}`, value: `null`},
+		{name: "integer accepts whole number", schema: `{"type": "integer"}`, value: `3.0`},
+		{name: "integer rejects fraction", schema: `{"type": "integer"}`, value: `3.5`, want: []string{"body:type"}},
+		{name: "number accepts integer", schema: `{"type": "number"}`, value: `3`},
+		{name: "boolean schema true", schema: `true`, value: `{"a": 1}`},
+		{name: "boolean schema false", schema: `false`, value: `1`, want: []string{"body:false"}},
+
+		// enum / const
+		{name: "enum ok", schema: `{"enum": ["a", 1, null]}`, value: `1`},
+		{name: "enum miss", schema: `{"enum": ["a", 1]}`, value: `"b"`, want: []string{"body:enum"}},
+		{name: "enum object", schema: `{"enum": [{"a": [1]}]}`, value: `{"a": [1]}`},
+		{name: "const ok", schema: `{"const": {"x": true}}`, value: `{"x": true}`},
+		{name: "const miss", schema: `{"const": "v1"}`, value: `"v2"`, want: []string{"body:const"}},
+
+		// 数值
+		{name: "minimum", schema: `{"minimum": 5}`, value: `4`, want: []string{"body:minimum"}},
+		{name: "minimum boundary", schema: `{"minimum": 5}`, value: `5`},
+		{name: "maximum", schema: `{"maximum": 5}`, value: `6`, want: []string{"body:maximum"}},
+		{name: "exclusiveMinimum", schema: `{"exclusiveMinimum": 5}`, value: `5`, want: []string{"body:exclusiveMinimum"}},
+		{name: "exclusiveMaximum", schema: `{"exclusiveMaximum": 5}`, value: `5`, want: []string{"body:exclusiveMaximum"}},
+		{name: "multipleOf", schema: `{"multipleOf": 0.1}`, value: `0.3`},
+		{name: "multipleOf miss", schema: `{"multipleOf": 3}`, value: `10`, want: []string{"body:multipleOf"}},
+		{name: "numeric keywords ignore strings", schema: `{"minimum": 5}`, value: `"1"`},
+
+		// 字符串
+		{name: "minLength counts runes", schema: `{"minLength": 2}`, value: `"中文"`},
+		{name: "minLength", schema: `{"minLength": 2}`, value: `"a"`, want: []string{"body:minLength"}},
+		{name: "maxLength", schema: `{"maxLength": 2}`, value: `"abc"`, want: []string{"body:maxLength"}},
+		{name: "pattern", schema: `{"pattern": "^[a-z]+$"}`, value: `"abc1"`, want: []string{"body:pattern"}},
+		{name: "pattern unanchored", schema: `{"pattern": "[0-9]"}`, value: `"abc1"`},
+
+		// format
+		{name: "date-time", schema: `{"format": "date-time"}`, value: `"2024-05-01T10:00:00Z"`},
+		{name: "date-time invalid", schema: `{"format": "date-time"}`, value: `"2024-05-01"`, want: []string{"body:format"}},
+		{name: "date", schema: `{"format": "date"}`, value: `"2024-02-30"`, want: []string{"body:format"}},
+		{name: "time", schema: `{"format": "time"}`, value: `"10:00:00+08:00"`},
+		{name: "email", schema: `{"format": "email"}`, value: `"a@example.com"`},
+		{name: "email with name", schema: `{"format": "email"}`, value: `"A <a@example.com>"`, want: []string{"body:format"}},
+		{name: "hostname", schema: `{"format": "hostname"}`, value: `"api.example.com"`},
+		{name: "hostname invalid", schema: `{"format": "hostname"}`, value: `"-bad.example.com"`, want: []string{"body:format"}},
+		{name: "ipv4", schema: `{"format": "ipv4"}`, value: `"192.0.2.1"`},
+		{name: "ipv4 rejects ipv6", schema: `{"format": "ipv4"}`, value: `"::ffff:192.0.2.1"`, want: []string{"body:format"}},
+		{name: "ipv6", schema: `{"format": "ipv6"}`, value: `"2001:db8::1"`},
+		{name: "ipv6 rejects ipv4", schema: `{"format": "ipv6"}`, value: `"192.0.2.1"`, want: []string{"body:format"}},
+		{name: "uri", schema: `{"format": "uri"}`, value: `"https://example.com/a"`},
+		{name: "uri requires scheme", schema: `{"format": "uri"}`, value: `"/a"`, want: []string{"body:format"}},
+		{name: "uri-reference", schema: `{"format": "uri-reference"}`, value: `"/a?b=1"`},
+		{name: "uuid", schema: `{"format": "uuid"}`, value: `"123e4567-e89b-12d3-a456-426614174000"`},
+		{name: "uuid invalid", schema: `{"format": "uuid"}`, value: `"123e4567"`, want: []string{"body:format"}},
+		{name: "regex invalid", schema: `{"format": "regex"}`, value: `"("`, want: []string{"body:format"}},
+		{name: "unknown format passes", schema: `{"format": "color"}`, value: `"red"`},
+
+		// 数组
+		{name: "minItems", schema: `{"minItems": 2}`, value: `[1]`, want: []string{"body:minItems"}},
+		{name: "maxItems", schema: `{"maxItems": 1}`, value: `[1, 2]`, want: []string{"body:maxItems"}},
+		{name: "uniqueItems", schema: `{"uniqueItems": true}`, value: `[{"a": 1}, {"a": 1}]`, want: []string{"body:uniqueItems"}},
+		{name: "items", schema: `{"items": {"type": "integer"}}`, value: `[1, "x", 3, "y"]`, want: []string{"body[1]:type", "body[3]:type"}},
+		{name: "tuple items", schema: `{"items": [{"type": "string"}, {"type": "integer"}]}`, value: `["a", "b", true]`, want: []string{"body[1]:type"}},
+		{name: "additionalItems", schema: `{"items": [{"type": "string"}], "additionalItems": false}`, value: `["a", 1]`, want: []string{"body[1]:false"}},
+		{name: "contains", schema: `{"contains": {"const": 3}}`, value: `[1, 2, 3]`},
+		{name: "contains miss", schema: `{"contains": {"const": 3}}`, value: `[1, 2]`, want: []string{"body:contains"}},
+
+		// 对象
+		{name: "required", schema: `{"required": ["id", "name"]}`, value: `{"id": 1}`, want: []string{"body.name:required"}},
+		{name: "properties", schema: `{"properties": {"id": {"type": "integer"}}}`, value: `{"id": "1"}`, want: []string{"body.id:type"}},
+		{name: "nested path", schema: `{"properties": {"items": {"items": {"required": ["name"]}}}}`, value: `{"items": [{"name": "a"}, {}]}`, want: []string{"body.items[1].name:required"}},
+		{name: "additionalProperties false", schema: `{"properties": {"a": true}, "additionalProperties": false}`, value: `{"a": 1, "b": 2}`, want: []string{"body.b:additionalProperties"}},
+		{name: "additionalProperties schema", schema: `{"additionalProperties": {"type": "string"}}`, value: `{"a": "x", "b": 2}`, want: []string{"body.b:type"}},
+		{name: "patternProperties", schema: `{"patternProperties": {"^x-": {"type": "string"}}, "additionalProperties": false}`, value: `{"x-a": 1, "y": 2}`, want: []string{"body.x-a:type", "body.y:additionalProperties"}},
+		{name: "minProperties", schema: `{"minProperties": 1}`, value: `{}`, want: []string{"body:minProperties"}},
+		{name: "maxProperties", schema: `{"maxProperties": 1}`, value: `{"a": 1, "b": 2}`, want: []string{"body:maxProperties"}},
+		{name: "propertyNames", schema: `{"propertyNames": {"pattern": "^[a-z]+$"}}`, value: `{"ok": 1, "Bad": 2}`, want: []string{"body.Bad:propertyNames"}},
+		{name: "dependencies list", schema: `{"dependencies": {"card": ["cvv"]}}`, value: `{"card": "4111"}`, want: []string{"body.cvv:dependencies"}},
+		{name: "dependencies schema", schema: `{"dependencies": {"card": {"required": ["cvv"]}}}`, value: `{"card": "4111"}`, want: []string{"body.cvv:required"}},
+
+		// 组合
+		{name: "allOf", schema: `{"allOf": [{"minimum": 1}, {"maximum": 3}]}`, value: `5`, want: []string{"body:maximum"}},
+		{name: "anyOf", schema: `{"anyOf": [{"type": "string"}, {"type": "integer"}]}`, value: `1`},
+		{name: "anyOf miss", schema: `{"anyOf": [{"type": "string"}, {"type": "integer"}]}`, value: `true`, want: []string{"body:anyOf"}},
+		{name: "oneOf", schema: `{"oneOf": [{"type": "integer"}, {"minimum": 10}]}`, value: `5`},
+		{name: "oneOf matches both", schema: `{"oneOf": [{"type": "integer"}, {"minimum": 10}]}`, value: `15`, want: []string{"body:oneOf"}},
+		{name: "oneOf matches none", schema: `{"oneOf": [{"type": "integer"}, {"minimum": 10}]}`, value: `5.5`, want: []string{"body:oneOf"}},
+		{name: "not", schema: `{"not": {"type": "null"}}`, value: `null`, want: []string{"body:not"}},
+		{name: "if then", schema: `{"if": {"properties": {"kind": {"const": "card"}}}, "then": {"required": ["number"]}, "else": {"required": ["iban"]}}`, value: `{"kind": "card"}`, want: []string{"body.number:required"}},
+		{name: "if else", schema: `{"if": {"properties": {"kind": {"const": "card"}}}, "then": {"required": ["number"]}, "else": {"required": ["iban"]}}`, value: `{"kind": "bank"}`, want: []string{"body.iban:required"}},
+
+		// $ref
+		{name: "ref definitions", schema: `{"definitions": {"id": {"type": "integer"}}, "properties": {"id": {"$ref": "#/definitions/id"}}}`, value: `{"id": "x"}`, want: []string{"body.id:type"}},
+		{name: "ref ignores siblings", schema: `{"definitions": {"any": true}, "properties": {"a": {"$ref": "#/definitions/any", "type": "string"}}}`, value: `{"a": 1}`},
+		{name: "ref escaped pointer", schema: `{"definitions": {"a/b": {"type": "string"}}, "$ref": "#/definitions/a~1b"}`, value: `1`, want: []string{"body:type"}},
+		{name: "recursive ref", schema: `{"type": "object", "properties": {"name": {"type": "string"}, "children": {"type": "array", "items": {"$ref": "#"}}}}`, value: `{"name": "a", "children": [{"name": "b", "children": [{"name": 3}]}]}`, want: []string{"body.children[0].children[0].name:type"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Compile([]byte(tt.schema))
+			if err != nil {
+				t.Fatalf("compile: %v", err)
+			}
+			var value interface{}
+			if err := json.Unmarshal([]byte(tt.value), &value); err != nil {
+				t.Fatal(err)
+			}
+			got := errorKeys(s.Validate("body", value))
+			want := tt.want
+			if want == nil {
+				want = []string{}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("errors = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateErrorLimit(t *testing.T) {
+	s, err := Compile([]byte(`{"items": {"type": "string"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := make([]interface{}, maxErrors+10)
+	for i := range items {
+		items[i] = float64(i)
+	}
+	if errs := s.Validate("body", items); len(errs) != maxErrors {
+		t.Fatalf("got %d errors, want %d", len(errs), maxErrors)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+	}{
+		{name: "invalid json", schema: `{"type": }`},
+		{name: "not an object", schema: `"string"`},
+		{name: "invalid pattern", schema: `{"pattern": "("}`},
+		{name: "invalid patternProperties", schema: `{"patternProperties": {"(": true}}`},
+		{name: "unresolved ref", schema: `{"$ref": "#/definitions/missing"}`},
+		{name: "external ref without file", schema: `{"$ref": "other.json#/a"}`},
+		{name: "non pointer fragment", schema: `{"$ref": "#anchor"}`},
+		{name: "invalid subschema", schema: `{"properties": {"a": 1}}`},
+		{name: "invalid minimum", schema: `{"minimum": "1"}`},
+		{name: "invalid minLength", schema: `{"minLength": 1.5}`},
+		{name: "invalid required", schema: `{"required": "id"}`},
+		{name: "invalid type", schema: `{"type": "text"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Compile([]byte(tt.schema)); err == nil {
+				t.Fatal("compile succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestCoerce(t *testing.T) {
+	s, err := Compile([]byte(`{
+		"definitions": {"page": {"type": "integer"}},
+		"properties": {
+			"page": {"$ref": "#/definitions/page"},
+			"ratio": {"type": "number"},
+			"active": {"type": "boolean"},
+			"tags": {"type": "array", "items": {"type": "integer"}},
+			"name": {"type": "string"}
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := s.Coerce(map[string][]string{
+		"page":   {"2"},
+		"ratio":  {"0.5"},
+		"active": {"true"},
+		"tags":   {"1", "x"},
+		"name":   {"42"},
+		"extra":  {"v"},
+		"empty":  {},
+	})
+	want := map[string]interface{}{
+		"page":   2.0,
+		"ratio":  0.5,
+		"active": true,
+		"tags":   []interface{}{1.0, "x"},
+		"name":   "42",
+		"extra":  "v",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Coerce = %#v, want %#v", got, want)
+	}
+	// 无法转换的值保留为字符串，由 Validate 报告类型错误
+	if errs := errorKeys(s.Validate("query", got)); !reflect.DeepEqual(errs, []string{"query.tags[1]:type"}) {
+		t.Fatalf("errors = %v", errs)
+	}
+}
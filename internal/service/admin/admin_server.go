@@ -0,0 +1,110 @@
+package admin
+
+import (
+	"bigHammer/internal/config"
+	"bigHammer/pkg/utils"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// StartAdminServer 启动管理接口
+// 功能：
+// 1. 在 admin.socket_path（Unix Socket）或 admin.host:admin.port 上监听
+// 2. 所有请求需要携带 admin.token 访问令牌
+// 3. 上下文取消时停止服务
+// 参数：
+//   - ctx context.Context: 用于停止服务的上下文
+//   - cfg config.AdminConfig: 管理接口配置
+// 返回值：无
+func StartAdminServer(ctx context.Context, cfg config.AdminConfig) {
+	if !cfg.Enabled {
+		return
+	}
+	if cfg.Token == "" {
+		log.Println("Admin token is not configured, admin server not started.")
+		return
+	}
+
+	listener, address, err := listen(cfg)
+	if err != nil {
+		log.Println("Error starting admin server:", err)
+		return
+	}
+	server := &http.Server{Handler: requireToken(cfg.Token, NewHandler())}
+	log.Println("Admin server listening on " + address)
+	go func() {
+		if err := server.Serve(listener); err != http.ErrServerClosed {
+			log.Println("Admin server stopped:", err)
+		}
+	}()
+
+	<-ctx.Done()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Admin server Shutdown: %v", err)
+	}
+}
+
+// listen 创建管理接口监听器，Unix Socket 权限为 0600
+func listen(cfg config.AdminConfig) (net.Listener, string, error) {
+	if cfg.SocketPath != "" {
+		path, err := utils.ResolvePath(cfg.SocketPath)
+		if err != nil {
+			return nil, "", err
+		}
+		os.Remove(path)
+		listener, err := net.Listen("unix", path)
+		if err != nil {
+			return nil, "", err
+		}
+		if err := os.Chmod(path, 0600); err != nil {
+			listener.Close()
+			return nil, "", err
+		}
+		return listener, "unix:" + path, nil
+	}
+	host := cfg.Host
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	address := net.JoinHostPort(host, cfg.Port)
+	listener, err := net.Listen("tcp", address)
+	return listener, address, err
+}
+
+// requireToken 校验访问令牌，支持 Authorization: Bearer <token> 和 X-Admin-Token 请求头
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		provided := req.Header.Get("X-Admin-Token")
+		if authz := req.Header.Get("Authorization"); strings.HasPrefix(authz, "Bearer ") {
+			provided = strings.TrimPrefix(authz, "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			writeJSON(w, http.StatusUnauthorized, "Invalid admin token", nil)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// writeJSON 以与插件 Response 相同的结构写入响应
+func writeJSON(w http.ResponseWriter, status int, message string, data interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  status,
+		"message": message,
+		"data":    data,
+	})
+}
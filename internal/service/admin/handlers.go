@@ -0,0 +1,188 @@
+package admin
+
+import (
+	"bigHammer/internal/cache"
+	ipc "bigHammer/internal/ipc/socket"
+	"bigHammer/internal/plugin"
+	"bigHammer/internal/resilience"
+	"bigHammer/internal/router"
+	"bigHammer/internal/shared"
+	"bigHammer/internal/upstream"
+	"bigHammer/pkg/utils"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// NewHandler 创建管理接口路由
+// 查询：
+//   - GET /routes: 当前生效的路由配置
+//   - GET /plugins: 已注册的插件
+//   - GET /services: 依赖注入容器中的服务
+//   - GET /ipc/connections: 业务进程到网关Socket服务的连接
+//   - GET /ipc/pending: 未完成的异步IPC请求
+//   - GET /backends: 后端端点状态
+//   - GET /breakers: 熔断器状态
+//   - GET /cache: 各路由缓存条目数
+//   - GET /metrics: Prometheus 指标
+// 操作：
+//   - POST /reload: 重新加载 config.json 和 router.json
+//   - POST /workers/restart: 重启业务进程
+//   - POST /backends/{name}/drain、/backends/{name}/undrain: 摘除或恢复端点，?endpoint= 指定端点，默认全部
+//   - POST /cache/purge: 清空缓存，?route= 指定路由，默认全部
+func NewHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /routes", listRoutes)
+	mux.HandleFunc("GET /plugins", listPlugins)
+	mux.HandleFunc("GET /services", listServices)
+	mux.HandleFunc("GET /ipc/connections", func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, http.StatusOK, "OK", ipc.Connections())
+	})
+	mux.HandleFunc("GET /ipc/pending", func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, http.StatusOK, "OK", ipc.PendingAsync())
+	})
+	mux.HandleFunc("GET /backends", listBackends)
+	mux.HandleFunc("GET /breakers", func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, http.StatusOK, "OK", resilience.Breakers())
+	})
+	mux.HandleFunc("GET /cache", func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, http.StatusOK, "OK", cache.Stats())
+	})
+	mux.Handle("GET /metrics", promhttp.Handler())
+	mux.HandleFunc("POST /reload", reload)
+	mux.HandleFunc("POST /workers/restart", restartWorkers)
+	mux.HandleFunc("POST /backends/{name}/drain", drain(true))
+	mux.HandleFunc("POST /backends/{name}/undrain", drain(false))
+	mux.HandleFunc("POST /cache/purge", purgeCache)
+	return mux
+}
+
+func listRoutes(w http.ResponseWriter, req *http.Request) {
+	r := router.Active()
+	if r == nil {
+		writeJSON(w, http.StatusServiceUnavailable, "Router not loaded", nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, "OK", map[string]interface{}{
+		"routes":      r.AllRoutes(),
+		"middlewares": r.Middlewares,
+		"backends":    r.Backends,
+	})
+}
+
+func listPlugins(w http.ResponseWriter, req *http.Request) {
+	type pluginInfo struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	}
+	plugins := make([]pluginInfo, 0, len(plugin.Plugins))
+	for name, p := range plugin.Plugins {
+		info := pluginInfo{Name: name}
+		if p != nil {
+			info.Type = reflect.TypeOf(p).String()
+		}
+		plugins = append(plugins, info)
+	}
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+	writeJSON(w, http.StatusOK, "OK", plugins)
+}
+
+func listServices(w http.ResponseWriter, req *http.Request) {
+	if shared.GlobalContainer == nil {
+		writeJSON(w, http.StatusOK, "OK", []interface{}{})
+		return
+	}
+	writeJSON(w, http.StatusOK, "OK", shared.GlobalContainer.Services())
+}
+
+func listBackends(w http.ResponseWriter, req *http.Request) {
+	backends := make(map[string][]upstream.EndpointStatus)
+	for name, pool := range upstream.Pools() {
+		backends[name] = pool.Status()
+	}
+	writeJSON(w, http.StatusOK, "OK", backends)
+}
+
+// reload 重新加载配置和路由，失败时保留旧路由
+func reload(w http.ResponseWriter, req *http.Request) {
+	db, err := shared.ResolveDatabase()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+	r, err := router.Reload(db)
+	if err != nil {
+		log.Println("管理接口重新加载路由失败:", err)
+		writeJSON(w, http.StatusUnprocessableEntity, "Reload failed: "+err.Error(), nil)
+		return
+	}
+	routes := len(r.AllRoutes())
+	log.Printf("管理接口重新加载路由成功，共 %d 条路由", routes)
+	writeJSON(w, http.StatusOK, "Reloaded", map[string]interface{}{"routes": routes})
+}
+
+// restartWorkers 通过文件监视器重启业务进程
+func restartWorkers(w http.ResponseWriter, req *http.Request) {
+	if shared.GlobalContainer == nil {
+		writeJSON(w, http.StatusServiceUnavailable, "Worker supervisor not available", nil)
+		return
+	}
+	service, err := shared.GlobalContainer.Resolve("watcher")
+	if err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, "Worker supervisor not available", nil)
+		return
+	}
+	watcher, ok := service.(*utils.Watcher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, "Unexpected watcher service type", nil)
+		return
+	}
+	pid, err := watcher.Restart()
+	if err != nil {
+		log.Println("管理接口重启业务进程失败:", err)
+		writeJSON(w, http.StatusInternalServerError, "Restart failed: "+err.Error(), nil)
+		return
+	}
+	log.Printf("管理接口重启业务进程，PID: %d", pid)
+	writeJSON(w, http.StatusOK, "Restarted", map[string]interface{}{"pid": pid})
+}
+
+// drain 返回摘除（drained 为 true）或恢复端点的处理器
+func drain(drained bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		name := req.PathValue("name")
+		pool, ok := upstream.Get(name)
+		if !ok {
+			writeJSON(w, http.StatusNotFound, fmt.Sprintf("Unknown backend %s", name), nil)
+			return
+		}
+		changed, err := pool.SetDrained(req.URL.Query().Get("endpoint"), drained)
+		if errors.Is(err, upstream.ErrUnknownEndpoint) {
+			writeJSON(w, http.StatusNotFound, "Unknown endpoint", nil)
+			return
+		}
+		action := "undrained"
+		if drained {
+			action = "drained"
+		}
+		log.Printf("管理接口 %s 后端 %s 的 %d 个端点", action, name, changed)
+		writeJSON(w, http.StatusOK, "OK", map[string]interface{}{
+			"changed":   changed,
+			"endpoints": pool.Status(),
+		})
+	}
+}
+
+func purgeCache(w http.ResponseWriter, req *http.Request) {
+	purged, err := cache.Purge(req.URL.Query().Get("route"))
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, err.Error(), nil)
+		return
+	}
+	writeJSON(w, http.StatusOK, "OK", map[string]interface{}{"purged": purged})
+}
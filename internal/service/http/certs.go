@@ -0,0 +1,325 @@
+package http
+
+import (
+	"bigHammer/internal/config"
+	"bigHammer/pkg/utils"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// selfSignedValidity 自签名证书有效期
+const selfSignedValidity = 365 * 24 * time.Hour
+
+// selfSignedRenewBefore 自签名证书在到期前多久自动续签
+const selfSignedRenewBefore = 30 * 24 * time.Hour
+
+// certEntry 已加载的证书及其文件路径
+type certEntry struct {
+	certFile string
+	keyFile  string
+	hosts    []string
+	cert     *tls.Certificate
+}
+
+// CertManager 证书管理器
+// 负责加载证书、按SNI选择证书，并在证书文件变更时无需重启即可重新加载
+type CertManager struct {
+	mu         sync.RWMutex
+	entries    []*certEntry
+	selfSigned bool
+}
+
+// NewCertManager 根据TLS配置创建证书管理器
+// 功能：
+// 1. 解析证书路径
+// 2. self_signed 模式下为缺失或即将过期的证书生成本地自签名证书
+// 3. 加载所有证书
+// 参数：
+//   - cfg config.TLSConfig: TLS配置
+// 返回值：
+//   - *CertManager: 证书管理器
+//   - error: 证书加载失败时返回的错误信息
+func NewCertManager(cfg config.TLSConfig) (*CertManager, error) {
+	if len(cfg.Certificates) == 0 {
+		return nil, errors.New("tls enabled but no certificates configured")
+	}
+	m := &CertManager{selfSigned: cfg.SelfSigned}
+	for _, c := range cfg.Certificates {
+		certFile, err := utils.ResolvePath(c.CertFile)
+		if err != nil {
+			return nil, fmt.Errorf("error resolving cert path: %v", err)
+		}
+		keyFile, err := utils.ResolvePath(c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error resolving key path: %v", err)
+		}
+		entry := &certEntry{certFile: certFile, keyFile: keyFile, hosts: c.Hosts}
+		if err := m.load(entry); err != nil {
+			return nil, err
+		}
+		m.entries = append(m.entries, entry)
+	}
+	return m, nil
+}
+
+// load 加载单个证书，必要时先生成自签名证书
+func (m *CertManager) load(entry *certEntry) error {
+	if m.selfSigned && needsSelfSigned(entry.certFile) {
+		if err := generateSelfSigned(entry.certFile, entry.keyFile, entry.hosts); err != nil {
+			return fmt.Errorf("error generating self-signed certificate: %v", err)
+		}
+		log.Println("已生成自签名证书:", entry.certFile)
+	}
+
+	cert, err := tls.LoadX509KeyPair(entry.certFile, entry.keyFile)
+	if err != nil {
+		return fmt.Errorf("error loading certificate %s: %v", entry.certFile, err)
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return fmt.Errorf("error parsing certificate %s: %v", entry.certFile, err)
+		}
+	}
+
+	m.mu.Lock()
+	entry.cert = &cert
+	m.mu.Unlock()
+	return nil
+}
+
+// GetCertificate 实现 tls.Config.GetCertificate，按SNI选择证书
+// 匹配顺序：精确域名 → 通配符域名 → 第一个证书
+func (m *CertManager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	name := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+	if name != "" {
+		// 先查找精确包含该域名的证书，配置顺序在前的通配符证书不会覆盖精确证书
+		for _, entry := range m.entries {
+			if hasExactName(entry.cert.Leaf, name) {
+				return entry.cert, nil
+			}
+		}
+		for _, entry := range m.entries {
+			if entry.cert.Leaf.VerifyHostname(name) == nil {
+				return entry.cert, nil
+			}
+		}
+	}
+	return m.entries[0].cert, nil
+}
+
+// hasExactName 判断证书的 SAN 是否精确包含域名（不含通配符匹配）
+func hasExactName(leaf *x509.Certificate, name string) bool {
+	for _, dnsName := range leaf.DNSNames {
+		if strings.ToLower(strings.TrimSuffix(dnsName, ".")) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Watch 监视证书文件变化并自动重新加载
+// 证书通常以替换文件的方式更新，因此监视所在目录而不是文件本身
+func (m *CertManager) Watch(ctx context.Context) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Println("创建证书监视器失败:", err)
+		return
+	}
+	defer watcher.Close()
+
+	dirs := make(map[string]bool)
+	for _, entry := range m.entries {
+		for _, file := range []string{entry.certFile, entry.keyFile} {
+			dir := filepath.Dir(file)
+			if dirs[dir] {
+				continue
+			}
+			dirs[dir] = true
+			if err := watcher.Add(dir); err != nil {
+				log.Printf("监视证书目录 %s 失败: %v", dir, err)
+			}
+		}
+	}
+
+	// 续签检查，self_signed 模式下自动替换即将过期的证书
+	renewTicker := time.NewTicker(12 * time.Hour)
+	defer renewTicker.Stop()
+
+	// 证书和私钥通常先后写入，延迟合并多次事件后再加载
+	var reload <-chan time.Time
+	for {
+		select {
+		case event := <-watcher.Events:
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 && m.watches(event.Name) {
+				reload = time.After(500 * time.Millisecond)
+			}
+		case err := <-watcher.Errors:
+			log.Println("证书监视器错误:", err)
+		case <-reload:
+			reload = nil
+			m.reloadAll()
+		case <-renewTicker.C:
+			if m.selfSigned {
+				m.reloadAll()
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// watches 判断文件是否为受管证书或私钥
+func (m *CertManager) watches(name string) bool {
+	name = filepath.Clean(name)
+	for _, entry := range m.entries {
+		if name == entry.certFile || name == entry.keyFile {
+			return true
+		}
+	}
+	return false
+}
+
+// reloadAll 重新加载所有证书，失败时保留旧证书继续服务
+func (m *CertManager) reloadAll() {
+	for _, entry := range m.entries {
+		if err := m.load(entry); err != nil {
+			log.Println("重新加载证书失败，继续使用旧证书:", err)
+			continue
+		}
+		log.Println("证书已重新加载:", entry.certFile)
+	}
+}
+
+// needsSelfSigned 判断证书文件是否缺失或即将过期
+func needsSelfSigned(certFile string) bool {
+	content, err := os.ReadFile(certFile)
+	if err != nil {
+		return true
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return true
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return true
+	}
+	return time.Until(cert.NotAfter) < selfSignedRenewBefore
+}
+
+// generateSelfSigned 生成ECDSA P-256自签名证书并写入文件
+func generateSelfSigned(certFile, keyFile string, hosts []string) error {
+	if len(hosts) == 0 {
+		hosts = []string{"localhost", "127.0.0.1"}
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: hosts[0], Organization: []string{"bigHammer local"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(certFile), 0755); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(keyFile), 0700); err != nil {
+		return err
+	}
+	// 先写私钥再写证书，证书监视器在证书变化后加载时私钥已就绪
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return err
+	}
+	return os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+}
+
+// BuildTLSConfig 根据配置构造 tls.Config
+// 功能：
+// 1. 设置最低TLS版本（默认1.2）
+// 2. 按名称设置TLS 1.2加密套件（TLS 1.3套件不可配置）
+// 3. 启用HTTP/2（h2）和HTTP/1.1的ALPN协商
+// 参数：
+//   - cfg config.TLSConfig: TLS配置
+//   - certs *CertManager: 证书管理器
+// 返回值：
+//   - *tls.Config: TLS配置
+//   - error: 版本或套件名称无效时返回的错误信息
+func BuildTLSConfig(cfg config.TLSConfig, certs *CertManager) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		GetCertificate: certs.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+
+	switch cfg.MinVersion {
+	case "", "1.2":
+		tlsConfig.MinVersion = tls.VersionTLS12
+	case "1.3":
+		tlsConfig.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("unsupported tls min_version %q", cfg.MinVersion)
+	}
+
+	if len(cfg.CipherSuites) > 0 {
+		available := make(map[string]uint16)
+		for _, suite := range tls.CipherSuites() {
+			available[suite.Name] = suite.ID
+		}
+		for _, name := range cfg.CipherSuites {
+			id, ok := available[name]
+			if !ok {
+				return nil, fmt.Errorf("unsupported or insecure cipher suite %q", name)
+			}
+			tlsConfig.CipherSuites = append(tlsConfig.CipherSuites, id)
+		}
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,55 @@
+package http
+
+import (
+	"crypto/tls"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetCertificate(t *testing.T) {
+	dir := t.TempDir()
+	m := &CertManager{}
+	// 通配符证书配置在精确证书之前
+	for _, c := range []struct {
+		name  string
+		hosts []string
+	}{
+		{"wildcard", []string{"*.example.com"}},
+		{"exact", []string{"api.example.com"}},
+		{"other", []string{"other.test"}},
+	} {
+		entry := &certEntry{
+			certFile: filepath.Join(dir, c.name+".crt"),
+			keyFile:  filepath.Join(dir, c.name+".key"),
+			hosts:    c.hosts,
+		}
+		if err := generateSelfSigned(entry.certFile, entry.keyFile, entry.hosts); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.load(entry); err != nil {
+			t.Fatal(err)
+		}
+		m.entries = append(m.entries, entry)
+	}
+
+	tests := []struct {
+		serverName string
+		want       string
+	}{
+		{"api.example.com", "api.example.com"},
+		{"API.example.com.", "api.example.com"},
+		{"www.example.com", "*.example.com"},
+		{"other.test", "other.test"},
+		{"unknown.test", "*.example.com"},
+		{"", "*.example.com"},
+	}
+	for _, tt := range tests {
+		cert, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: tt.serverName})
+		if err != nil {
+			t.Fatalf("%q: %v", tt.serverName, err)
+		}
+		if got := cert.Leaf.DNSNames[0]; got != tt.want {
+			t.Errorf("GetCertificate(%q) = %s, want %s", tt.serverName, got, tt.want)
+		}
+	}
+}
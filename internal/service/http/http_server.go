@@ -1,18 +1,26 @@
 package http
 
 import (
+	"bigHammer/internal/accesslog"
+	"bigHammer/internal/config"
+	"bigHammer/internal/lifecycle"
+	"bigHammer/internal/openapi"
 	"bigHammer/internal/plugin/agilitymemdb"
 	"bigHammer/internal/router"
 	"bigHammer/internal/shared"
+	"bigHammer/internal/sse"
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"sync"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func StartHTTPServer(ctx context.Context, httpPort string) { // 添加一个上下文参数用于取消操作
-	var loadedRouter router.Router // 使用 router 包中的 Router 类型
 	var err error
 
 	if err != nil {
@@ -29,16 +37,76 @@ func StartHTTPServer(ctx context.Context, httpPort string) { // 添加一个上
 	if !ok {
 		log.Fatal("The provided db service does not match the expected type.")
 	}
-	loadedRouter, _ = router.LoadRouterConfig() // 使用 router 包中的 LoadRouterConfig 函数
-	// 设置数据库实例到路由器
-	loadedRouter.DB = dbInstance
+	// 访问日志：每个请求输出一条结构化记录
+	if err := accesslog.Init(config.GlobalConfig.AccessLog); err != nil {
+		log.Println("Error initializing access log:", err)
+		return
+	}
+	// 加载并编译路由，之后可以通过管理接口重新加载
+	if _, err := router.Reload(dbInstance); err != nil {
+		log.Println("Error building router middleware chain:", err)
+		return
+	}
 	log.Println("Router loaded successfully.")
-	server := &http.Server{Addr: ":" + httpPort}  
-	
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		router.Active().HandleHTTP(w, req)
+	})
+	// 网关自身的端点不经过路由表，但仍受全局IP访问控制约束；router.Build 拒绝与这些路径相同的路由
+	// Prometheus指标默认只在管理接口上提供，配置了 metrics_path 时才在业务端口上暴露
+	if metricsPath := config.GlobalConfig.MetricsPath; metricsPath != "" {
+		mux.Handle(metricsPath, systemHandler(promhttp.Handler()))
+	}
+	// 就绪和存活检查：排空期间就绪检查返回503，供负载均衡摘除流量
+	shutdownCfg := config.GlobalConfig.Shutdown
+	mux.Handle(shutdownCfg.ReadinessPath(), systemHandler(lifecycle.ReadinessHandler()))
+	mux.Handle(shutdownCfg.LivenessPath(), systemHandler(lifecycle.LivenessHandler()))
+	// 提供根据路由配置生成的OpenAPI文档
+	if openapiCfg := config.GlobalConfig.OpenAPI; !openapiCfg.Disabled {
+		mux.Handle(openapiCfg.DocumentPath(), systemHandler(openAPIHandler()))
+	}
 
-	// 假设 Router 类型有一个 handleHTTP 方法
-	http.HandleFunc("/", loadedRouter.HandleHTTP)
-	log.Println("HTTP server listening on port:"+ httpPort)
+	servers := []*http.Server{}
+	tlsCfg := config.GlobalConfig.TLS
+	httpsPort := config.GlobalConfig.Ports.HTTPSPort
+
+	// 启用HTTPS时创建TLS监听，证书文件变更时自动重新加载
+	if tlsCfg.Enabled {
+		certs, err := NewCertManager(tlsCfg)
+		if err != nil {
+			log.Println("Error loading TLS certificates:", err)
+			return
+		}
+		tlsConfig, err := BuildTLSConfig(tlsCfg, certs)
+		if err != nil {
+			log.Println("Error building TLS config:", err)
+			return
+		}
+		go certs.Watch(ctx)
+
+		httpsServer := &http.Server{Addr: ":" + httpsPort, Handler: mux, TLSConfig: tlsConfig}
+		servers = append(servers, httpsServer)
+		log.Println("HTTPS server listening on port:" + httpsPort)
+		go func() {
+			if err := httpsServer.ListenAndServeTLS("", ""); err != http.ErrServerClosed {
+				log.Fatal("Error starting HTTPS server:", err)
+			}
+		}()
+	}
+
+	// HTTP监听：开启重定向时将请求跳转到HTTPS，就绪和存活检查除外，
+	// 使只能发送明文HTTP的探针仍然得到真实的检查结果
+	var httpHandler http.Handler = mux
+	if tlsCfg.Enabled && tlsCfg.RedirectHTTP {
+		probes := http.NewServeMux()
+		probes.Handle(shutdownCfg.ReadinessPath(), mux)
+		probes.Handle(shutdownCfg.LivenessPath(), mux)
+		probes.Handle("/", redirectToHTTPS(httpsPort))
+		httpHandler = probes
+	}
+	server := &http.Server{Addr: ":" + httpPort, Handler: httpHandler}
+	servers = append(servers, server)
+	log.Println("HTTP server listening on port:" + httpPort)
 
 	go func() {
 		if err := server.ListenAndServe(); err != http.ErrServerClosed {
@@ -48,12 +116,68 @@ func StartHTTPServer(ctx context.Context, httpPort string) { // 添加一个上
 
 	<-ctx.Done() // 等待上下文被取消
 
-	// 创建一个新的超时上下文用于服务器关闭
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// 停止接收新连接，等待在途请求完成，最长到排空截止时间
+	shutdownCtx, cancel := lifecycle.DrainContext(5 * time.Second)
 	defer cancel()
 
 	// 尝试优雅地关闭服务器
-	if err := server.Shutdown(shutdownCtx); err != nil {
-		log.Printf("HTTP server Shutdown: %v", err)
+	// SSE长连接不会自行结束，Shutdown 开始时关闭所有订阅，避免排空等待到超时
+	for _, srv := range servers {
+		srv.RegisterOnShutdown(sse.DefaultBroker.CloseAll)
+	}
+	for _, srv := range servers {
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("HTTP server Shutdown: %v", err)
+		}
 	}
 }
+
+// systemHandler 使用当前生效路由的全局IP访问控制保护网关自身的端点
+func systemHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		router.Active().ServeSystem(w, req, handler)
+	})
+}
+
+// openAPIHandler 返回提供OpenAPI文档的处理器
+// 文档根据当前生效的路由生成，路由重新加载后在下一次请求时重新生成
+func openAPIHandler() http.Handler {
+	var mu sync.Mutex
+	var built *router.Router
+	var handler http.Handler
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r := router.Active()
+		mu.Lock()
+		if r != built {
+			doc, err := openapi.Generate(r, config.GlobalConfig.OpenAPI)
+			if err == nil {
+				handler, err = openapi.Handler(doc)
+			}
+			if err != nil {
+				mu.Unlock()
+				log.Println("Error generating OpenAPI document:", err)
+				http.Error(w, "内部服务器错误", http.StatusInternalServerError)
+				return
+			}
+			built = r
+		}
+		h := handler
+		mu.Unlock()
+		h.ServeHTTP(w, req)
+	})
+}
+
+// redirectToHTTPS 返回将请求永久重定向到HTTPS的处理器
+func redirectToHTTPS(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		host := req.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != "" && httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+		target := "https://" + host + req.URL.RequestURI()
+		http.Redirect(w, req, target, http.StatusPermanentRedirect)
+	})
+}
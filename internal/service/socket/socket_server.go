@@ -12,6 +12,8 @@ import (
 )
 
 // StartSocketServer 启动socket服务器
+// 上下文取消时停止接收新连接，并关闭已有的IPC连接
+// 调用方应在在途IPC调用和异步请求排空之后再取消上下文
 func StartSocketServer(ctx context.Context) {
 
 	err := config.LoadConfig()
@@ -31,6 +33,9 @@ func StartSocketServer(ctx context.Context) {
 		<-ctx.Done()
 		log.Println("Received ctx.Done() signal, closing listener")
 		listener.Close()
+		if n := ipc.CloseConnections(); n > 0 {
+			log.Printf("Closed %d IPC connections", n)
+		}
 	}()
 
 	log.Println("Listening on Unix socket ...", configFilePath)
@@ -0,0 +1,196 @@
+package websocket
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrSendQueueFull 连接发送队列已满（客户端读取过慢）
+var ErrSendQueueFull = errors.New("websocket: send queue full")
+
+// ErrClosed 连接已关闭
+var ErrClosed = errors.New("websocket: connection closed")
+
+// outbound 待发送的消息
+type outbound struct {
+	opcode  byte
+	payload []byte
+}
+
+// Conn 单个WebSocket连接
+type Conn struct {
+	// ID 连接ID，业务进程通过该ID向连接推送消息
+	ID string
+	// Route 连接所属路由路径
+	Route string
+	// ClientIP 客户端IP
+	ClientIP string
+	// Header 握手请求头
+	Header http.Header
+	// RequestID 握手请求的请求ID，随连接的所有事件发送到业务进程
+	RequestID string
+
+	netConn net.Conn
+	reader  *bufio.Reader
+	limits  Limits
+
+	send      chan outbound
+	writeMu   sync.Mutex
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// newConn 创建连接
+func newConn(id, route, clientIP string, header http.Header, netConn net.Conn, reader *bufio.Reader, limits Limits) *Conn {
+	return &Conn{
+		ID:       id,
+		Route:    route,
+		ClientIP: clientIP,
+		Header:   header,
+		netConn:  netConn,
+		reader:   reader,
+		limits:   limits,
+		send:     make(chan outbound, limits.SendQueue),
+		closed:   make(chan struct{}),
+	}
+}
+
+// Send 将消息加入发送队列
+// 队列已满时关闭连接并返回 ErrSendQueueFull，避免慢客户端占用网关内存
+func (c *Conn) Send(opcode byte, payload []byte) error {
+	select {
+	case <-c.closed:
+		return ErrClosed
+	default:
+	}
+	select {
+	case c.send <- outbound{opcode: opcode, payload: payload}:
+		return nil
+	default:
+		c.Close(ClosePolicyViolation, "send queue full")
+		return ErrSendQueueFull
+	}
+}
+
+// Close 发送关闭帧并关闭底层连接，可重复调用
+func (c *Conn) Close(code uint16, reason string) {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.writeFrame(OpClose, closePayload(code, reason))
+		c.netConn.Close()
+	})
+}
+
+// Done 返回连接关闭时关闭的通道
+func (c *Conn) Done() <-chan struct{} {
+	return c.closed
+}
+
+// writeFrame 写入单个帧
+func (c *Conn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.netConn.SetWriteDeadline(time.Now().Add(c.limits.WriteTimeout))
+	_, err := c.netConn.Write(appendFrame(nil, opcode, payload))
+	return err
+}
+
+// writeLoop 发送队列中的消息并定时发送ping
+func (c *Conn) writeLoop() {
+	ticker := time.NewTicker(c.limits.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case msg := <-c.send:
+			if err := c.writeFrame(msg.opcode, msg.payload); err != nil {
+				c.Close(CloseGoingAway, "")
+				return
+			}
+		case <-ticker.C:
+			if err := c.writeFrame(OpPing, nil); err != nil {
+				c.Close(CloseGoingAway, "")
+				return
+			}
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+// ReadMessage 读取一条完整消息
+// 功能：
+// 1. 合并分片帧，校验消息大小和文本UTF-8编码
+// 2. 自动响应ping、处理pong（刷新读超时）和关闭帧
+// 返回值：
+//   - byte: 消息类型（OpText 或 OpBinary）
+//   - []byte: 消息内容
+//   - error: 连接关闭或协议错误时返回的错误信息（对端关闭时为 *CloseError）
+func (c *Conn) ReadMessage() (byte, []byte, error) {
+	var opcode byte
+	var message []byte
+	for {
+		// 每收到一帧都延长读超时，pong 也会触发延长
+		c.netConn.SetReadDeadline(time.Now().Add(c.limits.PingInterval + c.limits.PongTimeout))
+		h, err := readFrameHeader(c.reader)
+		if err != nil {
+			return 0, nil, err
+		}
+		if !h.masked {
+			// 客户端发送的帧必须加掩码
+			return 0, nil, errProtocol
+		}
+		if h.opcode < OpClose && int64(len(message))+h.length > c.limits.MaxMessageSize {
+			return 0, nil, ErrMessageTooBig
+		}
+
+		payload := make([]byte, h.length)
+		if _, err := io.ReadFull(c.reader, payload); err != nil {
+			return 0, nil, err
+		}
+		unmask(payload, h.mask, 0)
+
+		switch h.opcode {
+		case OpPing:
+			if err := c.writeFrame(OpPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case OpPong:
+			continue
+		case OpClose:
+			closeErr, err := parseClosePayload(payload)
+			if err != nil {
+				return 0, nil, err
+			}
+			return 0, nil, closeErr
+		case OpText, OpBinary:
+			if opcode != 0 {
+				return 0, nil, errProtocol
+			}
+			opcode = h.opcode
+		case OpContinuation:
+			if opcode == 0 {
+				return 0, nil, errProtocol
+			}
+		default:
+			return 0, nil, errProtocol
+		}
+
+		message = append(message, payload...)
+		if h.fin {
+			if opcode == OpText && !utf8.Valid(message) {
+				return 0, nil, errInvalidUTF8
+			}
+			return opcode, message, nil
+		}
+	}
+}
+
+// errInvalidUTF8 文本消息不是合法的UTF-8
+var errInvalidUTF8 = errors.New("websocket: invalid utf-8 in text message")
@@ -0,0 +1,213 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// RFC 6455 操作码
+const (
+	OpContinuation byte = 0x0
+	OpText         byte = 0x1
+	OpBinary       byte = 0x2
+	OpClose        byte = 0x8
+	OpPing         byte = 0x9
+	OpPong         byte = 0xA
+)
+
+// RFC 6455 关闭状态码
+const (
+	CloseNormal          uint16 = 1000
+	CloseGoingAway       uint16 = 1001
+	CloseProtocolError   uint16 = 1002
+	CloseInvalidPayload  uint16 = 1007
+	ClosePolicyViolation uint16 = 1008
+	CloseMessageTooBig   uint16 = 1009
+	CloseInternalError   uint16 = 1011
+)
+
+// websocketGUID 握手时拼接在 Sec-WebSocket-Key 之后的固定GUID
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxControlPayload 控制帧最大负载
+const maxControlPayload = 125
+
+var (
+	// ErrMessageTooBig 消息超过大小限制
+	ErrMessageTooBig = errors.New("websocket: message too big")
+	// errProtocol 对端违反协议
+	errProtocol = errors.New("websocket: protocol error")
+)
+
+// CloseError 对端发送的关闭帧
+type CloseError struct {
+	Code   uint16
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: close %d %s", e.Code, e.Reason)
+}
+
+// acceptKey 计算握手响应的 Sec-WebSocket-Accept
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// headerContains 判断以逗号分隔的请求头是否包含指定token（不区分大小写）
+func headerContains(h http.Header, name, token string) bool {
+	for _, value := range h.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkHandshake 校验客户端握手请求，返回 Sec-WebSocket-Key
+func checkHandshake(req *http.Request) (string, error) {
+	if req.Method != http.MethodGet {
+		return "", errors.New("websocket handshake requires GET")
+	}
+	if !headerContains(req.Header, "Connection", "upgrade") || !headerContains(req.Header, "Upgrade", "websocket") {
+		return "", errors.New("missing websocket upgrade headers")
+	}
+	if req.Header.Get("Sec-WebSocket-Version") != "13" {
+		return "", errors.New("unsupported websocket version")
+	}
+	key := req.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return "", errors.New("invalid Sec-WebSocket-Key")
+	}
+	return key, nil
+}
+
+// checkOrigin 校验浏览器握手请求的 Origin，防止跨站WebSocket劫持
+// 参数：
+//   - req *http.Request: 握手请求
+//   - allowed []string: 允许的来源，"*" 表示任意来源；为空时只允许与请求 Host 相同的来源
+// 返回值：
+//   - bool: 是否允许
+func checkOrigin(req *http.Request, allowed []string) bool {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if len(allowed) == 0 {
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, req.Host)
+	}
+	for _, a := range allowed {
+		if a == "*" || strings.EqualFold(strings.TrimSuffix(a, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// frameHeader 帧头
+type frameHeader struct {
+	fin    bool
+	opcode byte
+	masked bool
+	mask   [4]byte
+	length int64
+}
+
+// readFrameHeader 读取帧头
+func readFrameHeader(r *bufio.Reader) (frameHeader, error) {
+	var h frameHeader
+	var b [8]byte
+	if _, err := io.ReadFull(r, b[:2]); err != nil {
+		return h, err
+	}
+	if b[0]&0x70 != 0 {
+		// 未协商扩展时RSV位必须为0
+		return h, errProtocol
+	}
+	h.fin = b[0]&0x80 != 0
+	h.opcode = b[0] & 0x0F
+	h.masked = b[1]&0x80 != 0
+	h.length = int64(b[1] & 0x7F)
+
+	switch h.length {
+	case 126:
+		if _, err := io.ReadFull(r, b[:2]); err != nil {
+			return h, err
+		}
+		h.length = int64(binary.BigEndian.Uint16(b[:2]))
+	case 127:
+		if _, err := io.ReadFull(r, b[:8]); err != nil {
+			return h, err
+		}
+		n := binary.BigEndian.Uint64(b[:8])
+		if n > 1<<62 {
+			return h, errProtocol
+		}
+		h.length = int64(n)
+	}
+	if h.masked {
+		if _, err := io.ReadFull(r, h.mask[:]); err != nil {
+			return h, err
+		}
+	}
+	if h.opcode >= OpClose && (!h.fin || h.length > maxControlPayload) {
+		return h, errProtocol
+	}
+	return h, nil
+}
+
+// appendFrame 编码服务端帧（服务端发送的帧不加掩码）
+func appendFrame(buf []byte, opcode byte, payload []byte) []byte {
+	buf = append(buf, 0x80|opcode)
+	switch n := len(payload); {
+	case n <= 125:
+		buf = append(buf, byte(n))
+	case n <= 0xFFFF:
+		buf = append(buf, 126, byte(n>>8), byte(n))
+	default:
+		buf = append(buf, 127)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(n))
+	}
+	return append(buf, payload...)
+}
+
+// unmask 使用掩码原地解码负载
+func unmask(payload []byte, mask [4]byte, offset int) {
+	for i := range payload {
+		payload[i] ^= mask[(offset+i)%4]
+	}
+}
+
+// closePayload 编码关闭帧负载
+func closePayload(code uint16, reason string) []byte {
+	if len(reason) > maxControlPayload-2 {
+		reason = reason[:maxControlPayload-2]
+	}
+	payload := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(payload, code)
+	return append(payload, reason...)
+}
+
+// parseClosePayload 解析关闭帧负载
+func parseClosePayload(payload []byte) (*CloseError, error) {
+	if len(payload) == 0 {
+		return &CloseError{Code: CloseNormal}, nil
+	}
+	if len(payload) < 2 || !utf8.Valid(payload[2:]) {
+		return nil, errProtocol
+	}
+	return &CloseError{Code: binary.BigEndian.Uint16(payload), Reason: string(payload[2:])}, nil
+}
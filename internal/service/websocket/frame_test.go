@@ -0,0 +1,388 @@
+package websocket
+
+import (
+	"bigHammer/internal/router"
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testConn 记录写入内容的连接，读取由 Conn.reader 提供
+type testConn struct {
+	net.Conn
+	out bytes.Buffer
+}
+
+func (c *testConn) Write(b []byte) (int, error)      { return c.out.Write(b) }
+func (c *testConn) Close() error                     { return nil }
+func (c *testConn) SetReadDeadline(time.Time) error  { return nil }
+func (c *testConn) SetWriteDeadline(time.Time) error { return nil }
+
+// clientFrame 编码客户端帧，masked 为 true 时按 RFC 6455 加掩码
+func clientFrame(fin bool, opcode byte, payload []byte, masked bool) []byte {
+	var buf []byte
+	b0 := opcode
+	if fin {
+		b0 |= 0x80
+	}
+	buf = append(buf, b0)
+	var maskBit byte
+	if masked {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		buf = append(buf, maskBit|byte(n))
+	case n <= 0xFFFF:
+		buf = append(buf, maskBit|126, byte(n>>8), byte(n))
+	default:
+		buf = append(buf, maskBit|127)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(n))
+	}
+	if !masked {
+		return append(buf, payload...)
+	}
+	mask := [4]byte{0x37, 0xfa, 0x21, 0x3d}
+	buf = append(buf, mask[:]...)
+	buf = append(buf, payload...)
+	unmask(buf[len(buf)-len(payload):], mask, 0)
+	return buf
+}
+
+func frames(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func newTestConn(input []byte, maxSize int64) (*Conn, *testConn) {
+	tc := &testConn{}
+	limits := limitsFor(&router.WebSocketOptions{})
+	if maxSize > 0 {
+		limits.MaxMessageSize = maxSize
+	}
+	return newConn("c1", "/ws", "127.0.0.1", nil, tc, bufio.NewReader(bytes.NewReader(input)), limits), tc
+}
+
+func TestReadMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   []byte
+		maxSize int64
+		opcode  byte
+		message string
+		err     error
+	}{
+		{
+			name:    "masked text",
+			input:   clientFrame(true, OpText, []byte("hello"), true),
+			opcode:  OpText,
+			message: "hello",
+		},
+		{
+			name:    "binary allows invalid utf-8",
+			input:   clientFrame(true, OpBinary, []byte{0xff, 0xfe}, true),
+			opcode:  OpBinary,
+			message: "\xff\xfe",
+		},
+		{
+			name:    "extended 16-bit length",
+			input:   clientFrame(true, OpText, bytes.Repeat([]byte("a"), 300), true),
+			opcode:  OpText,
+			message: strings.Repeat("a", 300),
+		},
+		{
+			name: "fragmented text",
+			input: frames(
+				clientFrame(false, OpText, []byte("hel"), true),
+				clientFrame(false, OpContinuation, []byte("l"), true),
+				clientFrame(true, OpContinuation, []byte("o"), true),
+			),
+			opcode:  OpText,
+			message: "hello",
+		},
+		{
+			name: "utf-8 split across fragments",
+			input: frames(
+				clientFrame(false, OpText, []byte("中")[:2], true),
+				clientFrame(true, OpContinuation, []byte("中")[2:], true),
+			),
+			opcode:  OpText,
+			message: "中",
+		},
+		{
+			name: "control frames between fragments",
+			input: frames(
+				clientFrame(false, OpText, []byte("he"), true),
+				clientFrame(true, OpPing, []byte("p"), true),
+				clientFrame(true, OpPong, nil, true),
+				clientFrame(true, OpContinuation, []byte("llo"), true),
+			),
+			opcode:  OpText,
+			message: "hello",
+		},
+		{
+			name:  "unmasked client frame",
+			input: clientFrame(true, OpText, []byte("hello"), false),
+			err:   errProtocol,
+		},
+		{
+			name:  "reserved bits set",
+			input: append([]byte{0x80 | 0x40 | OpText}, clientFrame(true, OpText, nil, true)[1:]...),
+			err:   errProtocol,
+		},
+		{
+			name:  "unknown opcode",
+			input: clientFrame(true, 0x3, []byte("x"), true),
+			err:   errProtocol,
+		},
+		{
+			name:  "continuation without start",
+			input: clientFrame(true, OpContinuation, []byte("x"), true),
+			err:   errProtocol,
+		},
+		{
+			name: "new message before fragments end",
+			input: frames(
+				clientFrame(false, OpText, []byte("a"), true),
+				clientFrame(true, OpText, []byte("b"), true),
+			),
+			err: errProtocol,
+		},
+		{
+			name:  "fragmented control frame",
+			input: clientFrame(false, OpPing, []byte("p"), true),
+			err:   errProtocol,
+		},
+		{
+			name:  "oversized control frame",
+			input: clientFrame(true, OpPing, bytes.Repeat([]byte("p"), maxControlPayload+1), true),
+			err:   errProtocol,
+		},
+		{
+			name:  "64-bit length above 2^62",
+			input: append([]byte{0x80 | OpBinary, 0x80 | 127}, binary.BigEndian.AppendUint64(nil, 1<<63)...),
+			err:   errProtocol,
+		},
+		{
+			name:    "message too big",
+			input:   clientFrame(true, OpText, []byte("hello"), true),
+			maxSize: 4,
+			err:     ErrMessageTooBig,
+		},
+		{
+			name: "fragments too big",
+			input: frames(
+				clientFrame(false, OpText, []byte("hel"), true),
+				clientFrame(true, OpContinuation, []byte("lo"), true),
+			),
+			maxSize: 4,
+			err:     ErrMessageTooBig,
+		},
+		{
+			name:  "invalid utf-8 text",
+			input: clientFrame(true, OpText, []byte{0xff}, true),
+			err:   errInvalidUTF8,
+		},
+		{
+			name:  "close with one byte payload",
+			input: clientFrame(true, OpClose, []byte{0x03}, true),
+			err:   errProtocol,
+		},
+		{
+			name:  "close with invalid utf-8 reason",
+			input: clientFrame(true, OpClose, append(closePayload(CloseNormal, ""), 0xff), true),
+			err:   errProtocol,
+		},
+		{
+			name:  "truncated header",
+			input: []byte{0x80 | OpText},
+			err:   io.ErrUnexpectedEOF,
+		},
+		{
+			name:  "truncated payload",
+			input: clientFrame(true, OpText, []byte("hello"), true)[:8],
+			err:   io.ErrUnexpectedEOF,
+		},
+		{
+			name: "eof",
+			err:  io.EOF,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestConn(tt.input, tt.maxSize)
+			opcode, message, err := c.ReadMessage()
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if opcode != tt.opcode || string(message) != tt.message {
+				t.Fatalf("message = %#x %q, want %#x %q", opcode, message, tt.opcode, tt.message)
+			}
+		})
+	}
+}
+
+func TestReadMessagePong(t *testing.T) {
+	c, tc := newTestConn(frames(
+		clientFrame(true, OpPing, []byte("ping-1"), true),
+		clientFrame(true, OpText, []byte("hi"), true),
+	), 0)
+	if _, _, err := c.ReadMessage(); err != nil {
+		t.Fatal(err)
+	}
+	if want := appendFrame(nil, OpPong, []byte("ping-1")); !bytes.Equal(tc.out.Bytes(), want) {
+		t.Fatalf("pong = %x, want %x", tc.out.Bytes(), want)
+	}
+}
+
+func TestReadMessageClose(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+		want    CloseError
+	}{
+		{name: "empty payload", want: CloseError{Code: CloseNormal}},
+		{name: "code only", payload: closePayload(CloseGoingAway, ""), want: CloseError{Code: CloseGoingAway}},
+		{name: "code and reason", payload: closePayload(ClosePolicyViolation, "bye"), want: CloseError{Code: ClosePolicyViolation, Reason: "bye"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestConn(clientFrame(true, OpClose, tt.payload, true), 0)
+			_, _, err := c.ReadMessage()
+			var closeErr *CloseError
+			if !errors.As(err, &closeErr) {
+				t.Fatalf("err = %v, want *CloseError", err)
+			}
+			if *closeErr != tt.want {
+				t.Fatalf("close = %+v, want %+v", *closeErr, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppendFrame(t *testing.T) {
+	tests := []struct {
+		size   int
+		header []byte
+	}{
+		{size: 0, header: []byte{0x81, 0}},
+		{size: 125, header: []byte{0x81, 125}},
+		{size: 126, header: []byte{0x81, 126, 0, 126}},
+		{size: 0xFFFF, header: []byte{0x81, 126, 0xFF, 0xFF}},
+		{size: 0x10000, header: []byte{0x81, 127, 0, 0, 0, 0, 0, 1, 0, 0}},
+	}
+	for _, tt := range tests {
+		payload := bytes.Repeat([]byte("x"), tt.size)
+		frame := appendFrame(nil, OpText, payload)
+		if !bytes.HasPrefix(frame, tt.header) || len(frame) != len(tt.header)+tt.size {
+			t.Errorf("size %d: header = %x, want %x", tt.size, frame[:len(tt.header)], tt.header)
+			continue
+		}
+
+		// 服务端帧可被 readFrameHeader 解析回原始负载
+		r := bufio.NewReader(bytes.NewReader(frame))
+		h, err := readFrameHeader(r)
+		if err != nil {
+			t.Errorf("size %d: %v", tt.size, err)
+			continue
+		}
+		got, _ := io.ReadAll(r)
+		want := frameHeader{fin: true, opcode: OpText, length: int64(tt.size)}
+		if h != want || !bytes.Equal(got, payload) {
+			t.Errorf("size %d: header = %+v, payload %d bytes", tt.size, h, len(got))
+		}
+	}
+}
+
+func TestUnmaskOffset(t *testing.T) {
+	mask := [4]byte{1, 2, 3, 4}
+	payload := []byte("hello world")
+	masked := append([]byte(nil), payload...)
+	unmask(masked, mask, 0)
+
+	// 分段解码时按偏移继续使用掩码
+	unmask(masked[:3], mask, 0)
+	unmask(masked[3:], mask, 3)
+	if !bytes.Equal(masked, payload) {
+		t.Fatalf("unmask = %q, want %q", masked, payload)
+	}
+}
+
+func TestClosePayload(t *testing.T) {
+	payload := closePayload(CloseGoingAway, strings.Repeat("r", 200))
+	if len(payload) != maxControlPayload {
+		t.Fatalf("close payload length = %d, want %d", len(payload), maxControlPayload)
+	}
+	closeErr, err := parseClosePayload(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if closeErr.Code != CloseGoingAway || len(closeErr.Reason) != maxControlPayload-2 {
+		t.Fatalf("close = %d %d bytes", closeErr.Code, len(closeErr.Reason))
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	// RFC 6455 第1.3节的示例
+	if got := acceptKey("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("acceptKey = %q", got)
+	}
+
+	valid := func() *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "http://api.example.com/ws", nil)
+		req.Header.Set("Connection", "keep-alive, Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		return req
+	}
+	tests := []struct {
+		name    string
+		modify  func(req *http.Request)
+		wantErr bool
+	}{
+		{name: "valid", modify: func(req *http.Request) {}},
+		{name: "post", modify: func(req *http.Request) { req.Method = http.MethodPost }, wantErr: true},
+		{name: "missing upgrade", modify: func(req *http.Request) { req.Header.Del("Upgrade") }, wantErr: true},
+		{name: "old version", modify: func(req *http.Request) { req.Header.Set("Sec-WebSocket-Version", "8") }, wantErr: true},
+		{name: "short key", modify: func(req *http.Request) { req.Header.Set("Sec-WebSocket-Key", "c2hvcnQ=") }, wantErr: true},
+	}
+	for _, tt := range tests {
+		req := valid()
+		tt.modify(req)
+		if _, err := checkHandshake(req); (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	tests := []struct {
+		origin  string
+		allowed []string
+		want    bool
+	}{
+		{origin: "", want: true},
+		{origin: "https://api.example.com", want: true},
+		{origin: "https://evil.example.com"},
+		{origin: "https://app.example.com", allowed: []string{"https://app.example.com/"}, want: true},
+		{origin: "https://evil.example.com", allowed: []string{"https://app.example.com"}},
+		{origin: "https://evil.example.com", allowed: []string{"*"}, want: true},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "http://api.example.com/ws", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		if got := checkOrigin(req, tt.allowed); got != tt.want {
+			t.Errorf("checkOrigin(%q, %v) = %v, want %v", tt.origin, tt.allowed, got, tt.want)
+		}
+	}
+}
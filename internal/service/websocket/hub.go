@@ -0,0 +1,205 @@
+package websocket
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrConnectionNotFound 连接不存在
+var ErrConnectionNotFound = errors.New("websocket: connection not found")
+
+// Hub 连接注册中心
+// 维护所有活动连接和房间成员，供业务进程按连接ID或房间推送消息
+type Hub struct {
+	mu          sync.RWMutex
+	connections map[string]*Conn
+	rooms       map[string]map[string]*Conn
+	// memberships 连接加入的房间，用于断开时清理
+	memberships map[string]map[string]bool
+	// routeCounts 每个路由的连接数
+	routeCounts map[string]int
+	// ipCounts 每个路由下每个客户端IP的连接数
+	ipCounts map[string]int
+}
+
+// NewHub 创建连接注册中心
+func NewHub() *Hub {
+	return &Hub{
+		connections: make(map[string]*Conn),
+		rooms:       make(map[string]map[string]*Conn),
+		memberships: make(map[string]map[string]bool),
+		routeCounts: make(map[string]int),
+		ipCounts:    make(map[string]int),
+	}
+}
+
+// DefaultHub 全局连接注册中心
+var DefaultHub = NewHub()
+
+// reserve 检查连接数限制并预占名额，成功后必须调用 register 或 release
+func (h *Hub) reserve(route, clientIP string, limits Limits) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if limits.MaxConnections > 0 && h.routeCounts[route] >= limits.MaxConnections {
+		return false
+	}
+	ipKey := route + "|" + clientIP
+	if limits.MaxConnectionsPerIP > 0 && h.ipCounts[ipKey] >= limits.MaxConnectionsPerIP {
+		return false
+	}
+	h.routeCounts[route]++
+	h.ipCounts[ipKey]++
+	return true
+}
+
+// release 释放 reserve 预占的名额
+func (h *Hub) release(route, clientIP string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.releaseLocked(route, clientIP)
+}
+
+func (h *Hub) releaseLocked(route, clientIP string) {
+	ipKey := route + "|" + clientIP
+	if h.routeCounts[route]--; h.routeCounts[route] <= 0 {
+		delete(h.routeCounts, route)
+	}
+	if h.ipCounts[ipKey]--; h.ipCounts[ipKey] <= 0 {
+		delete(h.ipCounts, ipKey)
+	}
+}
+
+// register 登记已完成握手的连接
+func (h *Hub) register(c *Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.connections[c.ID] = c
+}
+
+// unregister 移除连接并退出所有房间
+func (h *Hub) unregister(c *Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.connections[c.ID]; !ok {
+		return
+	}
+	delete(h.connections, c.ID)
+	for room := range h.memberships[c.ID] {
+		h.leaveLocked(c.ID, room)
+	}
+	delete(h.memberships, c.ID)
+	h.releaseLocked(c.Route, c.ClientIP)
+}
+
+// Get 根据ID查找连接
+func (h *Hub) Get(id string) (*Conn, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	c, ok := h.connections[id]
+	return c, ok
+}
+
+// Count 返回活动连接数
+func (h *Hub) Count() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.connections)
+}
+
+// Send 向指定连接发送文本消息
+func (h *Hub) Send(id string, message []byte) error {
+	c, ok := h.Get(id)
+	if !ok {
+		return ErrConnectionNotFound
+	}
+	return c.Send(OpText, message)
+}
+
+// Broadcast 向房间内所有连接发送文本消息，room 为空时发送给所有连接
+// 返回值：
+//   - int: 成功加入发送队列的连接数
+func (h *Hub) Broadcast(room string, message []byte) int {
+	h.mu.RLock()
+	targets := make([]*Conn, 0)
+	if room == "" {
+		for _, c := range h.connections {
+			targets = append(targets, c)
+		}
+	} else {
+		for _, c := range h.rooms[room] {
+			targets = append(targets, c)
+		}
+	}
+	h.mu.RUnlock()
+
+	sent := 0
+	for _, c := range targets {
+		if c.Send(OpText, message) == nil {
+			sent++
+		}
+	}
+	return sent
+}
+
+// Join 将连接加入房间
+func (h *Hub) Join(id, room string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	c, ok := h.connections[id]
+	if !ok {
+		return ErrConnectionNotFound
+	}
+	if h.rooms[room] == nil {
+		h.rooms[room] = make(map[string]*Conn)
+	}
+	h.rooms[room][id] = c
+	if h.memberships[id] == nil {
+		h.memberships[id] = make(map[string]bool)
+	}
+	h.memberships[id][room] = true
+	return nil
+}
+
+// Leave 将连接移出房间
+func (h *Hub) Leave(id, room string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.connections[id]; !ok {
+		return ErrConnectionNotFound
+	}
+	h.leaveLocked(id, room)
+	delete(h.memberships[id], room)
+	return nil
+}
+
+func (h *Hub) leaveLocked(id, room string) {
+	if members, ok := h.rooms[room]; ok {
+		delete(members, id)
+		if len(members) == 0 {
+			delete(h.rooms, room)
+		}
+	}
+}
+
+// Disconnect 主动关闭连接
+func (h *Hub) Disconnect(id string, code uint16, reason string) error {
+	c, ok := h.Get(id)
+	if !ok {
+		return ErrConnectionNotFound
+	}
+	c.Close(code, reason)
+	return nil
+}
+
+// CloseAll 关闭所有连接，用于服务停止
+func (h *Hub) CloseAll(code uint16, reason string) {
+	h.mu.RLock()
+	conns := make([]*Conn, 0, len(h.connections))
+	for _, c := range h.connections {
+		conns = append(conns, c)
+	}
+	h.mu.RUnlock()
+	for _, c := range conns {
+		c.Close(code, reason)
+	}
+}
@@ -0,0 +1,54 @@
+package websocket
+
+import (
+	"bigHammer/internal/router"
+	"time"
+)
+
+// Limits 单个WebSocket路由的连接限制
+type Limits struct {
+	MaxConnections      int
+	MaxConnectionsPerIP int
+	MaxMessageSize      int64
+	MessagesPerSecond   int
+	PingInterval        time.Duration
+	PongTimeout         time.Duration
+	WriteTimeout        time.Duration
+	SendQueue           int
+}
+
+// 默认限制
+const (
+	defaultMaxMessageSize = 64 << 10
+	defaultPingInterval   = 30 * time.Second
+	defaultPongTimeout    = 10 * time.Second
+	defaultWriteTimeout   = 10 * time.Second
+	defaultSendQueue      = 64
+)
+
+// limitsFor 根据路由配置生成连接限制，未配置的项使用默认值
+func limitsFor(opts *router.WebSocketOptions) Limits {
+	l := Limits{
+		MaxConnections:      opts.MaxConnections,
+		MaxConnectionsPerIP: opts.MaxConnectionsPerIP,
+		MaxMessageSize:      opts.MaxMessageSize,
+		MessagesPerSecond:   opts.MessagesPerSecond,
+		PingInterval:        time.Duration(opts.PingInterval) * time.Second,
+		PongTimeout:         time.Duration(opts.PongTimeout) * time.Second,
+		WriteTimeout:        defaultWriteTimeout,
+		SendQueue:           opts.SendQueue,
+	}
+	if l.MaxMessageSize <= 0 {
+		l.MaxMessageSize = defaultMaxMessageSize
+	}
+	if l.PingInterval <= 0 {
+		l.PingInterval = defaultPingInterval
+	}
+	if l.PongTimeout <= 0 {
+		l.PongTimeout = defaultPongTimeout
+	}
+	if l.SendQueue <= 0 {
+		l.SendQueue = defaultSendQueue
+	}
+	return l
+}
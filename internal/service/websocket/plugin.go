@@ -0,0 +1,59 @@
+package websocket
+
+import (
+	"bigHammer/internal/plugin"
+	"fmt"
+	"strconv"
+)
+
+// Plugin WebSocket推送插件
+// 业务进程通过主Socket发送 service=websocket 的请求向连接推送消息，支持的方法：
+//   - send: 参数 connection_id、message
+//   - broadcast: 参数 room（为空时发送给所有连接）、message
+//   - join / leave: 参数 connection_id、room
+//   - close: 参数 connection_id、code（可选）、reason（可选）
+type Plugin struct {
+	hub *Hub
+}
+
+// HandleRequest 实现 ServicePlugin 接口的请求处理方法
+func (p *Plugin) HandleRequest(req plugin.Request) plugin.Response {
+	id := req.Params["connection_id"]
+	var err error
+	switch req.Method {
+	case "send":
+		err = p.hub.Send(id, []byte(req.Params["message"]))
+	case "broadcast":
+		sent := p.hub.Broadcast(req.Params["room"], []byte(req.Params["message"]))
+		return plugin.Response{Status: 200, Message: "Broadcast queued", Data: map[string]int{"sent": sent}}
+	case "join":
+		err = p.hub.Join(id, req.Params["room"])
+	case "leave":
+		err = p.hub.Leave(id, req.Params["room"])
+	case "close":
+		code := CloseNormal
+		if c, convErr := strconv.Atoi(req.Params["code"]); convErr == nil {
+			code = uint16(c)
+		}
+		err = p.hub.Disconnect(id, code, req.Params["reason"])
+	default:
+		return plugin.Response{Status: 400, Message: fmt.Sprintf("Unknown method %s", req.Method)}
+	}
+
+	if err == ErrConnectionNotFound {
+		return plugin.Response{Status: 404, Message: "Connection not found"}
+	}
+	if err != nil {
+		return plugin.Response{Status: 500, Message: err.Error()}
+	}
+	return plugin.Response{Status: 200, Message: "OK"}
+}
+
+// init 包初始化函数
+// 功能：
+// 1. 注册 websocket 插件到插件系统
+// 参数：无
+// 返回值：无
+func init() {
+	plugin.RegisterPlugin("websocket", &Plugin{hub: DefaultHub})
+}
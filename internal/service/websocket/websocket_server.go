@@ -0,0 +1,315 @@
+package websocket
+
+import (
+	"bigHammer/internal/config"
+	ipc "bigHammer/internal/ipc/socket"
+	"bigHammer/internal/lifecycle"
+	"bigHammer/internal/middleware"
+	"bigHammer/internal/middleware/auth"
+	"bigHammer/internal/router"
+	"bigHammer/internal/upstream"
+	"bigHammer/pkg/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StartWebSocketServer 启动WebSocket服务
+// 功能：
+// 1. 加载路由配置，为配置了 websocket 的路由注册升级处理器
+// 2. 复用路由的全局中间件和路由中间件（认证、限流等在握手阶段执行）
+// 3. 上下文取消时关闭所有连接并停止服务
+// 参数：
+//   - ctx context.Context: 用于停止服务的上下文
+//   - port string: 监听端口
+// 返回值：无
+func StartWebSocketServer(ctx context.Context, port string) {
+	if port == "" {
+		return
+	}
+	loadedRouter, err := router.LoadRouterConfig()
+	if err != nil {
+		log.Println("Error loading router configuration:", err)
+		return
+	}
+
+	// 同一路径可能被不同主机名的路由使用，按请求的 Host 选择路由
+	byPath := make(map[string][]hostRoute)
+	var paths []string
+	for _, route := range loadedRouter.AllRoutes() {
+		if route.WebSocket == nil {
+			continue
+		}
+		handler, err := loadedRouter.BuildHandler(route, upgradeHandler(DefaultHub, route))
+		if err != nil {
+			log.Println("Error building websocket route:", err)
+			return
+		}
+		if _, ok := byPath[route.Path]; !ok {
+			paths = append(paths, route.Path)
+		}
+		byPath[route.Path] = append(byPath[route.Path], hostRoute{hosts: route.Hosts, handler: handler})
+	}
+	mux := http.NewServeMux()
+	for _, path := range paths {
+		mux.Handle(path, hostHandler(byPath[path]))
+	}
+	if len(paths) == 0 {
+		log.Println("No websocket routes configured, websocket server not started.")
+		return
+	}
+
+	server := &http.Server{Addr: ":" + port, Handler: mux}
+	log.Println("WebSocket server listening on port:" + port)
+	go func() {
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			log.Fatal("Error starting WebSocket server:", err)
+		}
+	}()
+
+	<-ctx.Done()
+
+	shutdownCtx, cancel := lifecycle.DrainContext(5 * time.Second)
+	defer cancel()
+	// 已升级的连接不受 Shutdown 管理，需要单独关闭
+	DefaultHub.CloseAll(CloseGoingAway, "server shutdown")
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("WebSocket server Shutdown: %v", err)
+	}
+}
+
+// hostRoute 配置了主机名的WebSocket路由处理链
+type hostRoute struct {
+	hosts   []string
+	handler http.Handler
+}
+
+// hostHandler 按请求的 Host 选择同一路径下的路由，配置了主机名的路由优先于未配置的路由
+func hostHandler(routes []hostRoute) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var fallback http.Handler
+		for _, r := range routes {
+			if len(r.hosts) == 0 {
+				if fallback == nil {
+					fallback = r.handler
+				}
+				continue
+			}
+			if router.MatchHost(r.hosts, req.Host) {
+				r.handler.ServeHTTP(w, req)
+				return
+			}
+		}
+		if fallback == nil {
+			http.NotFound(w, req)
+			return
+		}
+		fallback.ServeHTTP(w, req)
+	})
+}
+
+// upgradeHandler 返回完成握手并处理连接的处理器
+func upgradeHandler(hub *Hub, route router.Route) http.Handler {
+	limits := limitsFor(route.WebSocket)
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		key, err := checkHandshake(req)
+		if err != nil {
+			middleware.WriteError(w, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		if !checkOrigin(req, route.WebSocket.AllowedOrigins) {
+			middleware.WriteError(w, http.StatusForbidden, "Origin not allowed", nil)
+			return
+		}
+
+		req = middleware.AssignRequestID(w, req, middleware.RequestIDHeader)
+		requestID := middleware.RequestIDFromContext(req.Context())
+		clientIP := middleware.ClientIP(req)
+		if !hub.reserve(route.Path, clientIP, limits) {
+			middleware.WriteError(w, http.StatusServiceUnavailable, "Too many websocket connections", nil)
+			return
+		}
+
+		id := uuid.NewString()
+		identity := auth.IdentityFromContext(req.Context())
+
+		// connect 事件：业务进程可以返回 {"accept": false} 拒绝连接
+		reply, err := dispatch(route, clientIP, requestID, event("connect", id, route.Path, clientIP, identity, map[string]interface{}{
+			"headers": req.Header,
+			"uri":     req.RequestURI,
+		}))
+		if err != nil {
+			hub.release(route.Path, clientIP)
+			log.Println("WebSocket connect 事件转发失败:", err)
+			middleware.WriteError(w, http.StatusBadGateway, "Business process unavailable", nil)
+			return
+		}
+		if rejected(reply) {
+			hub.release(route.Path, clientIP)
+			middleware.WriteError(w, http.StatusForbidden, "Connection rejected", nil)
+			return
+		}
+
+		netConn, rw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			hub.release(route.Path, clientIP)
+			log.Println("WebSocket hijack 失败:", err)
+			middleware.WriteError(w, http.StatusInternalServerError, "Websocket upgrade not supported", nil)
+			return
+		}
+		netConn.SetDeadline(time.Time{})
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+			"Upgrade: websocket\r\n" +
+			"Connection: Upgrade\r\n" +
+			"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n" +
+			middleware.RequestIDHeader + ": " + requestID + "\r\n\r\n")
+		if err := rw.Flush(); err != nil {
+			netConn.Close()
+			hub.release(route.Path, clientIP)
+			return
+		}
+
+		conn := newConn(id, route.Path, clientIP, req.Header.Clone(), netConn, rw.Reader, limits)
+		conn.RequestID = requestID
+		hub.register(conn)
+		go conn.writeLoop()
+		serve(hub, conn, route, identity)
+	})
+}
+
+// serve 读取客户端消息并转发到业务进程，连接结束时发送 close 事件
+func serve(hub *Hub, conn *Conn, route router.Route, identity *auth.Identity) {
+	closeCode, closeReason := CloseNormal, ""
+	defer func() {
+		conn.Close(closeCode, closeReason)
+		hub.unregister(conn)
+		_, err := dispatch(route, conn.ClientIP, conn.RequestID, event("close", conn.ID, conn.Route, conn.ClientIP, identity, map[string]interface{}{
+			"code":   closeCode,
+			"reason": closeReason,
+		}))
+		if err != nil {
+			log.Println("WebSocket close 事件转发失败:", err)
+		}
+	}()
+
+	windowStart := time.Now()
+	windowCount := 0
+	for {
+		opcode, message, err := conn.ReadMessage()
+		if err != nil {
+			closeCode, closeReason = closeStatus(err)
+			return
+		}
+
+		// 每秒消息数限制
+		if conn.limits.MessagesPerSecond > 0 {
+			if time.Since(windowStart) >= time.Second {
+				windowStart, windowCount = time.Now(), 0
+			}
+			windowCount++
+			if windowCount > conn.limits.MessagesPerSecond {
+				closeCode, closeReason = ClosePolicyViolation, "rate limit exceeded"
+				return
+			}
+		}
+
+		data := map[string]interface{}{"binary": opcode == OpBinary}
+		if opcode == OpBinary {
+			data["data"] = message
+		} else {
+			data["data"] = string(message)
+		}
+		reply, err := dispatch(route, conn.ClientIP, conn.RequestID, event("message", conn.ID, conn.Route, conn.ClientIP, identity, data))
+		if err != nil {
+			log.Println("WebSocket message 事件转发失败:", err)
+			closeCode, closeReason = CloseInternalError, "business process unavailable"
+			return
+		}
+		// 业务进程的同步返回值直接回复给当前连接
+		if len(reply) > 0 {
+			if err := conn.Send(OpText, reply); err != nil {
+				closeCode, closeReason = ClosePolicyViolation, "send queue full"
+				return
+			}
+		}
+	}
+}
+
+// event 组装转发给业务进程的事件数据
+func event(name, id, route, clientIP string, identity *auth.Identity, extra map[string]interface{}) map[string]interface{} {
+	data := map[string]interface{}{
+		"event":         name,
+		"connection_id": id,
+		"route":         route,
+		"client_ip":     clientIP,
+		"timestamp":     time.Now().Format(time.RFC3339Nano),
+		"auth":          identity,
+	}
+	for k, v := range extra {
+		data[k] = v
+	}
+	return data
+}
+
+// dispatch 通过IPC将事件发送到业务进程
+// 路由配置了 backend 时从后端中选择端点，同一客户端IP的事件在一致性哈希下落到同一 worker
+// requestID 为握手请求的请求ID，写入IPC请求的 request_id 字段
+func dispatch(route router.Route, key, requestID string, data map[string]interface{}) ([]byte, error) {
+	if route.Backend != "" {
+		pool, ok := upstream.Get(route.Backend)
+		if !ok {
+			return nil, fmt.Errorf("unknown backend %s", route.Backend)
+		}
+		e, err := pool.Pick(key)
+		if err != nil {
+			return nil, err
+		}
+		output, _, err := ipc.TransmitIPC(false, requestID, route.Command, data, e.Address)
+		pool.Done(e, err)
+		return output, err
+	}
+	socketPath, err := utils.ResolvePath(config.GlobalConfig.BussinessSocketPath)
+	if err != nil {
+		return nil, err
+	}
+	output, _, err := ipc.TransmitIPC(false, requestID, route.Command, data, socketPath)
+	return output, err
+}
+
+// rejected 判断业务进程是否拒绝了连接
+func rejected(reply []byte) bool {
+	var decision struct {
+		Accept *bool `json:"accept"`
+	}
+	if err := json.Unmarshal(reply, &decision); err != nil {
+		return false
+	}
+	return decision.Accept != nil && !*decision.Accept
+}
+
+// closeStatus 根据读取错误确定回复给客户端的关闭状态码
+func closeStatus(err error) (uint16, string) {
+	var closeErr *CloseError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &closeErr):
+		return CloseNormal, ""
+	case errors.Is(err, ErrMessageTooBig):
+		return CloseMessageTooBig, "message too big"
+	case errors.Is(err, errInvalidUTF8):
+		return CloseInvalidPayload, "invalid utf-8"
+	case errors.Is(err, errProtocol):
+		return CloseProtocolError, "protocol error"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return CloseGoingAway, "pong timeout"
+	default:
+		return CloseGoingAway, ""
+	}
+}
@@ -1,6 +1,27 @@
 package shared
 
-import "bigHammer/internal/di"
+import (
+	"bigHammer/internal/di"
+	"bigHammer/internal/interface/database"
+	"errors"
+	"fmt"
+)
 
 // GlobalContainer 是整个应用程序的全局DI容器。
 var GlobalContainer *di.Container
+
+// ResolveDatabase 从全局容器解析内存数据库服务
+// 返回值：
+//   - database.IDatabase: 数据库实例
+//   - error: 服务未注册或类型不匹配时返回的错误信息
+func ResolveDatabase() (database.IDatabase, error) {
+	dbInterface, err := GlobalContainer.Resolve("database")
+	if err != nil {
+		return nil, fmt.Errorf("error resolving 'database': %v", err)
+	}
+	db, ok := dbInterface.(database.IDatabase)
+	if !ok {
+		return nil, errors.New("error asserting database instance to the correct type")
+	}
+	return db, nil
+}
@@ -0,0 +1,198 @@
+package split
+
+import (
+	"bigHammer/internal/middleware"
+	"bigHammer/internal/middleware/auth"
+	"fmt"
+	"hash/crc32"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// DefaultHeader 标记所选变体的默认响应头
+const DefaultHeader = "X-Variant"
+
+var (
+	variantRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_variant_requests_total",
+		Help: "按变体统计的请求数",
+	}, []string{"route", "variant", "status"})
+	variantDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gateway_variant_request_duration_seconds",
+		Help:    "按变体统计的请求处理耗时",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "variant"})
+)
+
+// Options 流量拆分配置，对应 router.json 中路由的 split 字段
+type Options struct {
+	// Variants 流量目标，先按顺序匹配 match 规则，都不匹配时按权重分配
+	Variants []Variant `json:"variants"`
+	// Sticky 按权重分配时使用的哈希键：ip（默认）、identity、header:名称、cookie:名称
+	// 同一个键总是分配到同一个变体；为 random 时每个请求随机分配
+	Sticky string `json:"sticky,omitempty"`
+	// Header 标记所选变体的响应头，默认 X-Variant
+	Header string `json:"header,omitempty"`
+}
+
+// Variant 流量目标
+type Variant struct {
+	// Name 变体名称，写入响应头和指标标签
+	Name string `json:"name"`
+	// Command 变体使用的业务命令，为空时使用路由的 command
+	Command string `json:"command,omitempty"`
+	// Backend 变体使用的后端，为空时使用路由的 backend
+	Backend string `json:"backend,omitempty"`
+	// Weight 按权重分配时的权重，0表示只接收 match 命中的请求
+	Weight int `json:"weight,omitempty"`
+	// Match 匹配规则，命中时直接选择该变体
+	Match *Match `json:"match,omitempty"`
+}
+
+// Match 变体匹配规则，所有条件都满足时命中
+// 值为 * 时表示只要存在且非空即可
+type Match struct {
+	// Headers 请求头匹配
+	Headers map[string]string `json:"headers,omitempty"`
+	// Cookies Cookie匹配
+	Cookies map[string]string `json:"cookies,omitempty"`
+}
+
+// matches 判断请求是否满足匹配规则
+func (m *Match) matches(req *http.Request) bool {
+	for name, want := range m.Headers {
+		if !matchValue(req.Header.Get(name), want) {
+			return false
+		}
+	}
+	for name, want := range m.Cookies {
+		value := ""
+		if c, err := req.Cookie(name); err == nil {
+			value = c.Value
+		}
+		if !matchValue(value, want) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchValue(value, want string) bool {
+	if want == "*" {
+		return value != ""
+	}
+	return value == want
+}
+
+// Splitter 编译后的流量拆分规则
+type Splitter struct {
+	route string
+	opts  Options
+	// total 权重总和
+	total int
+}
+
+// New 校验配置并创建流量拆分器
+// 参数：
+//   - route string: 路由路径，用作指标标签
+//   - opts Options: 流量拆分配置
+// 返回值：
+//   - *Splitter: 流量拆分器
+//   - error: 变体名称为空或重复、权重为负或权重总和为0时返回的错误信息
+func New(route string, opts Options) (*Splitter, error) {
+	if len(opts.Variants) == 0 {
+		return nil, fmt.Errorf("split: no variants configured")
+	}
+	s := &Splitter{route: route, opts: opts}
+	seen := make(map[string]bool)
+	for _, v := range opts.Variants {
+		if v.Name == "" {
+			return nil, fmt.Errorf("split: variant name is required")
+		}
+		if seen[v.Name] {
+			return nil, fmt.Errorf("split: duplicate variant %s", v.Name)
+		}
+		seen[v.Name] = true
+		if v.Weight < 0 {
+			return nil, fmt.Errorf("split: variant %s has negative weight", v.Name)
+		}
+		s.total += v.Weight
+	}
+	if s.total == 0 {
+		return nil, fmt.Errorf("split: total weight must be greater than 0")
+	}
+	switch sticky := opts.Sticky; {
+	case sticky == "", sticky == "ip", sticky == "identity", sticky == "random",
+		strings.HasPrefix(sticky, "header:"), strings.HasPrefix(sticky, "cookie:"):
+	default:
+		return nil, fmt.Errorf("split: unknown sticky key %q", sticky)
+	}
+	return s, nil
+}
+
+// Header 返回标记所选变体的响应头名称
+func (s *Splitter) Header() string {
+	if s.opts.Header == "" {
+		return DefaultHeader
+	}
+	return s.opts.Header
+}
+
+// Pick 为请求选择变体
+// 返回值：
+//   - int: 变体在 Variants 中的下标
+func (s *Splitter) Pick(req *http.Request) int {
+	for i, v := range s.opts.Variants {
+		if v.Match != nil && v.Match.matches(req) {
+			return i
+		}
+	}
+
+	var bucket int
+	if key := s.key(req); key == "" {
+		bucket = rand.Intn(s.total)
+	} else {
+		bucket = int(crc32.ChecksumIEEE([]byte(key)) % uint32(s.total))
+	}
+	for i, v := range s.opts.Variants {
+		if bucket < v.Weight {
+			return i
+		}
+		bucket -= v.Weight
+	}
+	return len(s.opts.Variants) - 1
+}
+
+// key 返回按权重分配时使用的哈希键，随机分配时返回空字符串
+func (s *Splitter) key(req *http.Request) string {
+	switch sticky := s.opts.Sticky; {
+	case sticky == "random":
+		return ""
+	case sticky == "identity":
+		if id := auth.IdentityFromContext(req.Context()); id != nil {
+			return id.Subject
+		}
+	case strings.HasPrefix(sticky, "header:"):
+		if value := req.Header.Get(strings.TrimPrefix(sticky, "header:")); value != "" {
+			return value
+		}
+	case strings.HasPrefix(sticky, "cookie:"):
+		if c, err := req.Cookie(strings.TrimPrefix(sticky, "cookie:")); err == nil && c.Value != "" {
+			return c.Value
+		}
+	}
+	return middleware.ClientIP(req)
+}
+
+// Observe 记录变体的请求结果
+func (s *Splitter) Observe(variant int, status int, elapsed time.Duration) {
+	name := s.opts.Variants[variant].Name
+	variantRequests.WithLabelValues(s.route, name, strconv.Itoa(status)).Inc()
+	variantDuration.WithLabelValues(s.route, name).Observe(elapsed.Seconds())
+}
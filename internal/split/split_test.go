@@ -0,0 +1,198 @@
+package split
+
+import (
+	"bigHammer/internal/middleware/auth"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{name: "valid", opts: Options{Variants: []Variant{{Name: "a", Weight: 1}, {Name: "b"}}}},
+		{name: "no variants", opts: Options{}, wantErr: true},
+		{name: "empty name", opts: Options{Variants: []Variant{{Weight: 1}}}, wantErr: true},
+		{name: "duplicate name", opts: Options{Variants: []Variant{{Name: "a", Weight: 1}, {Name: "a", Weight: 1}}}, wantErr: true},
+		{name: "negative weight", opts: Options{Variants: []Variant{{Name: "a", Weight: 2}, {Name: "b", Weight: -1}}}, wantErr: true},
+		{name: "zero total weight", opts: Options{Variants: []Variant{{Name: "a"}}}, wantErr: true},
+		{name: "header sticky", opts: Options{Variants: []Variant{{Name: "a", Weight: 1}}, Sticky: "header:X-User"}},
+		{name: "cookie sticky", opts: Options{Variants: []Variant{{Name: "a", Weight: 1}}, Sticky: "cookie:uid"}},
+		{name: "unknown sticky", opts: Options{Variants: []Variant{{Name: "a", Weight: 1}}, Sticky: "query:uid"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New("/items", tt.opts); (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHeader(t *testing.T) {
+	s, _ := New("/items", Options{Variants: []Variant{{Name: "a", Weight: 1}}})
+	if s.Header() != DefaultHeader {
+		t.Fatalf("Header = %q, want %q", s.Header(), DefaultHeader)
+	}
+	s, _ = New("/items", Options{Variants: []Variant{{Name: "a", Weight: 1}}, Header: "X-Canary"})
+	if s.Header() != "X-Canary" {
+		t.Fatalf("Header = %q, want X-Canary", s.Header())
+	}
+}
+
+func TestPickMatch(t *testing.T) {
+	s, err := New("/items", Options{Variants: []Variant{
+		{Name: "stable", Weight: 100},
+		{Name: "beta", Match: &Match{Headers: map[string]string{"X-Beta": "1"}}},
+		{Name: "internal", Match: &Match{Headers: map[string]string{"X-Staff": "*"}, Cookies: map[string]string{"team": "core"}}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		headers map[string]string
+		cookie  string
+		want    string
+	}{
+		{name: "no match uses weights", want: "stable"},
+		{name: "header match", headers: map[string]string{"X-Beta": "1"}, want: "beta"},
+		{name: "header value mismatch", headers: map[string]string{"X-Beta": "2"}, want: "stable"},
+		{name: "all conditions match", headers: map[string]string{"X-Staff": "alice"}, cookie: "core", want: "internal"},
+		{name: "missing cookie", headers: map[string]string{"X-Staff": "alice"}, want: "stable"},
+		{name: "wildcard requires value", headers: map[string]string{"X-Staff": ""}, cookie: "core", want: "stable"},
+		{name: "first match wins", headers: map[string]string{"X-Beta": "1", "X-Staff": "alice"}, cookie: "core", want: "beta"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/items", nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "team", Value: tt.cookie})
+			}
+			if got := s.opts.Variants[s.Pick(req)].Name; got != tt.want {
+				t.Fatalf("Pick = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPickWeights(t *testing.T) {
+	s, err := New("/items", Options{Variants: []Variant{
+		{Name: "stable", Weight: 90},
+		{Name: "canary", Weight: 10},
+		{Name: "match-only"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make([]int, 3)
+	const n = 10000
+	for i := 0; i < n; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/items", nil)
+		req.RemoteAddr = fmt.Sprintf("10.%d.%d.%d:1234", i>>16&0xFF, i>>8&0xFF, i&0xFF)
+		counts[s.Pick(req)]++
+	}
+	if counts[2] != 0 {
+		t.Fatalf("zero-weight variant picked %d times", counts[2])
+	}
+	// 哈希分布允许 ±2% 的偏差
+	if share := float64(counts[1]) / n; share < 0.08 || share > 0.12 {
+		t.Fatalf("canary share = %.3f, want about 0.10", share)
+	}
+}
+
+func TestPickSticky(t *testing.T) {
+	variants := []Variant{{Name: "a", Weight: 1}, {Name: "b", Weight: 1}, {Name: "c", Weight: 1}, {Name: "d", Weight: 1}}
+	tests := []struct {
+		sticky string
+		// keyed 返回以 key 作为粘性键、但客户端IP不同的请求
+		keyed func(key string, ip int) *http.Request
+	}{
+		{sticky: "ip", keyed: func(key string, ip int) *http.Request {
+			req := httptest.NewRequest(http.MethodGet, "/items", nil)
+			req.RemoteAddr = key + ":1234"
+			return req
+		}},
+		{sticky: "header:X-User", keyed: func(key string, ip int) *http.Request {
+			req := httptest.NewRequest(http.MethodGet, "/items", nil)
+			req.RemoteAddr = fmt.Sprintf("192.0.2.%d:1234", ip)
+			req.Header.Set("X-User", key)
+			return req
+		}},
+		{sticky: "cookie:uid", keyed: func(key string, ip int) *http.Request {
+			req := httptest.NewRequest(http.MethodGet, "/items", nil)
+			req.RemoteAddr = fmt.Sprintf("192.0.2.%d:1234", ip)
+			req.AddCookie(&http.Cookie{Name: "uid", Value: key})
+			return req
+		}},
+		{sticky: "identity", keyed: func(key string, ip int) *http.Request {
+			req := httptest.NewRequest(http.MethodGet, "/items", nil)
+			req.RemoteAddr = fmt.Sprintf("192.0.2.%d:1234", ip)
+			return req.WithContext(auth.WithIdentity(req.Context(), &auth.Identity{Subject: key, Method: "jwt"}))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sticky, func(t *testing.T) {
+			s, err := New("/items", Options{Variants: variants, Sticky: tt.sticky})
+			if err != nil {
+				t.Fatal(err)
+			}
+			seen := make(map[int]bool)
+			for k := 0; k < 50; k++ {
+				key := fmt.Sprintf("198.51.100.%d", k)
+				first := s.Pick(tt.keyed(key, 1))
+				seen[first] = true
+				for ip := 2; ip < 6; ip++ {
+					if got := s.Pick(tt.keyed(key, ip)); got != first {
+						t.Fatalf("key %s: variant %d then %d", key, first, got)
+					}
+				}
+			}
+			if len(seen) < 2 {
+				t.Fatalf("all keys mapped to the same variant")
+			}
+		})
+	}
+}
+
+func TestPickStickyFallsBackToIP(t *testing.T) {
+	variants := []Variant{{Name: "a", Weight: 1}, {Name: "b", Weight: 1}, {Name: "c", Weight: 1}}
+	byHeader, err := New("/items", Options{Variants: variants, Sticky: "header:X-User"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byIP, err := New("/items", Options{Variants: variants, Sticky: "ip"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 未携带粘性键的请求按客户端IP分配
+	for i := 0; i < 50; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/items", nil)
+		req.RemoteAddr = fmt.Sprintf("203.0.113.%d:1234", i)
+		if got, want := byHeader.Pick(req), byIP.Pick(req); got != want {
+			t.Fatalf("ip %d: variant %d, want %d", i, got, want)
+		}
+	}
+}
+
+func TestPickRandom(t *testing.T) {
+	s, err := New("/items", Options{Variants: []Variant{{Name: "a", Weight: 1}, {Name: "b", Weight: 1}}, Sticky: "random"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 同一客户端的请求也会分到不同变体
+	seen := make(map[int]bool)
+	for i := 0; i < 200 && len(seen) < 2; i++ {
+		seen[s.Pick(httptest.NewRequest(http.MethodGet, "/items", nil))] = true
+	}
+	if len(seen) != 2 {
+		t.Fatalf("random split always picked %v", seen)
+	}
+}
@@ -0,0 +1,219 @@
+package sse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event 推送给订阅者的事件
+type Event struct {
+	// ID 事件ID，格式为 <启动时间戳>-<序号>，客户端重连时通过 Last-Event-ID 带回
+	ID string `json:"id"`
+	// Topic 事件主题
+	Topic string `json:"topic"`
+	// Name 事件类型，对应 SSE 的 event 字段，为空时客户端按 message 处理
+	Name string `json:"event,omitempty"`
+	// Data 事件内容
+	Data string `json:"data"`
+
+	seq uint64
+}
+
+// defaultReplaySize 重放缓冲区默认保留的事件数
+const defaultReplaySize = 256
+
+// defaultSubscriberQueue 订阅者发送队列长度
+const defaultSubscriberQueue = 64
+
+// Subscriber 单个订阅者
+type Subscriber struct {
+	topics []string
+	events chan Event
+	closed chan struct{}
+	once   sync.Once
+}
+
+// Events 返回接收事件的通道
+func (s *Subscriber) Events() <-chan Event {
+	return s.events
+}
+
+// Done 返回订阅被关闭时关闭的通道（取消订阅或客户端读取过慢）
+func (s *Subscriber) Done() <-chan struct{} {
+	return s.closed
+}
+
+// matches 判断订阅者是否订阅了主题
+func (s *Subscriber) matches(topic string) bool {
+	for _, t := range s.topics {
+		if MatchTopic(t, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Subscriber) close() {
+	s.once.Do(func() { close(s.closed) })
+}
+
+// Broker 主题订阅与事件分发中心
+// 保留最近的事件用于断线重连时按 Last-Event-ID 重放
+type Broker struct {
+	mu          sync.RWMutex
+	epoch       string
+	seq         uint64
+	replay      []Event
+	replaySize  int
+	subscribers map[*Subscriber]struct{}
+}
+
+// NewBroker 创建事件分发中心
+// 参数：
+//   - replaySize int: 重放缓冲区保留的事件数，<=0 时使用默认值
+// 返回值：
+//   - *Broker: 事件分发中心
+func NewBroker(replaySize int) *Broker {
+	if replaySize <= 0 {
+		replaySize = defaultReplaySize
+	}
+	return &Broker{
+		epoch:       strconv.FormatInt(time.Now().Unix(), 10),
+		replaySize:  replaySize,
+		subscribers: make(map[*Subscriber]struct{}),
+	}
+}
+
+// DefaultBroker 全局事件分发中心，SSE路由和 sse 插件共用
+var DefaultBroker = NewBroker(defaultReplaySize)
+
+// Publish 发布事件到指定主题
+// 参数：
+//   - topic string: 主题
+//   - name string: 事件类型，可为空
+//   - data string: 事件内容
+// 返回值：
+//   - Event: 已发布的事件（包含分配的ID）
+func (b *Broker) Publish(topic, name, data string) Event {
+	b.mu.Lock()
+	b.seq++
+	ev := Event{
+		ID:    fmt.Sprintf("%s-%d", b.epoch, b.seq),
+		Topic: topic,
+		Name:  name,
+		Data:  data,
+		seq:   b.seq,
+	}
+	b.replay = append(b.replay, ev)
+	if len(b.replay) > b.replaySize {
+		b.replay = append(b.replay[:0:0], b.replay[len(b.replay)-b.replaySize:]...)
+	}
+	targets := make([]*Subscriber, 0, len(b.subscribers))
+	for s := range b.subscribers {
+		if s.matches(topic) {
+			targets = append(targets, s)
+		}
+	}
+	b.mu.Unlock()
+
+	for _, s := range targets {
+		select {
+		case s.events <- ev:
+		default:
+			// 客户端读取过慢，断开后由客户端重连并重放
+			b.Unsubscribe(s)
+		}
+	}
+	return ev
+}
+
+// Subscribe 订阅主题
+// 功能：
+// 1. 注册订阅者
+// 2. lastEventID 不为空时，返回缓冲区中该事件之后的匹配事件用于重放
+// 参数：
+//   - topics []string: 订阅的主题，支持 device.* 形式的前缀通配
+//   - lastEventID string: 客户端最后收到的事件ID，可为空
+// 返回值：
+//   - *Subscriber: 订阅者
+//   - []Event: 需要先发送给客户端的重放事件
+func (b *Broker) Subscribe(topics []string, lastEventID string) (*Subscriber, []Event) {
+	s := &Subscriber{
+		topics: topics,
+		events: make(chan Event, defaultSubscriberQueue),
+		closed: make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[s] = struct{}{}
+
+	var missed []Event
+	if lastEventID != "" {
+		after := b.resumePoint(lastEventID)
+		for _, ev := range b.replay {
+			if ev.seq > after && s.matches(ev.Topic) {
+				missed = append(missed, ev)
+			}
+		}
+	}
+	return s, missed
+}
+
+// resumePoint 解析 Last-Event-ID，返回需要重放的起始序号
+// 网关重启后事件序号重新计数，此时重放缓冲区中的全部事件
+func (b *Broker) resumePoint(lastEventID string) uint64 {
+	epoch, seq, ok := strings.Cut(lastEventID, "-")
+	if !ok || epoch != b.epoch {
+		return 0
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// Unsubscribe 取消订阅
+func (b *Broker) Unsubscribe(s *Subscriber) {
+	b.mu.Lock()
+	delete(b.subscribers, s)
+	b.mu.Unlock()
+	s.close()
+}
+
+// CloseAll 关闭所有订阅，SSE处理器随之结束响应，客户端按 retry 间隔重连并按 Last-Event-ID 重放
+// 网关退出时调用，否则长连接会让排空一直等到超时
+func (b *Broker) CloseAll() {
+	b.mu.Lock()
+	subscribers := b.subscribers
+	b.subscribers = make(map[*Subscriber]struct{})
+	b.mu.Unlock()
+	for s := range subscribers {
+		s.close()
+	}
+}
+
+// Subscribers 返回当前订阅者数量
+func (b *Broker) Subscribers() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subscribers)
+}
+
+// MatchTopic 判断主题是否匹配订阅模式
+// 模式以 .* 或 * 结尾时按前缀匹配，单独的 * 匹配所有主题
+func MatchTopic(pattern, topic string) bool {
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(topic, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == topic
+}
+
+// Publish 发布事件到全局事件分发中心，供Go插件直接调用
+func Publish(topic, name, data string) Event {
+	return DefaultBroker.Publish(topic, name, data)
+}
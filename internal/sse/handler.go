@@ -0,0 +1,153 @@
+package sse
+
+import (
+	"bigHammer/internal/middleware"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Options SSE路由配置，对应 router.json 中路由的 sse 字段
+type Options struct {
+	// Topics 允许订阅的主题，支持 device.* 前缀通配；为空时允许订阅任意主题
+	// 客户端未通过 topic 参数指定主题时订阅这里配置的全部主题
+	Topics []string `json:"topics,omitempty"`
+	// KeepAlive 发送保活注释的间隔秒数，默认15
+	KeepAlive int `json:"keep_alive,omitempty"`
+	// Retry 建议客户端重连间隔毫秒数，默认3000
+	Retry int `json:"retry,omitempty"`
+}
+
+// keepAlive 返回保活间隔
+func (o Options) keepAlive() time.Duration {
+	if o.KeepAlive <= 0 {
+		return 15 * time.Second
+	}
+	return time.Duration(o.KeepAlive) * time.Second
+}
+
+// retry 返回建议重连间隔
+func (o Options) retry() int {
+	if o.Retry <= 0 {
+		return 3000
+	}
+	return o.Retry
+}
+
+// allowed 判断客户端请求的主题是否在允许范围内
+func (o Options) allowed(topic string) bool {
+	if len(o.Topics) == 0 {
+		return true
+	}
+	for _, pattern := range o.Topics {
+		if MatchTopic(pattern, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestedTopics 解析客户端订阅的主题
+// 支持 ?topic=a&topic=b 与 ?topic=a,b 两种写法
+func requestedTopics(req *http.Request) []string {
+	var topics []string
+	for _, v := range req.URL.Query()["topic"] {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				topics = append(topics, t)
+			}
+		}
+	}
+	return topics
+}
+
+// Handler 返回SSE订阅处理器
+// 功能：
+// 1. 校验客户端订阅的主题
+// 2. 按 Last-Event-ID 请求头（或 last_event_id 查询参数）重放错过的事件
+// 3. 持续推送 text/event-stream，定时发送保活注释
+// 参数：
+//   - broker *Broker: 事件分发中心
+//   - opts Options: 路由配置
+// 返回值：
+//   - http.Handler: SSE处理器
+func Handler(broker *Broker, opts Options) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			middleware.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed", nil)
+			return
+		}
+
+		topics := requestedTopics(req)
+		if len(topics) == 0 {
+			topics = opts.Topics
+		}
+		if len(topics) == 0 {
+			middleware.WriteError(w, http.StatusBadRequest, "No topic specified", nil)
+			return
+		}
+		for _, t := range topics {
+			if !opts.allowed(t) {
+				middleware.WriteError(w, http.StatusForbidden, fmt.Sprintf("Topic %s not allowed", t), nil)
+				return
+			}
+		}
+
+		rc := http.NewResponseController(w)
+		lastEventID := req.Header.Get("Last-Event-ID")
+		if lastEventID == "" {
+			lastEventID = req.URL.Query().Get("last_event_id")
+		}
+		sub, missed := broker.Subscribe(topics, lastEventID)
+		defer broker.Unsubscribe(sub)
+
+		h := w.Header()
+		h.Set("Content-Type", "text/event-stream")
+		h.Set("Cache-Control", "no-cache")
+		h.Set("Connection", "keep-alive")
+		// 关闭 nginx 等反向代理的响应缓冲
+		h.Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "retry: %d\n\n", opts.retry())
+		for _, ev := range missed {
+			writeEvent(w, ev)
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		ticker := time.NewTicker(opts.keepAlive())
+		defer ticker.Stop()
+		for {
+			select {
+			case ev := <-sub.Events():
+				writeEvent(w, ev)
+			case <-ticker.C:
+				fmt.Fprint(w, ": keep-alive\n\n")
+			case <-sub.Done():
+				return
+			case <-req.Context().Done():
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	})
+}
+
+// writeEvent 按 text/event-stream 格式写出事件，多行数据拆分为多个 data 字段
+func writeEvent(w http.ResponseWriter, ev Event) {
+	var b strings.Builder
+	b.WriteString("id: " + ev.ID + "\n")
+	if ev.Name != "" {
+		b.WriteString("event: " + ev.Name + "\n")
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(ev.Data, "\r\n", "\n"), "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+	w.Write([]byte(b.String()))
+}
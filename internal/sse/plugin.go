@@ -0,0 +1,35 @@
+package sse
+
+import (
+	"bigHammer/internal/plugin"
+	"fmt"
+)
+
+// Plugin SSE发布插件
+// 业务进程通过主Socket发送 service=sse、method=publish 的请求发布事件
+// 参数：topic（必填）、event（可选）、data
+type Plugin struct {
+	broker *Broker
+}
+
+// HandleRequest 实现 ServicePlugin 接口的请求处理方法
+func (p *Plugin) HandleRequest(req plugin.Request) plugin.Response {
+	if req.Method != "publish" {
+		return plugin.Response{Status: 400, Message: fmt.Sprintf("Unknown method %s", req.Method)}
+	}
+	topic := req.Params["topic"]
+	if topic == "" {
+		return plugin.Response{Status: 400, Message: "Missing topic"}
+	}
+	ev := p.broker.Publish(topic, req.Params["event"], req.Params["data"])
+	return plugin.Response{Status: 200, Message: "Published", Data: map[string]string{"id": ev.ID}}
+}
+
+// init 包初始化函数
+// 功能：
+// 1. 注册 sse 插件到插件系统
+// 参数：无
+// 返回值：无
+func init() {
+	plugin.RegisterPlugin("sse", &Plugin{broker: DefaultBroker})
+}
@@ -0,0 +1,152 @@
+package transform
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// segment 选择器路径中的一段：对象字段或数组下标
+type segment struct {
+	key   string
+	index int
+	isIdx bool
+}
+
+// Selector 类JSONPath选择器
+// 支持 $.a.b、$.items[0].name、$["key with space"] 等形式，"$." 前缀可省略
+type Selector struct {
+	raw      string
+	segments []segment
+}
+
+// ParseSelector 解析选择器表达式
+// 参数：
+//   - expr string: 选择器表达式
+// 返回值：
+//   - Selector: 解析后的选择器
+//   - error: 表达式语法错误时返回的错误信息
+func ParseSelector(expr string) (Selector, error) {
+	sel := Selector{raw: expr}
+	s := strings.TrimSpace(expr)
+	s = strings.TrimPrefix(s, "$")
+
+	for len(s) > 0 {
+		switch s[0] {
+		case '.':
+			s = s[1:]
+			end := strings.IndexAny(s, ".[")
+			if end < 0 {
+				end = len(s)
+			}
+			if end == 0 {
+				return sel, fmt.Errorf("invalid selector %q: empty field name", expr)
+			}
+			sel.segments = append(sel.segments, segment{key: s[:end]})
+			s = s[end:]
+		case '[':
+			end := strings.IndexByte(s, ']')
+			if end < 0 {
+				return sel, fmt.Errorf("invalid selector %q: missing ]", expr)
+			}
+			inner := strings.TrimSpace(s[1:end])
+			s = s[end+1:]
+			if len(inner) >= 2 && (inner[0] == '"' || inner[0] == '\'') && inner[len(inner)-1] == inner[0] {
+				sel.segments = append(sel.segments, segment{key: inner[1 : len(inner)-1]})
+				continue
+			}
+			idx, err := strconv.Atoi(inner)
+			if err != nil || idx < 0 {
+				return sel, fmt.Errorf("invalid selector %q: bad index %q", expr, inner)
+			}
+			sel.segments = append(sel.segments, segment{index: idx, isIdx: true})
+		default:
+			// 省略 "$." 前缀时首段为字段名
+			if len(sel.segments) > 0 {
+				return sel, fmt.Errorf("invalid selector %q", expr)
+			}
+			s = "." + s
+		}
+	}
+	return sel, nil
+}
+
+// String 返回选择器原始表达式
+func (sel Selector) String() string {
+	return sel.raw
+}
+
+// Get 读取文档中选择器指向的值
+func (sel Selector) Get(doc interface{}) (interface{}, bool) {
+	cur := doc
+	for _, seg := range sel.segments {
+		if seg.isIdx {
+			list, ok := cur.([]interface{})
+			if !ok || seg.index >= len(list) {
+				return nil, false
+			}
+			cur = list[seg.index]
+			continue
+		}
+		obj, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = obj[seg.key]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// Set 在文档中写入值，自动创建缺失的中间对象和数组
+// 返回写入后的文档（根节点可能被替换）
+func (sel Selector) Set(doc interface{}, value interface{}) interface{} {
+	return setPath(doc, sel.segments, value)
+}
+
+func setPath(cur interface{}, segs []segment, value interface{}) interface{} {
+	if len(segs) == 0 {
+		return value
+	}
+	seg := segs[0]
+	if seg.isIdx {
+		list, _ := cur.([]interface{})
+		for len(list) <= seg.index {
+			list = append(list, nil)
+		}
+		list[seg.index] = setPath(list[seg.index], segs[1:], value)
+		return list
+	}
+	obj, ok := cur.(map[string]interface{})
+	if !ok {
+		obj = make(map[string]interface{})
+	}
+	obj[seg.key] = setPath(obj[seg.key], segs[1:], value)
+	return obj
+}
+
+// Delete 删除文档中选择器指向的字段或数组元素
+func (sel Selector) Delete(doc interface{}) interface{} {
+	if len(sel.segments) == 0 {
+		return nil
+	}
+	parentSel := Selector{segments: sel.segments[:len(sel.segments)-1]}
+	parent, ok := parentSel.Get(doc)
+	if !ok {
+		return doc
+	}
+	last := sel.segments[len(sel.segments)-1]
+	if last.isIdx {
+		list, ok := parent.([]interface{})
+		if !ok || last.index >= len(list) {
+			return doc
+		}
+		list = append(list[:last.index:last.index], list[last.index+1:]...)
+		return parentSel.Set(doc, list)
+	}
+	if obj, ok := parent.(map[string]interface{}); ok {
+		delete(obj, last.key)
+	}
+	return doc
+}
@@ -0,0 +1,399 @@
+package transform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+)
+
+// FieldRules 请求头/响应头/查询参数的变换规则
+type FieldRules struct {
+	// Add 设置字段（已存在时覆盖）
+	Add map[string]string `json:"add,omitempty"`
+	// Remove 删除字段
+	Remove []string `json:"remove,omitempty"`
+	// Rename 重命名字段 key: 原名称 value: 新名称
+	Rename map[string]string `json:"rename,omitempty"`
+}
+
+// BodyRules JSON请求体/响应体的变换规则
+// 执行顺序：Map → Set → Remove → Filter
+type BodyRules struct {
+	// Map 字段映射 key: 目标选择器 value: 源选择器
+	// 配置后结果以新对象为基础构建，源选择器作用于 {"body", "query", "headers"} 上下文
+	Map map[string]string `json:"map,omitempty"`
+	// Set 写入常量 key: 目标选择器 value: 常量值
+	Set map[string]interface{} `json:"set,omitempty"`
+	// Remove 删除的字段选择器
+	Remove []string `json:"remove,omitempty"`
+	// Filter 仅保留的字段选择器
+	Filter []string `json:"filter,omitempty"`
+}
+
+// TemplateRule 静态响应模板
+// 配置后网关直接渲染模板作为响应，不再调用业务进程
+type TemplateRule struct {
+	// Status 响应状态码，默认200
+	Status int `json:"status,omitempty"`
+	// ContentType 响应类型，默认 application/json
+	ContentType string `json:"content_type,omitempty"`
+	// Headers 附加响应头
+	Headers map[string]string `json:"headers,omitempty"`
+	// Body text/template 模板，数据为 {"body", "query", "headers", "route"}
+	// 模板不会自动转义，请求中的值必须通过 json 函数输出，例如 {"id": {{json .query.id}}}，
+	// 否则调用方可以在值中注入JSON结构；content_type 为JSON时渲染结果必须是合法JSON
+	Body string `json:"body"`
+}
+
+// RequestRules 请求变换规则，在组装IPC requestData 之前执行
+type RequestRules struct {
+	Headers *FieldRules `json:"headers,omitempty"`
+	Query   *FieldRules `json:"query,omitempty"`
+	Body    *BodyRules  `json:"body,omitempty"`
+}
+
+// ResponseRules 响应变换规则，在收到IPC响应之后执行
+type ResponseRules struct {
+	Headers  *FieldRules   `json:"headers,omitempty"`
+	Body     *BodyRules    `json:"body,omitempty"`
+	Template *TemplateRule `json:"template,omitempty"`
+}
+
+// Rules 路由的变换规则，对应 router.json 中路由的 transform 字段
+type Rules struct {
+	Request  *RequestRules  `json:"request,omitempty"`
+	Response *ResponseRules `json:"response,omitempty"`
+}
+
+// compiledBody 预解析选择器后的请求体规则
+type compiledBody struct {
+	mapTargets []Selector
+	mapSources []Selector
+	setTargets []Selector
+	// setValues 常量值的JSON编码，每次执行时解码出新的副本，
+	// 请求中对写入值的修改不会影响路由配置，也不会在并发请求之间共享
+	setValues []json.RawMessage
+	remove    []Selector
+	filter    []Selector
+}
+
+// Transformer 编译后的变换器
+// nil 值表示路由未配置变换，所有方法原样返回输入
+type Transformer struct {
+	rules        Rules
+	requestBody  *compiledBody
+	responseBody *compiledBody
+	template     *template.Template
+}
+
+// Compile 编译变换规则
+// 功能：
+// 1. 解析所有选择器
+// 2. 解析静态响应模板
+// 参数：
+//   - rules *Rules: 变换规则，为nil时返回nil
+// 返回值：
+//   - *Transformer: 编译后的变换器
+//   - error: 选择器或模板语法错误时返回的错误信息
+func Compile(rules *Rules) (*Transformer, error) {
+	if rules == nil {
+		return nil, nil
+	}
+	t := &Transformer{rules: *rules}
+	var err error
+	if rules.Request != nil && rules.Request.Body != nil {
+		if t.requestBody, err = compileBody(rules.Request.Body); err != nil {
+			return nil, fmt.Errorf("request body: %v", err)
+		}
+	}
+	if rules.Response != nil && rules.Response.Body != nil {
+		if t.responseBody, err = compileBody(rules.Response.Body); err != nil {
+			return nil, fmt.Errorf("response body: %v", err)
+		}
+	}
+	if rules.Response != nil && rules.Response.Template != nil {
+		if t.template, err = template.New("response").Option("missingkey=zero").Funcs(templateFuncs).Parse(rules.Response.Template.Body); err != nil {
+			return nil, fmt.Errorf("response template: %v", err)
+		}
+	}
+	return t, nil
+}
+
+// templateFuncs 响应模板可用的函数
+var templateFuncs = template.FuncMap{
+	// json 将值编码为JSON（字符串带引号并转义），用于在JSON模板中安全地输出请求中的值
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// isJSON 判断响应类型是否为JSON（application/json 或 +json 后缀）
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// compileBody 解析请求体规则中的选择器
+func compileBody(rules *BodyRules) (*compiledBody, error) {
+	cb := &compiledBody{}
+	for target, source := range rules.Map {
+		ts, err := ParseSelector(target)
+		if err != nil {
+			return nil, err
+		}
+		ss, err := ParseSelector(source)
+		if err != nil {
+			return nil, err
+		}
+		cb.mapTargets, cb.mapSources = append(cb.mapTargets, ts), append(cb.mapSources, ss)
+	}
+	for target, value := range rules.Set {
+		ts, err := ParseSelector(target)
+		if err != nil {
+			return nil, err
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("set %s: %v", target, err)
+		}
+		cb.setTargets, cb.setValues = append(cb.setTargets, ts), append(cb.setValues, encoded)
+	}
+	for _, expr := range rules.Remove {
+		sel, err := ParseSelector(expr)
+		if err != nil {
+			return nil, err
+		}
+		cb.remove = append(cb.remove, sel)
+	}
+	for _, expr := range rules.Filter {
+		sel, err := ParseSelector(expr)
+		if err != nil {
+			return nil, err
+		}
+		cb.filter = append(cb.filter, sel)
+	}
+	return cb, nil
+}
+
+// apply 对文档执行请求体规则
+func (cb *compiledBody) apply(body interface{}, context map[string]interface{}) interface{} {
+	if len(cb.mapTargets) > 0 {
+		var mapped interface{} = make(map[string]interface{})
+		for i, target := range cb.mapTargets {
+			if value, ok := cb.mapSources[i].Get(context); ok {
+				mapped = target.Set(mapped, value)
+			}
+		}
+		body = mapped
+	}
+	for i, target := range cb.setTargets {
+		var value interface{}
+		json.Unmarshal(cb.setValues[i], &value)
+		body = target.Set(body, value)
+	}
+	for _, sel := range cb.remove {
+		body = sel.Delete(body)
+	}
+	if len(cb.filter) > 0 {
+		var filtered interface{} = make(map[string]interface{})
+		for _, sel := range cb.filter {
+			if value, ok := sel.Get(body); ok {
+				filtered = sel.Set(filtered, value)
+			}
+		}
+		body = filtered
+	}
+	return body
+}
+
+// applyHeaders 对HTTP头执行字段规则
+func applyHeaders(h http.Header, rules *FieldRules) {
+	if rules == nil {
+		return
+	}
+	for from, to := range rules.Rename {
+		if values, ok := h[http.CanonicalHeaderKey(from)]; ok {
+			h.Del(from)
+			h[http.CanonicalHeaderKey(to)] = values
+		}
+	}
+	for _, name := range rules.Remove {
+		h.Del(name)
+	}
+	for name, value := range rules.Add {
+		h.Set(name, value)
+	}
+}
+
+// applyQuery 对查询参数执行字段规则
+func applyQuery(q url.Values, rules *FieldRules) {
+	if rules == nil {
+		return
+	}
+	for from, to := range rules.Rename {
+		if values, ok := q[from]; ok {
+			delete(q, from)
+			q[to] = values
+		}
+	}
+	for _, name := range rules.Remove {
+		q.Del(name)
+	}
+	for name, value := range rules.Add {
+		q.Set(name, value)
+	}
+}
+
+// flatten 将多值映射转换为选择器可访问的文档，单值字段展开为字符串
+func flatten(values map[string][]string) map[string]interface{} {
+	doc := make(map[string]interface{}, len(values))
+	for key, list := range values {
+		if len(list) == 1 {
+			doc[key] = list[0]
+			continue
+		}
+		items := make([]interface{}, len(list))
+		for i, v := range list {
+			items[i] = v
+		}
+		doc[key] = items
+	}
+	return doc
+}
+
+// TemplateData 构造选择器和模板使用的上下文
+func TemplateData(header http.Header, query url.Values, body interface{}, route string) map[string]interface{} {
+	return map[string]interface{}{
+		"body":    body,
+		"query":   flatten(query),
+		"headers": flatten(header),
+		"route":   route,
+	}
+}
+
+// TransformRequest 变换请求
+// 参数：
+//   - header http.Header: 请求头（原地修改）
+//   - query url.Values: 查询参数（原地修改）
+//   - body interface{}: 解析后的请求体
+// 返回值：
+//   - interface{}: 变换后的请求体
+func (t *Transformer) TransformRequest(header http.Header, query url.Values, body interface{}) interface{} {
+	if t == nil || t.rules.Request == nil {
+		return body
+	}
+	context := TemplateData(header, query, body, "")
+	applyHeaders(header, t.rules.Request.Headers)
+	applyQuery(query, t.rules.Request.Query)
+	if t.requestBody != nil {
+		body = t.requestBody.apply(body, context)
+	}
+	return body
+}
+
+// TransformRequestHeaders 只对请求头和查询参数执行变换，供不解析请求体的路由（如HTTP反向代理）使用
+// 参数：
+//   - header http.Header: 请求头（原地修改）
+//   - query url.Values: 查询参数（原地修改）
+// 返回值：无
+func (t *Transformer) TransformRequestHeaders(header http.Header, query url.Values) {
+	if t == nil || t.rules.Request == nil {
+		return
+	}
+	applyHeaders(header, t.rules.Request.Headers)
+	applyQuery(query, t.rules.Request.Query)
+}
+
+// TransformResponseHeaders 只对响应头执行变换，供不缓冲响应体的路由（如HTTP反向代理）使用
+// 参数：
+//   - header http.Header: 响应头（原地修改）
+// 返回值：无
+func (t *Transformer) TransformResponseHeaders(header http.Header) {
+	if t == nil || t.rules.Response == nil {
+		return
+	}
+	applyHeaders(header, t.rules.Response.Headers)
+}
+
+// ModifiesQuery 返回是否配置了查询参数变换
+func (t *Transformer) ModifiesQuery() bool {
+	return t != nil && t.rules.Request != nil && t.rules.Request.Query != nil
+}
+
+// HasTemplate 返回是否配置了静态响应模板
+func (t *Transformer) HasTemplate() bool {
+	return t != nil && t.template != nil
+}
+
+// RenderTemplate 渲染静态响应模板
+// 参数：
+//   - w http.ResponseWriter: 响应写入器
+//   - data map[string]interface{}: 模板数据，见 TemplateData
+// 返回值：
+//   - error: 模板执行失败时返回的错误信息（此时尚未写入响应）
+func (t *Transformer) RenderTemplate(w http.ResponseWriter, data map[string]interface{}) error {
+	rule := t.rules.Response.Template
+	var buf bytes.Buffer
+	if err := t.template.Execute(&buf, data); err != nil {
+		return err
+	}
+	contentType := rule.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	if isJSON(contentType) && !json.Valid(buf.Bytes()) {
+		return fmt.Errorf("response template rendered invalid JSON")
+	}
+	for name, value := range rule.Headers {
+		w.Header().Set(name, value)
+	}
+	w.Header().Set("Content-Type", contentType)
+	applyHeaders(w.Header(), t.rules.Response.Headers)
+	status := rule.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// TransformResponse 变换业务进程返回的响应
+// 功能：
+// 1. 对响应头执行字段规则
+// 2. 响应体为JSON时执行响应体规则，非JSON响应体保持原样
+// 参数：
+//   - header http.Header: 响应头（原地修改）
+//   - output []byte: 业务进程返回的响应体
+// 返回值：
+//   - []byte: 变换后的响应体
+//   - bool: 响应体是否经过JSON变换
+func (t *Transformer) TransformResponse(header http.Header, output []byte) ([]byte, bool) {
+	if t == nil || t.rules.Response == nil {
+		return output, false
+	}
+	applyHeaders(header, t.rules.Response.Headers)
+	if t.responseBody == nil {
+		return output, false
+	}
+
+	var body interface{}
+	if err := json.Unmarshal(output, &body); err != nil {
+		return output, false
+	}
+	body = t.responseBody.apply(body, map[string]interface{}{"body": body, "headers": flatten(header)})
+	transformed, err := json.Marshal(body)
+	if err != nil {
+		return output, false
+	}
+	return transformed, true
+}
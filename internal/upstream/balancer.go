@@ -0,0 +1,133 @@
+package upstream
+
+import (
+	"fmt"
+	"hash/crc32"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// 负载均衡策略
+const (
+	RoundRobin     = "round_robin"
+	LeastInflight  = "least_inflight"
+	ConsistentHash = "consistent_hash"
+)
+
+// virtualNodes 一致性哈希环上每个端点的虚拟节点数
+const virtualNodes = 100
+
+// balancer 负载均衡器，从可用端点中选择一个
+type balancer interface {
+	pick(p *Pool, key string, now time.Time) *Endpoint
+}
+
+// newBalancer 根据策略名称创建负载均衡器
+func newBalancer(name string, endpoints []*Endpoint) (balancer, error) {
+	switch name {
+	case "", RoundRobin:
+		return &roundRobin{}, nil
+	case LeastInflight:
+		return leastInflight{}, nil
+	case ConsistentHash:
+		return newHashRing(endpoints), nil
+	default:
+		return nil, fmt.Errorf("unknown balance strategy %q", name)
+	}
+}
+
+// admit 按慢启动权重决定是否选择端点，权重为1时总是选择
+func admit(p *Pool, e *Endpoint, now time.Time) bool {
+	w := e.weight(now, p.opts.slowStart())
+	return w >= 1 || rand.Float64() < w
+}
+
+// roundRobin 轮询
+type roundRobin struct {
+	next uint64
+}
+
+func (b *roundRobin) pick(p *Pool, key string, now time.Time) *Endpoint {
+	n := len(p.endpoints)
+	start := int(atomic.AddUint64(&b.next, 1) % uint64(n))
+	var fallback *Endpoint
+	for i := 0; i < n; i++ {
+		e := p.endpoints[(start+i)%n]
+		if !e.available(now) {
+			continue
+		}
+		if admit(p, e, now) {
+			return e
+		}
+		if fallback == nil {
+			fallback = e
+		}
+	}
+	return fallback
+}
+
+// leastInflight 选择在途请求最少的端点，慢启动期间按权重放大在途数
+type leastInflight struct{}
+
+func (leastInflight) pick(p *Pool, key string, now time.Time) *Endpoint {
+	var best *Endpoint
+	bestScore := 0.0
+	for _, e := range p.endpoints {
+		if !e.available(now) {
+			continue
+		}
+		w := e.weight(now, p.opts.slowStart())
+		score := float64(e.Inflight()+1) / w
+		if best == nil || score < bestScore {
+			best, bestScore = e, score
+		}
+	}
+	return best
+}
+
+// hashRing 一致性哈希，相同键总是落到同一端点，端点不可用时顺延到环上的下一个端点
+type hashRing struct {
+	hashes []uint32
+	owners map[uint32]*Endpoint
+}
+
+func newHashRing(endpoints []*Endpoint) *hashRing {
+	r := &hashRing{owners: make(map[uint32]*Endpoint)}
+	for _, e := range endpoints {
+		for i := 0; i < virtualNodes; i++ {
+			h := crc32.ChecksumIEEE([]byte(e.Address + "#" + strconv.Itoa(i)))
+			if _, exists := r.owners[h]; exists {
+				continue
+			}
+			r.owners[h] = e
+			r.hashes = append(r.hashes, h)
+		}
+	}
+	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+	return r
+}
+
+func (r *hashRing) pick(p *Pool, key string, now time.Time) *Endpoint {
+	if len(r.hashes) == 0 {
+		return nil
+	}
+	h := crc32.ChecksumIEEE([]byte(key))
+	start := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	var fallback *Endpoint
+	for i := 0; i < len(r.hashes); i++ {
+		e := r.owners[r.hashes[(start+i)%len(r.hashes)]]
+		if !e.available(now) {
+			continue
+		}
+		if admit(p, e, now) {
+			return e
+		}
+		if fallback == nil {
+			fallback = e
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,98 @@
+package upstream
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Endpoint 业务进程端点（一个 worker 的Unix Socket或TCP地址）
+type Endpoint struct {
+	// Address 端点地址，如 /runtime/php1.sock、unix:///runtime/php1.sock、tcp://127.0.0.1:9001
+	Address string
+
+	inflight int64
+
+	mu sync.Mutex
+	// healthy 主动健康检查结果
+	healthy bool
+	// drained 是否被管理接口摘除，摘除后不再接收新请求，在途请求正常完成
+	drained bool
+	// checkSuccesses / checkFailures 连续健康检查成功/失败次数
+	checkSuccesses int
+	checkFailures  int
+	// failures 连续请求失败次数（被动检测）
+	failures int
+	// ejections 连续被剔除次数，剔除时间按次数递增
+	ejections int
+	// ejectedUntil 被动剔除截止时间
+	ejectedUntil time.Time
+	// admittedAt 最近一次恢复可用的时间，用于慢启动
+	admittedAt time.Time
+}
+
+// EndpointStatus 端点状态快照
+type EndpointStatus struct {
+	Address      string `json:"address"`
+	Healthy      bool   `json:"healthy"`
+	Ejected      bool   `json:"ejected"`
+	Drained      bool   `json:"drained"`
+	Inflight     int64  `json:"inflight"`
+	Failures     int    `json:"failures"`
+	EjectedUntil string `json:"ejected_until,omitempty"`
+	// Weight 当前有效权重（0~1），慢启动期间小于1
+	Weight float64 `json:"weight"`
+}
+
+// Inflight 返回正在处理的请求数
+func (e *Endpoint) Inflight() int64 {
+	return atomic.LoadInt64(&e.inflight)
+}
+
+// available 判断端点当前是否可以接收请求
+func (e *Endpoint) available(now time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.healthy && !e.drained && !now.Before(e.ejectedUntil)
+}
+
+// weight 返回慢启动期间的有效权重，从10%线性增长到100%
+func (e *Endpoint) weight(now time.Time, slowStart time.Duration) float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	admitted := e.admittedAt
+	if e.ejectedUntil.After(admitted) {
+		admitted = e.ejectedUntil
+	}
+	if slowStart <= 0 || admitted.IsZero() {
+		return 1
+	}
+	elapsed := now.Sub(admitted)
+	if elapsed >= slowStart {
+		return 1
+	}
+	if elapsed < 0 {
+		return 0
+	}
+	return 0.1 + 0.9*float64(elapsed)/float64(slowStart)
+}
+
+// status 返回端点状态快照
+func (e *Endpoint) status(now time.Time, slowStart time.Duration) EndpointStatus {
+	w := e.weight(now, slowStart)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	s := EndpointStatus{
+		Address:  e.Address,
+		Healthy:  e.healthy,
+		Ejected:  now.Before(e.ejectedUntil),
+		Drained:  e.drained,
+		Inflight: e.Inflight(),
+		Failures: e.failures,
+		Weight:   w,
+	}
+	if s.Ejected {
+		s.EjectedUntil = e.ejectedUntil.Format(time.RFC3339)
+	}
+	return s
+}
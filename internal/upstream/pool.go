@@ -0,0 +1,420 @@
+package upstream
+
+import (
+	"bigHammer/internal/middleware"
+	"bigHammer/internal/middleware/auth"
+	ipc "bigHammer/internal/ipc/socket"
+	"bigHammer/pkg/utils"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ErrNoEndpoint 后端没有可用端点
+var ErrNoEndpoint = errors.New("no healthy endpoint available")
+
+// ErrUnknownEndpoint 后端中不存在指定地址的端点
+var ErrUnknownEndpoint = errors.New("unknown endpoint")
+
+var (
+	endpointHealthy = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gateway_upstream_endpoint_up",
+		Help: "后端端点是否可用（健康且未被剔除）",
+	}, []string{"backend", "endpoint"})
+	endpointRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_upstream_requests_total",
+		Help: "发送到后端端点的请求数，result 取值 success / error",
+	}, []string{"backend", "endpoint", "result"})
+)
+
+// HealthCheckOptions 主动健康检查配置
+type HealthCheckOptions struct {
+	// Type 检查方式：heartbeat（发送0x02心跳包，默认）或 connect（仅建立连接）
+	Type string `json:"type,omitempty"`
+	// Interval 检查间隔秒数，0表示不做主动检查
+	Interval int `json:"interval,omitempty"`
+	// Timeout 单次检查超时秒数，默认2
+	Timeout int `json:"timeout,omitempty"`
+	// UnhealthyThreshold 连续失败多少次标记为不健康，默认3
+	UnhealthyThreshold int `json:"unhealthy_threshold,omitempty"`
+	// HealthyThreshold 连续成功多少次恢复为健康，默认2
+	HealthyThreshold int `json:"healthy_threshold,omitempty"`
+}
+
+// Options 后端配置，对应 router.json 中 backends 的一项
+type Options struct {
+	// Endpoints worker 端点地址列表
+	Endpoints []string `json:"endpoints"`
+	// Balance 负载均衡策略：round_robin（默认）、least_inflight、consistent_hash
+	Balance string `json:"balance,omitempty"`
+	// HashKey 一致性哈希的键：ip（默认）、identity、header:<名称>、query:<参数名>
+	HashKey string `json:"hash_key,omitempty"`
+	// HealthCheck 主动健康检查
+	HealthCheck HealthCheckOptions `json:"health_check,omitempty"`
+	// MaxFailures 连续请求失败多少次后剔除端点，0表示不做被动剔除
+	MaxFailures int `json:"max_failures,omitempty"`
+	// EjectTime 剔除秒数，连续剔除时按次数递增（最多5倍），默认30
+	EjectTime int `json:"eject_time,omitempty"`
+	// SlowStart 端点恢复后权重从10%增长到100%的秒数，0表示立即全量
+	SlowStart int `json:"slow_start,omitempty"`
+}
+
+func (o Options) slowStart() time.Duration {
+	return time.Duration(o.SlowStart) * time.Second
+}
+
+func (o Options) ejectTime() time.Duration {
+	if o.EjectTime <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(o.EjectTime) * time.Second
+}
+
+func (o HealthCheckOptions) timeout() time.Duration {
+	if o.Timeout <= 0 {
+		return 2 * time.Second
+	}
+	return time.Duration(o.Timeout) * time.Second
+}
+
+func (o HealthCheckOptions) unhealthyThreshold() int {
+	if o.UnhealthyThreshold <= 0 {
+		return 3
+	}
+	return o.UnhealthyThreshold
+}
+
+func (o HealthCheckOptions) healthyThreshold() int {
+	if o.HealthyThreshold <= 0 {
+		return 2
+	}
+	return o.HealthyThreshold
+}
+
+// Pool 由多个 worker 端点组成的后端
+type Pool struct {
+	Name      string
+	opts      Options
+	endpoints []*Endpoint
+	balancer  balancer
+	stop      chan struct{}
+	stopOnce  sync.Once
+}
+
+// NewPool 创建后端
+// 参数：
+//   - name string: 后端名称
+//   - opts Options: 后端配置
+// 返回值：
+//   - *Pool: 后端
+//   - error: 配置无效时返回的错误信息
+func NewPool(name string, opts Options) (*Pool, error) {
+	if len(opts.Endpoints) == 0 {
+		return nil, fmt.Errorf("backend %s has no endpoints", name)
+	}
+	if t := opts.HealthCheck.Type; t != "" && t != "heartbeat" && t != "connect" {
+		return nil, fmt.Errorf("backend %s: unknown health check type %q", name, t)
+	}
+	p := &Pool{Name: name, opts: opts, stop: make(chan struct{})}
+	for _, address := range opts.Endpoints {
+		resolved, err := resolveAddress(address)
+		if err != nil {
+			return nil, fmt.Errorf("backend %s: %v", name, err)
+		}
+		p.endpoints = append(p.endpoints, &Endpoint{Address: resolved, healthy: true})
+		endpointHealthy.WithLabelValues(name, resolved).Set(1)
+	}
+	var err error
+	if p.balancer, err = newBalancer(opts.Balance, p.endpoints); err != nil {
+		return nil, fmt.Errorf("backend %s: %v", name, err)
+	}
+	return p, nil
+}
+
+// resolveAddress 将项目相对的Unix Socket路径解析为绝对路径，TCP地址保持不变
+func resolveAddress(address string) (string, error) {
+	if strings.HasPrefix(address, "tcp://") {
+		return address, nil
+	}
+	return utils.ResolvePath(strings.TrimPrefix(address, "unix://"))
+}
+
+// Key 根据配置的 hash_key 提取一致性哈希的键
+func (p *Pool) Key(req *http.Request) string {
+	switch key := p.opts.HashKey; {
+	case key == "identity":
+		if id := auth.IdentityFromContext(req.Context()); id != nil {
+			return id.Subject
+		}
+	case strings.HasPrefix(key, "header:"):
+		if value := req.Header.Get(strings.TrimPrefix(key, "header:")); value != "" {
+			return value
+		}
+	case strings.HasPrefix(key, "query:"):
+		if value := req.URL.Query().Get(strings.TrimPrefix(key, "query:")); value != "" {
+			return value
+		}
+	}
+	return middleware.ClientIP(req)
+}
+
+// Pick 选择一个端点并增加其在途请求数，使用完毕后必须调用 Done
+// 参数：
+//   - key string: 一致性哈希的键，其他策略忽略
+// 返回值：
+//   - *Endpoint: 选中的端点
+//   - error: 没有可用端点时返回 ErrNoEndpoint
+func (p *Pool) Pick(key string) (*Endpoint, error) {
+	e := p.balancer.pick(p, key, time.Now())
+	if e == nil {
+		return nil, ErrNoEndpoint
+	}
+	atomic.AddInt64(&e.inflight, 1)
+	return e, nil
+}
+
+// Done 记录请求结果并减少在途请求数
+// 连续失败达到 max_failures 时剔除端点，剔除结束后按 slow_start 逐步恢复流量
+func (p *Pool) Done(e *Endpoint, err error) {
+	atomic.AddInt64(&e.inflight, -1)
+	if err == nil {
+		endpointRequests.WithLabelValues(p.Name, e.Address, "success").Inc()
+		e.mu.Lock()
+		e.failures = 0
+		if time.Now().After(e.ejectedUntil.Add(p.opts.slowStart())) {
+			e.ejections = 0
+		}
+		e.mu.Unlock()
+		return
+	}
+
+	endpointRequests.WithLabelValues(p.Name, e.Address, "error").Inc()
+	if p.opts.MaxFailures <= 0 {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.failures++
+	if e.failures < p.opts.MaxFailures {
+		return
+	}
+	e.failures = 0
+	if e.ejections < 5 {
+		e.ejections++
+	}
+	e.ejectedUntil = time.Now().Add(p.opts.ejectTime() * time.Duration(e.ejections))
+	endpointHealthy.WithLabelValues(p.Name, e.Address).Set(0)
+	log.Printf("后端 %s 端点 %s 连续失败，剔除至 %s", p.Name, e.Address, e.ejectedUntil.Format(time.RFC3339))
+	go p.markAdmitted(e, e.ejectedUntil)
+}
+
+// markAdmitted 剔除结束后更新可用指标
+func (p *Pool) markAdmitted(e *Endpoint, until time.Time) {
+	select {
+	case <-time.After(time.Until(until)):
+	case <-p.stop:
+		return
+	}
+	if e.available(time.Now()) {
+		endpointHealthy.WithLabelValues(p.Name, e.Address).Set(1)
+	}
+}
+
+// Status 返回所有端点的状态快照
+func (p *Pool) Status() []EndpointStatus {
+	now := time.Now()
+	statuses := make([]EndpointStatus, len(p.endpoints))
+	for i, e := range p.endpoints {
+		statuses[i] = e.status(now, p.opts.slowStart())
+	}
+	return statuses
+}
+
+// Start 启动主动健康检查（未配置 interval 时不启动）
+func (p *Pool) Start() {
+	if p.opts.HealthCheck.Interval <= 0 {
+		return
+	}
+	go p.healthLoop(time.Duration(p.opts.HealthCheck.Interval) * time.Second)
+}
+
+// Stop 停止健康检查
+func (p *Pool) Stop() {
+	p.stopOnce.Do(func() { close(p.stop) })
+}
+
+// healthLoop 定期检查所有端点
+func (p *Pool) healthLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			var wg sync.WaitGroup
+			for _, e := range p.endpoints {
+				wg.Add(1)
+				go func(e *Endpoint) {
+					defer wg.Done()
+					p.check(e)
+				}(e)
+			}
+			wg.Wait()
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// check 对单个端点执行一次健康检查，连续成功/失败达到阈值时切换状态
+func (p *Pool) check(e *Endpoint) {
+	hc := p.opts.HealthCheck
+	var err error
+	if hc.Type == "connect" {
+		err = ipc.Ping(e.Address, hc.timeout())
+	} else {
+		err = ipc.Heartbeat(e.Address, hc.timeout())
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err != nil {
+		e.checkSuccesses = 0
+		e.checkFailures++
+		if e.healthy && e.checkFailures >= hc.unhealthyThreshold() {
+			e.healthy = false
+			endpointHealthy.WithLabelValues(p.Name, e.Address).Set(0)
+			log.Printf("后端 %s 端点 %s 健康检查失败，标记为不可用: %v", p.Name, e.Address, err)
+		}
+		return
+	}
+	e.checkFailures = 0
+	e.checkSuccesses++
+	if !e.healthy && e.checkSuccesses >= hc.healthyThreshold() {
+		e.healthy = true
+		e.admittedAt = time.Now()
+		if !e.drained && !time.Now().Before(e.ejectedUntil) {
+			endpointHealthy.WithLabelValues(p.Name, e.Address).Set(1)
+		}
+		log.Printf("后端 %s 端点 %s 健康检查恢复", p.Name, e.Address)
+	}
+}
+
+var (
+	poolsMu sync.RWMutex
+	// pools 已注册的后端
+	// key: 后端名称
+	pools = make(map[string]*Pool)
+)
+
+// Register 以本次编译的后端替换全局注册表并启动健康检查
+// 功能：
+// 1. 同名的旧后端被停止，旧后端中被摘除的端点在新后端中保持摘除状态
+// 2. 不在新配置中的旧后端被停止并移除，不再出现在管理接口和健康指标中
+// 参数：
+//   - list []*Pool: 生效路由使用的所有后端
+// 返回值：无
+func Register(list []*Pool) {
+	next := make(map[string]*Pool, len(list))
+	for _, p := range list {
+		next[p.Name] = p
+	}
+	poolsMu.Lock()
+	old := pools
+	pools = next
+	poolsMu.Unlock()
+
+	for name, prev := range old {
+		p := next[name]
+		for _, e := range prev.endpoints {
+			if p == nil || !p.hasEndpoint(e.Address) {
+				endpointHealthy.DeleteLabelValues(name, e.Address)
+				continue
+			}
+			e.mu.Lock()
+			drained := e.drained
+			e.mu.Unlock()
+			if drained {
+				p.SetDrained(e.Address, true)
+			}
+		}
+		prev.Stop()
+	}
+	for _, p := range list {
+		p.Start()
+	}
+}
+
+// hasEndpoint 判断后端是否包含指定地址的端点
+func (p *Pool) hasEndpoint(address string) bool {
+	for _, e := range p.endpoints {
+		if e.Address == address {
+			return true
+		}
+	}
+	return false
+}
+
+// SetDrained 摘除或恢复端点
+// 摘除的端点不再被选中，在途请求正常完成；恢复时按 slow_start 逐步恢复流量
+// 参数：
+//   - address string: 端点地址（配置中的写法或解析后的地址），为空时作用于所有端点
+//   - drained bool: true 摘除，false 恢复
+// 返回值：
+//   - int: 状态发生变化的端点数
+//   - error: 端点不存在时返回 ErrUnknownEndpoint
+func (p *Pool) SetDrained(address string, drained bool) (int, error) {
+	resolved, _ := resolveAddress(address)
+	changed, found := 0, false
+	for _, e := range p.endpoints {
+		if address != "" && e.Address != address && e.Address != resolved {
+			continue
+		}
+		found = true
+		e.mu.Lock()
+		if e.drained != drained {
+			e.drained = drained
+			changed++
+			if !drained {
+				e.admittedAt = time.Now()
+			}
+		}
+		up := e.healthy && !e.drained && !time.Now().Before(e.ejectedUntil)
+		e.mu.Unlock()
+		if up {
+			endpointHealthy.WithLabelValues(p.Name, e.Address).Set(1)
+		} else {
+			endpointHealthy.WithLabelValues(p.Name, e.Address).Set(0)
+		}
+	}
+	if !found {
+		return 0, ErrUnknownEndpoint
+	}
+	return changed, nil
+}
+
+// Get 根据名称查找已注册的后端
+func Get(name string) (*Pool, bool) {
+	poolsMu.RLock()
+	defer poolsMu.RUnlock()
+	p, ok := pools[name]
+	return p, ok
+}
+
+// Pools 返回所有已注册的后端
+func Pools() map[string]*Pool {
+	poolsMu.RLock()
+	defer poolsMu.RUnlock()
+	result := make(map[string]*Pool, len(pools))
+	for name, p := range pools {
+		result[name] = p
+	}
+	return result
+}
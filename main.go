@@ -4,18 +4,26 @@ import (
 	"bigHammer/internal/config"
 	"bigHammer/internal/di"
 	"bigHammer/internal/ipc/cmd"
+	"bigHammer/internal/lifecycle"
+	"bigHammer/internal/openapi"
 	"bigHammer/internal/plugin"
 	"bigHammer/internal/plugin/agilitymemdb"
+	"bigHammer/internal/router"
+	"bigHammer/internal/service/admin"
 	"bigHammer/internal/service/http"
 	"bigHammer/internal/service/socket"
+	"bigHammer/internal/service/websocket"
 	"bigHammer/internal/shared"
 	"bigHammer/pkg/utils"
 	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 )
 
 // globalConfig 全局配置变量
@@ -142,9 +150,98 @@ func cleanup() {
 	fmt.Println("清理完成，程序已退出。")
 }
 
+// drain 排空在途工作
+// 功能：
+// 1. 标记网关未就绪（就绪检查返回503），等待 ready_delay 秒供负载均衡摘除流量
+// 2. 取消前端上下文，HTTP/WebSocket/管理接口停止接收新请求
+// 3. 等待在途HTTP请求、IPC调用和异步请求完成，最长 drain_timeout 秒
+// 4. 停止Socket服务器
+// 排空期间再次收到退出信号时立即停止等待
+// 参数：
+//   - sigChan chan os.Signal: 退出信号通道
+//   - cancel context.CancelFunc: 取消前端服务的上下文
+//   - cancelIPC context.CancelFunc: 取消Socket服务器的上下文
+// 返回值：无
+func drain(sigChan chan os.Signal, cancel, cancelIPC context.CancelFunc) {
+	defer cancelIPC()
+	shutdownCfg := globalConfig.Shutdown
+	lifecycle.BeginDrain(shutdownCfg.DrainTimeoutDuration())
+	drainCtx, stop := lifecycle.DrainContext(shutdownCfg.DrainTimeoutDuration())
+	defer stop()
+	go func() {
+		select {
+		case <-sigChan:
+			fmt.Println("再次接收到退出信号，停止等待在途请求")
+			stop()
+		case <-drainCtx.Done():
+		}
+	}()
+
+	if shutdownCfg.ReadyDelay > 0 {
+		select {
+		case <-time.After(time.Duration(shutdownCfg.ReadyDelay) * time.Second):
+		case <-drainCtx.Done():
+		}
+	}
+	cancel()
+
+	if err := lifecycle.Wait(drainCtx); err != nil {
+		fmt.Printf("排空超时，仍有未完成的工作: %v\n", lifecycle.InFlight())
+		return
+	}
+	fmt.Println("在途请求已全部完成")
+}
+
+// persistDatabase 将内存数据库写入文件
+// 参数：无
+// 返回值：无
+func persistDatabase() {
+	db, err := shared.GlobalContainer.Resolve("database")
+	if err != nil {
+		fmt.Printf("无法获取内存数据库: %v\n", err)
+		return
+	}
+	dbInstance, ok := db.(*agilitymemdb.AgilityMemDB)
+	if !ok {
+		return
+	}
+	if err := dbInstance.Persist(); err != nil {
+		fmt.Printf("持久化内存数据库失败: %v\n", err)
+		return
+	}
+	fmt.Println("内存数据库已持久化")
+}
+
+// ExportOpenAPI 根据路由配置生成OpenAPI文档并写入文件
+// 参数：
+//   - path string: 输出文件路径，为 - 时写到标准输出
+// 返回值：
+//   - error: 路由配置加载失败、文档生成失败或写入失败时返回的错误信息
+func ExportOpenAPI(path string) error {
+	loadedRouter, err := router.LoadRouterConfig()
+	if err != nil {
+		return err
+	}
+	doc, err := openapi.Generate(&loadedRouter, globalConfig.OpenAPI)
+	if err != nil {
+		return err
+	}
+	body, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	body = append(body, '\n')
+	if path == "-" {
+		_, err = os.Stdout.Write(body)
+		return err
+	}
+	return os.WriteFile(path, body, 0644)
+}
+
 // main 程序入口函数
 // 功能：
 // 1. 初始化配置和路径
+//    - 指定 -export-openapi 时导出OpenAPI文档后退出
 //    - 解析主PID路径
 //    - 解析业务路径
 //    - 解析内存数据库路径
@@ -153,20 +250,35 @@ func cleanup() {
 //    - 创建全局容器实例
 //    - 注册插件服务
 //    - 注册数据库服务
+//    - 加载插件参数校验规则
 // 3. 启动服务组件
 //    - 写入PID文件
 //    - 创建上下文和等待组
-//    - 创建文件监视器
-//    - 启动信号处理
+//    - 创建文件监视器并注册到容器
+//    - 启动信号处理（收到信号后按 drain 的顺序排空在途请求）
 //    - 启动Socket服务器
 //    - 启动HTTP服务器
+//    - 启动WebSocket服务器
+//    - 启动管理接口
 //    - 启动文件监视器
 // 4. 处理信号和优雅退出
 //    - 等待所有goroutine完成
+//    - 持久化内存数据库
 //    - 执行清理操作
 // 参数：无
 // 返回值：无
 func main() {
+	// 命令行导出OpenAPI文档后直接退出，不启动服务
+	exportOpenAPI := flag.String("export-openapi", "", "导出OpenAPI文档到指定文件（- 表示标准输出）后退出")
+	flag.Parse()
+	if *exportOpenAPI != "" {
+		if err := ExportOpenAPI(*exportOpenAPI); err != nil {
+			fmt.Printf("导出OpenAPI文档失败: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// 解析各种路径配置
 	mainPIDPath, err := utils.ResolvePath(globalConfig.BussinessPIDPath)
 	if err != nil {
@@ -215,6 +327,12 @@ func main() {
 		os.Exit(1)
 	}
 
+	// 加载插件参数校验规则
+	if err := plugin.LoadSchemas(globalConfig.PluginSchemas); err != nil {
+		fmt.Printf("加载插件参数校验规则失败: %v\n", err)
+		os.Exit(1)
+	}
+
 	// 写入PID文件
 	WritePidToFile()
 
@@ -222,8 +340,18 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	var wg sync.WaitGroup
 
-	// 创建文件监视器
+	// 创建文件监视器，并注册到容器供管理接口重启业务进程
 	watcher := utils.New([]string{watcherPath}, mainPIDPath, businessPath)
+	err = shared.GlobalContainer.Register("watcher", func() interface{} {
+		return watcher
+	}, di.Singleton)
+	if err != nil {
+		fmt.Printf("注册文件监视器服务失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Socket服务器使用独立的上下文，在途IPC调用和异步请求排空后才停止
+	ipcCtx, cancelIPC := context.WithCancel(context.Background())
 
 	// 启动信号处理
 	wg.Add(1)
@@ -232,15 +360,15 @@ func main() {
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 		<-sigChan
-		fmt.Println("接收到退出信号，开始清理...")
-		cancel()
+		fmt.Println("接收到退出信号，开始排空在途请求...")
+		drain(sigChan, cancel, cancelIPC)
 	}()
 
 	// 启动Socket服务器
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		socket.StartSocketServer(ctx)
+		socket.StartSocketServer(ipcCtx)
 	}()
 
 	// 启动HTTP服务器
@@ -252,6 +380,20 @@ func main() {
 		http.StartHTTPServer(ctx,httpPort)
 	}()
 
+	// 启动WebSocket服务器
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		websocket.StartWebSocketServer(ctx, globalConfig.Ports.WebSocketPort)
+	}()
+
+	// 启动管理接口
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		admin.StartAdminServer(ctx, globalConfig.Admin)
+	}()
+
 	// 启动文件监视器
 	wg.Add(1)
 	go func() {
@@ -262,6 +404,9 @@ func main() {
 	// 等待所有goroutine完成
 	wg.Wait()
 
+	// 持久化内存数据库后再停止业务进程
+	persistDatabase()
+
 	// 执行清理操作
 	cleanup()
 }
@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/exec"
 	"strconv"
+	"sync"
 	"syscall"
 
 	"github.com/fsnotify/fsnotify"
@@ -17,6 +18,8 @@ type Watcher struct {
 	Folders []string
 	PIDFile string
 	Script  string
+	// mu 防止文件变化和管理接口同时重启进程
+	mu sync.Mutex
 }
 
 // New 创建一个新的 Watcher
@@ -62,6 +65,20 @@ func (w *Watcher) Start(ctx context.Context) {
 
 // restartPHPProcess 重启 PHP 进程
 func (w *Watcher) restartPHPProcess() {
+	if _, err := w.Restart(); err != nil {
+		log.Fatalf("PHP process failed to start: %s", err)
+	}
+}
+
+// Restart 结束当前业务进程并启动新的 PHP 进程
+// 参数：无
+// 返回值：
+//   - int: 新进程的PID
+//   - error: 新进程启动失败时返回的错误信息
+func (w *Watcher) Restart() (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
 	// 读取文件中的现有 PID 并结束进程
 	if pidData, err := os.ReadFile(w.PIDFile); err == nil {
 		if pid, err := strconv.Atoi(string(pidData)); err == nil {
@@ -72,7 +89,7 @@ func (w *Watcher) restartPHPProcess() {
 	// 启动新的 PHP 进程
 	cmd := exec.Command("php", w.Script)
 	if err := cmd.Start(); err != nil {
-		log.Fatalf("PHP process failed to start: %s", err)
+		return 0, err
 	}
 
 	// 将新的 PID 保存到文件
@@ -80,4 +97,5 @@ func (w *Watcher) restartPHPProcess() {
 	os.WriteFile(w.PIDFile, []byte(fmt.Sprintf("%d", newPid)), 0644)
 
 	fmt.Printf("PHP process started with PID: %d\n", newPid)
+	return newPid, nil
 }